@@ -0,0 +1,35 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+// DefaultConfig is the default configuration for the index on top of the DPS
+// API, which does not use any cache.
+var DefaultConfig = Config{
+	Cache: nil,
+}
+
+// Config contains the configuration options for the index on top of the DPS
+// API.
+type Config struct {
+	Cache Cache
+}
+
+// WithCache sets the cache used by the index for data at final heights, which
+// are the heights below the last indexed height.
+func WithCache(cache Cache) func(*Config) {
+	return func(cfg *Config) {
+		cfg.Cache = cache
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+)
+
+// DefaultDialConfig is the default configuration for connections to the DPS
+// API. It sends keepalive pings on active connections and retries requests
+// that fail because the API is temporarily unavailable.
+var DefaultDialConfig = DialConfig{
+	KeepaliveTime:    30 * time.Second,
+	KeepaliveTimeout: 10 * time.Second,
+	MaxRetries:       5,
+	RetryBackoff:     100 * time.Millisecond,
+}
+
+// DialConfig contains the configuration options for connections to the DPS
+// API.
+type DialConfig struct {
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	MaxRetries       uint
+	RetryBackoff     time.Duration
+	Compression      bool
+}
+
+// WithKeepalive sets the interval after which a keepalive ping is sent on an
+// active connection without activity, and how long to wait for its response
+// before considering the connection broken. A zero interval disables pings.
+func WithKeepalive(interval time.Duration, timeout time.Duration) func(*DialConfig) {
+	return func(cfg *DialConfig) {
+		cfg.KeepaliveTime = interval
+		cfg.KeepaliveTimeout = timeout
+	}
+}
+
+// WithRetries sets the maximum number of times a request is retried when the
+// API is unavailable, and the base duration of the exponential backoff between
+// retries. Zero retries disables retrying.
+func WithRetries(max uint, backoff time.Duration) func(*DialConfig) {
+	return func(cfg *DialConfig) {
+		cfg.MaxRetries = max
+		cfg.RetryBackoff = backoff
+	}
+}
+
+// WithCompression sets whether requests are sent with gzip compression, which
+// also makes the API compress its responses. Importing this package registers
+// the gzip compressor, so that API servers always accept compressed requests,
+// while still answering uncompressed requests without compression.
+func WithCompression(enabled bool) func(*DialConfig) {
+	return func(cfg *DialConfig) {
+		cfg.Compression = enabled
+	}
+}
+
+// Dial creates a client connection to the DPS API at the given address, which
+// recovers from transient network failures by reconnecting and retrying the
+// failed requests.
+func Dial(address string, options ...func(*DialConfig)) (*grpc.ClientConn, error) {
+
+	cfg := DefaultDialConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+	}
+	if cfg.KeepaliveTime > 0 {
+		params := keepalive.ClientParameters{
+			Time:    cfg.KeepaliveTime,
+			Timeout: cfg.KeepaliveTimeout,
+		}
+		opts = append(opts, grpc.WithKeepaliveParams(params))
+	}
+	if cfg.MaxRetries > 0 {
+		backoff := retry.BackoffExponentialWithJitter(cfg.RetryBackoff, 0.1)
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(retry.UnaryClientInterceptor(
+				retry.WithMax(cfg.MaxRetries),
+				retry.WithBackoff(backoff),
+			)),
+			grpc.WithChainStreamInterceptor(retry.StreamClientInterceptor(
+				retry.WithMax(cfg.MaxRetries),
+				retry.WithBackoff(backoff),
+			)),
+		)
+	}
+
+	if cfg.Compression {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	conn, err := grpc.Dial(address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not dial API: %w", err)
+	}
+
+	return conn, nil
+}
+
+// KeepaliveEnforcement returns the server option that allows clients to send
+// keepalive pings as often as every ten seconds, which is the minimum interval
+// allowed by GRPC clients. Without it, servers close the connections of
+// clients that ping more often than every five minutes.
+func KeepaliveEnforcement() grpc.ServerOption {
+	policy := keepalive.EnforcementPolicy{
+		MinTime: 10 * time.Second,
+	}
+	return grpc.KeepaliveEnforcementPolicy(policy)
+}
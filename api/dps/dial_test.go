@@ -0,0 +1,238 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+
+	"github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+// flakyServer is an API server which is unavailable for a given number of
+// requests before it starts answering them.
+type flakyServer struct {
+	dps.UnimplementedAPIServer
+	failures int32
+}
+
+func (f *flakyServer) GetLast(context.Context, *dps.GetLastRequest) (*dps.GetLastResponse, error) {
+	if atomic.AddInt32(&f.failures, -1) >= 0 {
+		return nil, status.Error(codes.Unavailable, "flaky server unavailable")
+	}
+	return &dps.GetLastResponse{Height: mocks.GenericHeight}, nil
+}
+
+// largeServer is an API server which answers register value requests with a
+// large and easily compressible value for each path.
+type largeServer struct {
+	dps.UnimplementedAPIServer
+}
+
+func (largeServer) GetRegisterValues(_ context.Context, req *dps.GetRegisterValuesRequest) (*dps.GetRegisterValuesResponse, error) {
+	values := make([][]byte, 0, len(req.Paths))
+	for range req.Paths {
+		values = append(values, bytes.Repeat([]byte{0x2a}, 1<<20))
+	}
+	res := dps.GetRegisterValuesResponse{
+		Height: req.Height,
+		Paths:  req.Paths,
+		Values: values,
+	}
+	return &res, nil
+}
+
+// payloadRecorder is a GRPC stats handler which records the uncompressed and
+// the on-the-wire length of each outgoing payload.
+type payloadRecorder struct {
+	sync.Mutex
+	payloads []*stats.OutPayload
+}
+
+func (p *payloadRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (p *payloadRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	out, ok := s.(*stats.OutPayload)
+	if !ok {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.payloads = append(p.payloads, out)
+}
+
+func (p *payloadRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (p *payloadRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func (p *payloadRecorder) last() *stats.OutPayload {
+	p.Lock()
+	defer p.Unlock()
+	return p.payloads[len(p.payloads)-1]
+}
+
+// serve starts serving the given API server on the given address and returns
+// the GRPC server, so it can be stopped.
+func serve(t *testing.T, address string, server dps.APIServer, options ...grpc.ServerOption) (*grpc.Server, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", address)
+	require.NoError(t, err)
+
+	gsvr := grpc.NewServer(append(options, dps.KeepaliveEnforcement())...)
+	dps.RegisterAPIServer(gsvr, server)
+	go func() {
+		_ = gsvr.Serve(listener)
+	}()
+
+	return gsvr, listener.Addr().String()
+}
+
+func TestDial(t *testing.T) {
+	t.Run("retries requests while API is unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		gsvr, address := serve(t, "127.0.0.1:0", &flakyServer{failures: 3})
+		defer gsvr.Stop()
+
+		conn, err := dps.Dial(address, dps.WithRetries(5, time.Millisecond))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		res, err := dps.NewAPIClient(conn).GetLast(context.Background(), &dps.GetLastRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, res.Height)
+	})
+
+	t.Run("fails when retries are exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		gsvr, address := serve(t, "127.0.0.1:0", &flakyServer{failures: 3})
+		defer gsvr.Stop()
+
+		conn, err := dps.Dial(address, dps.WithRetries(2, time.Millisecond))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = dps.NewAPIClient(conn).GetLast(context.Background(), &dps.GetLastRequest{})
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("fails without retries", func(t *testing.T) {
+		t.Parallel()
+
+		gsvr, address := serve(t, "127.0.0.1:0", &flakyServer{failures: 1})
+		defer gsvr.Stop()
+
+		conn, err := dps.Dial(address, dps.WithRetries(0, 0))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = dps.NewAPIClient(conn).GetLast(context.Background(), &dps.GetLastRequest{})
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("reconnects after connection loss", func(t *testing.T) {
+		t.Parallel()
+
+		gsvr, address := serve(t, "127.0.0.1:0", &flakyServer{})
+
+		conn, err := dps.Dial(address, dps.WithRetries(5, 10*time.Millisecond))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		client := dps.NewAPIClient(conn)
+		_, err = client.GetLast(context.Background(), &dps.GetLastRequest{})
+		require.NoError(t, err)
+
+		// Dropping the connection by restarting the server on the same
+		// address should not surface as an error to the client.
+		gsvr.Stop()
+		gsvr, _ = serve(t, address, &flakyServer{})
+		defer gsvr.Stop()
+
+		res, err := client.GetLast(context.Background(), &dps.GetLastRequest{})
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, res.Height)
+	})
+}
+
+func TestDial_Compression(t *testing.T) {
+	req := dps.GetRegisterValuesRequest{
+		Height: mocks.GenericHeight,
+		Paths:  [][]byte{mocks.GenericBytes},
+	}
+
+	t.Run("compresses responses when negotiated", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &payloadRecorder{}
+		gsvr, address := serve(t, "127.0.0.1:0", largeServer{}, grpc.StatsHandler(recorder))
+		defer gsvr.Stop()
+
+		conn, err := dps.Dial(address, dps.WithCompression(true))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		res, err := dps.NewAPIClient(conn).GetRegisterValues(context.Background(), &req)
+		require.NoError(t, err)
+		require.Len(t, res.Values, 1)
+		assert.Len(t, res.Values[0], 1<<20)
+
+		out := recorder.last()
+		assert.Less(t, out.WireLength, out.Length/10)
+	})
+
+	t.Run("does not compress responses when not negotiated", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := &payloadRecorder{}
+		gsvr, address := serve(t, "127.0.0.1:0", largeServer{}, grpc.StatsHandler(recorder))
+		defer gsvr.Stop()
+
+		conn, err := dps.Dial(address)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		res, err := dps.NewAPIClient(conn).GetRegisterValues(context.Background(), &req)
+		require.NoError(t, err)
+		require.Len(t, res.Values, 1)
+		assert.Len(t, res.Values[0], 1<<20)
+
+		out := recorder.last()
+		assert.GreaterOrEqual(t, out.WireLength, out.Length)
+	})
+}
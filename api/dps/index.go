@@ -17,6 +17,8 @@ package dps
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
@@ -32,15 +34,23 @@ import (
 type Index struct {
 	client APIClient
 	codec  dps.Codec
+	cache  Cache
+	last   uint64
 }
 
 // IndexFromAPI creates a new instance of an index reader that uses the provided
 // GRPC API client to retrieve state from the index.
-func IndexFromAPI(client APIClient, codec dps.Codec) *Index {
+func IndexFromAPI(client APIClient, codec dps.Codec, options ...func(*Config)) *Index {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
 
 	i := Index{
 		client: client,
 		codec:  codec,
+		cache:  cfg.Cache,
 	}
 
 	return &i
@@ -48,9 +58,15 @@ func IndexFromAPI(client APIClient, codec dps.Codec) *Index {
 
 // First returns the height of the first finalized block that was indexed.
 func (i *Index) First() (uint64, error) {
+	return i.FirstContext(context.Background())
+}
+
+// FirstContext is like First, but it uses the given context for the API
+// request.
+func (i *Index) FirstContext(ctx context.Context) (uint64, error) {
 
 	req := GetFirstRequest{}
-	res, err := i.client.GetFirst(context.Background(), &req)
+	res, err := i.client.GetFirst(ctx, &req)
 	if err != nil {
 		return 0, fmt.Errorf("could not get first height: %w", err)
 	}
@@ -60,23 +76,59 @@ func (i *Index) First() (uint64, error) {
 
 // Last returns the height of the last finalized block that was indexed.
 func (i *Index) Last() (uint64, error) {
+	return i.LastContext(context.Background())
+}
+
+// LastContext is like Last, but it uses the given context for the API request.
+func (i *Index) LastContext(ctx context.Context) (uint64, error) {
 
 	req := GetLastRequest{}
-	res, err := i.client.GetLast(context.Background(), &req)
+	res, err := i.client.GetLast(ctx, &req)
 	if err != nil {
 		return 0, fmt.Errorf("could not get last height: %w", err)
 	}
 
+	// We keep track of the last height we have seen, so we know which heights
+	// can be cached without having to ask for the last height every time.
+	i.observe(res.Height)
+
+	return res.Height, nil
+}
+
+// Sealed returns the height of the last indexed block that was sealed by one of
+// the indexed blocks.
+func (i *Index) Sealed() (uint64, error) {
+	return i.SealedContext(context.Background())
+}
+
+// SealedContext is like Sealed, but it uses the given context for the API
+// request.
+func (i *Index) SealedContext(ctx context.Context) (uint64, error) {
+
+	req := GetLastRequest{
+		Sealed: true,
+	}
+	res, err := i.client.GetLast(ctx, &req)
+	if err != nil {
+		return 0, fmt.Errorf("could not get last sealed height: %w", err)
+	}
+
 	return res.Height, nil
 }
 
 // HeightForBlock returns the height of the given blockID.
 func (i *Index) HeightForBlock(blockID flow.Identifier) (uint64, error) {
+	return i.HeightForBlockContext(context.Background(), blockID)
+}
+
+// HeightForBlockContext is like HeightForBlock, but it uses the given context
+// for the API request.
+func (i *Index) HeightForBlockContext(ctx context.Context, blockID flow.Identifier) (uint64, error) {
 
 	req := GetHeightForBlockRequest{
 		BlockID: blockID[:],
 	}
-	res, err := i.client.GetHeightForBlock(context.Background(), &req)
+	res, err := i.client.GetHeightForBlock(ctx, &req)
 	if err != nil {
 		return 0, fmt.Errorf("could not get height: %w", err)
 	}
@@ -87,11 +139,23 @@ func (i *Index) HeightForBlock(blockID flow.Identifier) (uint64, error) {
 // Commit returns the commitment of the execution state as it was after the
 // execution of the finalized block at the given height.
 func (i *Index) Commit(height uint64) (flow.StateCommitment, error) {
+	return i.CommitContext(context.Background(), height)
+}
+
+// CommitContext is like Commit, but it uses the given context for the API
+// request.
+func (i *Index) CommitContext(ctx context.Context, height uint64) (flow.StateCommitment, error) {
+
+	key := fmt.Sprintf("commit/%d", height)
+	cached, ok := i.lookup(key)
+	if ok {
+		return cached.(flow.StateCommitment), nil
+	}
 
 	req := GetCommitRequest{
 		Height: height,
 	}
-	res, err := i.client.GetCommit(context.Background(), &req)
+	res, err := i.client.GetCommit(ctx, &req)
 	if err != nil {
 		return flow.DummyStateCommitment, fmt.Errorf("could not get commit: %w", err)
 	}
@@ -101,26 +165,46 @@ func (i *Index) Commit(height uint64) (flow.StateCommitment, error) {
 		return flow.DummyStateCommitment, fmt.Errorf("could not convert commit: %w", err)
 	}
 
+	i.store(height, key, commit, int64(len(commit)))
+
 	return commit, nil
 }
 
 // Header returns the header for the finalized block at the given height.
 func (i *Index) Header(height uint64) (*flow.Header, error) {
+	return i.HeaderContext(context.Background(), height)
+}
 
-	req := GetHeaderRequest{
-		Height: height,
-	}
-	res, err := i.client.GetHeader(context.Background(), &req)
-	if err != nil {
-		return nil, fmt.Errorf("could not get header: %w", err)
+// HeaderContext is like Header, but it uses the given context for the API
+// request.
+func (i *Index) HeaderContext(ctx context.Context, height uint64) (*flow.Header, error) {
+
+	// The cache holds the encoded header, so that each caller gets its own
+	// copy of the header.
+	key := fmt.Sprintf("header/%d", height)
+	cached, ok := i.lookup(key)
+	data, _ := cached.([]byte)
+	if !ok {
+		req := GetHeaderRequest{
+			Height: height,
+		}
+		res, err := i.client.GetHeader(ctx, &req)
+		if err != nil {
+			return nil, fmt.Errorf("could not get header: %w", err)
+		}
+		data = res.Data
 	}
 
 	var header flow.Header
-	err = i.codec.Unmarshal(res.Data, &header)
+	err := i.codec.Unmarshal(data, &header)
 	if err != nil {
 		return nil, fmt.Errorf("could not decode header: %w", err)
 	}
 
+	if !ok {
+		i.store(height, key, data, int64(len(data)))
+	}
+
 	return &header, nil
 }
 
@@ -129,28 +213,67 @@ func (i *Index) Header(height uint64) (*flow.Header, error) {
 // For compatibility with existing Flow execution node code, a path that is not
 // found within the indexed execution state returns a nil value without error.
 func (i *Index) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+	return i.ValuesContext(context.Background(), height, paths)
+}
+
+// ValuesContext is like Values, but it uses the given context for the API
+// request.
+func (i *Index) ValuesContext(ctx context.Context, height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+
+	// We first try to get each value from the cache, and only request the
+	// values that are missing from the API.
+	values := make([]ledger.Value, len(paths))
+	keys := make([]string, 0, len(paths))
+	missing := make([]int, 0, len(paths))
+	for index, path := range paths {
+		key := fmt.Sprintf("value/%d/%x", height, path[:])
+		cached, ok := i.lookup(key)
+		if ok {
+			values[index] = copyValue(cached.(ledger.Value))
+			continue
+		}
+		keys = append(keys, key)
+		missing = append(missing, index)
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	lookup := make([]ledger.Path, 0, len(missing))
+	for _, index := range missing {
+		lookup = append(lookup, paths[index])
+	}
 
 	req := GetRegisterValuesRequest{
 		Height: height,
-		Paths:  convert.PathsToBytes(paths),
+		Paths:  convert.PathsToBytes(lookup),
 	}
-	res, err := i.client.GetRegisterValues(context.Background(), &req)
+	res, err := i.client.GetRegisterValues(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get registers: %w", err)
 	}
 
-	values := convert.BytesToValues(res.Values)
+	for j, value := range convert.BytesToValues(res.Values) {
+		values[missing[j]] = value
+		i.store(height, keys[j], copyValue(value), int64(len(value)))
+	}
 
 	return values, nil
 }
 
 // Collection returns the collection with the given ID.
 func (i *Index) Collection(collID flow.Identifier) (*flow.LightCollection, error) {
+	return i.CollectionContext(context.Background(), collID)
+}
+
+// CollectionContext is like Collection, but it uses the given context for the
+// API request.
+func (i *Index) CollectionContext(ctx context.Context, collID flow.Identifier) (*flow.LightCollection, error) {
 
 	req := GetCollectionRequest{
 		CollectionID: collID[:],
 	}
-	res, err := i.client.GetCollection(context.Background(), &req)
+	res, err := i.client.GetCollection(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get collection: %w", err)
 	}
@@ -166,11 +289,17 @@ func (i *Index) Collection(collID flow.Identifier) (*flow.LightCollection, error
 
 // CollectionsByHeight returns the transaction IDs within the given block.
 func (i *Index) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
+	return i.CollectionsByHeightContext(context.Background(), height)
+}
+
+// CollectionsByHeightContext is like CollectionsByHeight, but it uses the given
+// context for the API request.
+func (i *Index) CollectionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
 
 	req := ListCollectionsForHeightRequest{
 		Height: height,
 	}
-	res, err := i.client.ListCollectionsForHeight(context.Background(), &req)
+	res, err := i.client.ListCollectionsForHeight(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get transactions: %w", err)
 	}
@@ -185,11 +314,17 @@ func (i *Index) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
 
 // Guarantee returns the collection guarantee for the given collection ID.
 func (i *Index) Guarantee(collID flow.Identifier) (*flow.CollectionGuarantee, error) {
+	return i.GuaranteeContext(context.Background(), collID)
+}
+
+// GuaranteeContext is like Guarantee, but it uses the given context for the API
+// request.
+func (i *Index) GuaranteeContext(ctx context.Context, collID flow.Identifier) (*flow.CollectionGuarantee, error) {
 
 	req := GetGuaranteeRequest{
 		CollectionID: collID[:],
 	}
-	res, err := i.client.GetGuarantee(context.Background(), &req)
+	res, err := i.client.GetGuarantee(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get guarantee: %w", err)
 	}
@@ -205,11 +340,17 @@ func (i *Index) Guarantee(collID flow.Identifier) (*flow.CollectionGuarantee, er
 
 // Transaction returns the transaction with the given ID.
 func (i *Index) Transaction(txID flow.Identifier) (*flow.TransactionBody, error) {
+	return i.TransactionContext(context.Background(), txID)
+}
+
+// TransactionContext is like Transaction, but it uses the given context for the
+// API request.
+func (i *Index) TransactionContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionBody, error) {
 
 	req := GetTransactionRequest{
 		TransactionID: txID[:],
 	}
-	res, err := i.client.GetTransaction(context.Background(), &req)
+	res, err := i.client.GetTransaction(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get transaction: %w", err)
 	}
@@ -225,11 +366,17 @@ func (i *Index) Transaction(txID flow.Identifier) (*flow.TransactionBody, error)
 
 // HeightForTransaction returns the height of the given transaction ID.
 func (i *Index) HeightForTransaction(txID flow.Identifier) (uint64, error) {
+	return i.HeightForTransactionContext(context.Background(), txID)
+}
+
+// HeightForTransactionContext is like HeightForTransaction, but it uses the
+// given context for the API request.
+func (i *Index) HeightForTransactionContext(ctx context.Context, txID flow.Identifier) (uint64, error) {
 
 	req := GetHeightForTransactionRequest{
 		TransactionID: txID[:],
 	}
-	res, err := i.client.GetHeightForTransaction(context.Background(), &req)
+	res, err := i.client.GetHeightForTransaction(ctx, &req)
 	if err != nil {
 		return 0, fmt.Errorf("could not get height: %w", err)
 	}
@@ -239,11 +386,17 @@ func (i *Index) HeightForTransaction(txID flow.Identifier) (uint64, error) {
 
 // TransactionsByHeight returns the transaction IDs within the given block.
 func (i *Index) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
+	return i.TransactionsByHeightContext(context.Background(), height)
+}
+
+// TransactionsByHeightContext is like TransactionsByHeight, but it uses the
+// given context for the API request.
+func (i *Index) TransactionsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
 
 	req := ListTransactionsForHeightRequest{
 		Height: height,
 	}
-	res, err := i.client.ListTransactionsForHeight(context.Background(), &req)
+	res, err := i.client.ListTransactionsForHeight(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get transactions: %w", err)
 	}
@@ -258,11 +411,17 @@ func (i *Index) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
 
 // Result returns the result for a given transaction ID.
 func (i *Index) Result(txID flow.Identifier) (*flow.TransactionResult, error) {
+	return i.ResultContext(context.Background(), txID)
+}
+
+// ResultContext is like Result, but it uses the given context for the API
+// request.
+func (i *Index) ResultContext(ctx context.Context, txID flow.Identifier) (*flow.TransactionResult, error) {
 
 	req := GetResultRequest{
 		TransactionID: txID[:],
 	}
-	res, err := i.client.GetResult(context.Background(), &req)
+	res, err := i.client.GetResult(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get transaction result: %w", err)
 	}
@@ -280,33 +439,128 @@ func (i *Index) Result(txID flow.Identifier) (*flow.TransactionResult, error) {
 // finalized block at the given height. It can optionally filter them by event
 // type; if no event types are given, all events are returned.
 func (i *Index) Events(height uint64, types ...flow.EventType) ([]flow.Event, error) {
+	return i.EventsContext(context.Background(), height, types...)
+}
+
+// EventsContext is like Events, but it uses the given context for the API
+// request.
+func (i *Index) EventsContext(ctx context.Context, height uint64, types ...flow.EventType) ([]flow.Event, error) {
 	tt := convert.TypesToStrings(types)
 
-	req := GetEventsRequest{
-		Height: height,
-		Types:  tt,
-	}
-	res, err := i.client.GetEvents(context.Background(), &req)
-	if err != nil {
-		return nil, fmt.Errorf("could not get events: %w", err)
+	// The cache holds the encoded events, so that each caller gets its own
+	// copy of the events.
+	key := fmt.Sprintf("events/%d/%s", height, strings.Join(tt, ","))
+	cached, ok := i.lookup(key)
+	data, _ := cached.([]byte)
+	if !ok {
+		req := GetEventsRequest{
+			Height: height,
+			Types:  tt,
+		}
+		res, err := i.client.GetEvents(ctx, &req)
+		if err != nil {
+			return nil, fmt.Errorf("could not get events: %w", err)
+		}
+		data = res.Data
 	}
 
 	var events []flow.Event
-	err = i.codec.Unmarshal(res.Data, &events)
+	err := i.codec.Unmarshal(data, &events)
 	if err != nil {
 		return nil, fmt.Errorf("could not decode events: %w", err)
 	}
 
+	if !ok {
+		i.store(height, key, data, int64(len(data)))
+	}
+
 	return events, nil
 }
 
+// EventLocation returns the ID of the transaction that emitted the event at the
+// given index within the events of the finalized block at the given height.
+// Events are indexed in the order of their transactions within the block, and
+// then in the order in which they were emitted by each transaction.
+func (i *Index) EventLocation(height uint64, eventIndex uint32) (flow.Identifier, error) {
+	return i.EventLocationContext(context.Background(), height, eventIndex)
+}
+
+// EventLocationContext is like EventLocation, but it uses the given context for
+// the API request.
+func (i *Index) EventLocationContext(ctx context.Context, height uint64, eventIndex uint32) (flow.Identifier, error) {
+	events, err := i.EventsContext(ctx, height)
+	if err != nil {
+		return flow.ZeroID, fmt.Errorf("could not get events: %w", err)
+	}
+	return dps.EventLocation(events, eventIndex)
+}
+
+// IterateEvents calls the given process function with the events of each
+// height from first to last, in order and one height at a time, so that
+// large ranges can be consumed without holding all of their events in
+// memory. If types are given, only events of those types are passed on.
+func (i *Index) IterateEvents(first uint64, last uint64, process func(height uint64, events []flow.Event) error, types ...flow.EventType) error {
+	return i.IterateEventsContext(context.Background(), first, last, process, types...)
+}
+
+// IterateEventsContext is like IterateEvents, but it uses the given context
+// for the API requests.
+func (i *Index) IterateEventsContext(ctx context.Context, first uint64, last uint64, process func(height uint64, events []flow.Event) error, types ...flow.EventType) error {
+
+	if first > last {
+		return fmt.Errorf("invalid height range (first: %d, last: %d)", first, last)
+	}
+
+	// The API is asked to filter the events by type already, but we filter
+	// them again in case it returns more than we asked for.
+	filter := make(map[flow.EventType]struct{}, len(types))
+	for _, typ := range types {
+		filter[typ] = struct{}{}
+	}
+
+	// We check for the last height at the end of the loop rather than in its
+	// condition, so that we can not overflow when it is the maximum height.
+	for height := first; ; height++ {
+
+		events, err := i.EventsContext(ctx, height, types...)
+		if err != nil {
+			return fmt.Errorf("could not get events (height: %d): %w", height, err)
+		}
+
+		if len(filter) > 0 {
+			filtered := make([]flow.Event, 0, len(events))
+			for _, event := range events {
+				_, ok := filter[event.Type]
+				if ok {
+					filtered = append(filtered, event)
+				}
+			}
+			events = filtered
+		}
+
+		err = process(height, events)
+		if err != nil {
+			return fmt.Errorf("could not process events (height: %d): %w", height, err)
+		}
+
+		if height == last {
+			return nil
+		}
+	}
+}
+
 // Seal returns the seal with the given ID.
 func (i *Index) Seal(sealID flow.Identifier) (*flow.Seal, error) {
+	return i.SealContext(context.Background(), sealID)
+}
+
+// SealContext is like Seal, but it uses the given context for the API request.
+func (i *Index) SealContext(ctx context.Context, sealID flow.Identifier) (*flow.Seal, error) {
 
 	req := GetSealRequest{
 		SealID: sealID[:],
 	}
-	res, err := i.client.GetSeal(context.Background(), &req)
+	res, err := i.client.GetSeal(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get seal: %w", err)
 	}
@@ -322,11 +576,17 @@ func (i *Index) Seal(sealID flow.Identifier) (*flow.Seal, error) {
 
 // SealsByHeight returns the seal IDs at the given height.
 func (i *Index) SealsByHeight(height uint64) ([]flow.Identifier, error) {
+	return i.SealsByHeightContext(context.Background(), height)
+}
+
+// SealsByHeightContext is like SealsByHeight, but it uses the given context for
+// the API request.
+func (i *Index) SealsByHeightContext(ctx context.Context, height uint64) ([]flow.Identifier, error) {
 
 	req := ListSealsForHeightRequest{
 		Height: height,
 	}
-	res, err := i.client.ListSealsForHeight(context.Background(), &req)
+	res, err := i.client.ListSealsForHeight(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("could not get seals: %w", err)
 	}
@@ -338,3 +598,54 @@ func (i *Index) SealsByHeight(height uint64) ([]flow.Identifier, error) {
 
 	return sealIDs, nil
 }
+
+// lookup returns the cached value for the given key, if there is a cache and
+// it contains the key.
+func (i *Index) lookup(key string) (interface{}, bool) {
+	if i.cache == nil {
+		return nil, false
+	}
+	return i.cache.Get(key)
+}
+
+// store adds the given value to the cache, if there is a cache and the given
+// height is final. Only heights below the last indexed height are considered
+// final, so that data at the tip of the index is always retrieved from the
+// API. Rather than asking the API for the last height, we rely on the last
+// height we have seen: the API only returns data for indexed heights, so the
+// height of any value we retrieved is indexed as well.
+func (i *Index) store(height uint64, key string, value interface{}, cost int64) {
+	if i.cache == nil {
+		return
+	}
+	if height >= atomic.LoadUint64(&i.last) {
+		i.observe(height)
+		return
+	}
+	_ = i.cache.Set(key, value, cost)
+}
+
+// observe records the given height as indexed, unless a higher height was
+// already seen.
+func (i *Index) observe(height uint64) {
+	for {
+		last := atomic.LoadUint64(&i.last)
+		if height <= last {
+			return
+		}
+		if atomic.CompareAndSwapUint64(&i.last, last, height) {
+			return
+		}
+	}
+}
+
+// copyValue returns a copy of the given register value, so that callers can't
+// modify the values held by the cache.
+func copyValue(value ledger.Value) ledger.Value {
+	if value == nil {
+		return nil
+	}
+	c := make(ledger.Value, len(value))
+	copy(c, value)
+	return c
+}
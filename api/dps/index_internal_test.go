@@ -17,12 +17,16 @@ package dps
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
 	"github.com/optakt/flow-dps/models/convert"
 	"github.com/optakt/flow-dps/testing/mocks"
 )
@@ -36,6 +40,13 @@ func TestIndexFromAPI(t *testing.T) {
 	require.NotNil(t, index)
 	assert.Equal(t, mock, index.client)
 	assert.NotNil(t, mock, index.codec)
+	assert.Nil(t, index.cache)
+
+	cache := mocks.BaselineCache(t)
+	index = IndexFromAPI(mock, codec, WithCache(cache))
+
+	require.NotNil(t, index)
+	assert.Equal(t, cache, index.cache)
 }
 
 func TestIndex_First(t *testing.T) {
@@ -114,6 +125,44 @@ func TestIndex_Last(t *testing.T) {
 	})
 }
 
+func TestIndex_Sealed(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			client: &apiMock{
+				GetLastFunc: func(_ context.Context, in *GetLastRequest, _ ...grpc.CallOption) (*GetLastResponse, error) {
+					assert.True(t, in.Sealed)
+
+					return &GetLastResponse{
+						Height: mocks.GenericHeight,
+					}, nil
+				},
+			},
+		}
+
+		got, err := index.Sealed()
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got)
+	})
+
+	t.Run("handles index failure", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			client: &apiMock{
+				GetLastFunc: func(context.Context, *GetLastRequest, ...grpc.CallOption) (*GetLastResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.Sealed()
+		assert.Error(t, err)
+	})
+}
+
 func TestIndex_Header(t *testing.T) {
 	// We need to use the proper encoding to support nanoseconds
 	// and timezones in timestamps.
@@ -296,6 +345,47 @@ func TestIndex_Values(t *testing.T) {
 		assert.Equal(t, values, got)
 	})
 
+	t.Run("batch matches single path reads", func(t *testing.T) {
+		t.Parallel()
+
+		lookup := make(map[ledger.Path]ledger.Value, len(paths))
+		for i, path := range paths {
+			lookup[path] = values[i]
+		}
+
+		var requests int
+		index := Index{
+			client: &apiMock{
+				GetRegisterValuesFunc: func(_ context.Context, in *GetRegisterValuesRequest, _ ...grpc.CallOption) (*GetRegisterValuesResponse, error) {
+					requests++
+					paths, err := convert.BytesToPaths(in.Paths)
+					require.NoError(t, err)
+					var values []ledger.Value
+					for _, path := range paths {
+						values = append(values, lookup[path])
+					}
+					return &GetRegisterValuesResponse{
+						Height: in.Height,
+						Paths:  in.Paths,
+						Values: convert.ValuesToBytes(values),
+					}, nil
+				},
+			},
+		}
+
+		batch, err := index.Values(mocks.GenericHeight, paths)
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests)
+
+		for i, path := range paths {
+			single, err := index.Values(mocks.GenericHeight, []ledger.Path{path})
+			require.NoError(t, err)
+			require.Len(t, single, 1)
+			assert.Equal(t, batch[i], single[0])
+		}
+		assert.Equal(t, 1+len(paths), requests)
+	})
+
 	t.Run("handles index failures", func(t *testing.T) {
 		t.Parallel()
 
@@ -703,6 +793,192 @@ func TestIndex_Events(t *testing.T) {
 	})
 }
 
+func TestIndex_EventLocation(t *testing.T) {
+	events := mocks.GenericEvents(4)
+
+	data, err := cbor.Marshal(events)
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = cbor.Unmarshal
+
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				GetEventsFunc: func(_ context.Context, in *GetEventsRequest, _ ...grpc.CallOption) (*GetEventsResponse, error) {
+					assert.Equal(t, mocks.GenericHeight, in.Height)
+					assert.Empty(t, in.Types)
+
+					return &GetEventsResponse{
+						Height: mocks.GenericHeight,
+						Data:   data,
+					}, nil
+				},
+			},
+		}
+
+		got, err := index.EventLocation(mocks.GenericHeight, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, events[2].TransactionID, got)
+	})
+
+	t.Run("handles index failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec: mocks.BaselineCodec(t),
+			client: &apiMock{
+				GetEventsFunc: func(context.Context, *GetEventsRequest, ...grpc.CallOption) (*GetEventsResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		_, err := index.EventLocation(mocks.GenericHeight, 0)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestIndex_IterateEvents(t *testing.T) {
+	types := mocks.GenericEventTypes(2)
+
+	// The API returns events of both types at each height, even when asked
+	// for only one of them.
+	events := mocks.GenericEvents(4, types...)
+	data, err := cbor.Marshal(events)
+	require.NoError(t, err)
+
+	codec := mocks.BaselineCodec(t)
+	codec.UnmarshalFunc = cbor.Unmarshal
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var requested []uint64
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				GetEventsFunc: func(_ context.Context, in *GetEventsRequest, _ ...grpc.CallOption) (*GetEventsResponse, error) {
+					assert.Equal(t, convert.TypesToStrings(types[:1]), in.Types)
+					requested = append(requested, in.Height)
+
+					return &GetEventsResponse{
+						Height: in.Height,
+						Types:  in.Types,
+						Data:   data,
+					}, nil
+				},
+			},
+		}
+
+		var heights []uint64
+		process := func(height uint64, got []flow.Event) error {
+			heights = append(heights, height)
+			assert.Equal(t, []flow.Event{events[0], events[2]}, got)
+
+			// Each height is requested only once it is processed.
+			assert.Equal(t, heights, requested)
+			return nil
+		}
+
+		err := index.IterateEvents(mocks.GenericHeight, mocks.GenericHeight+3, process, types[0])
+
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{mocks.GenericHeight, mocks.GenericHeight + 1, mocks.GenericHeight + 2, mocks.GenericHeight + 3}, heights)
+	})
+
+	t.Run("nominal case without types", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				GetEventsFunc: func(_ context.Context, in *GetEventsRequest, _ ...grpc.CallOption) (*GetEventsResponse, error) {
+					return &GetEventsResponse{
+						Height: in.Height,
+						Data:   data,
+					}, nil
+				},
+			},
+		}
+
+		var count int
+		err := index.IterateEvents(mocks.GenericHeight, mocks.GenericHeight+1, func(_ uint64, got []flow.Event) error {
+			count++
+			assert.Equal(t, events, got)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("handles invalid range", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec:  codec,
+			client: &apiMock{},
+		}
+
+		err := index.IterateEvents(mocks.GenericHeight+1, mocks.GenericHeight, func(uint64, []flow.Event) error {
+			t.Fatal("unexpected call to process function")
+			return nil
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles index failures", func(t *testing.T) {
+		t.Parallel()
+
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				GetEventsFunc: func(context.Context, *GetEventsRequest, ...grpc.CallOption) (*GetEventsResponse, error) {
+					return nil, mocks.GenericError
+				},
+			},
+		}
+
+		err := index.IterateEvents(mocks.GenericHeight, mocks.GenericHeight+1, func(uint64, []flow.Event) error {
+			return nil
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("stops on process failure", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int
+		index := Index{
+			codec: codec,
+			client: &apiMock{
+				GetEventsFunc: func(_ context.Context, in *GetEventsRequest, _ ...grpc.CallOption) (*GetEventsResponse, error) {
+					calls++
+					return &GetEventsResponse{
+						Height: in.Height,
+						Data:   data,
+					}, nil
+				},
+			},
+		}
+
+		err := index.IterateEvents(mocks.GenericHeight, mocks.GenericHeight+3, func(uint64, []flow.Event) error {
+			return mocks.GenericError
+		})
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+		assert.Equal(t, 1, calls)
+	})
+}
+
 func TestIndex_Seals(t *testing.T) {
 	seal := mocks.GenericSeal(0)
 	sealID := seal.ID()
@@ -803,6 +1079,329 @@ func TestIndex_ListSealsForHeight(t *testing.T) {
 	})
 }
 
+func TestIndex_Cache(t *testing.T) {
+	header := mocks.GenericHeader
+	events := mocks.GenericEvents(4)
+	types := mocks.GenericEventTypes(2)
+	paths := mocks.GenericLedgerPaths(6)
+	values := mocks.GenericLedgerValues(6)
+
+	// We need to use the proper encoding to support nanoseconds
+	// and timezones in timestamps.
+	options := cbor.CanonicalEncOptions()
+	options.Time = cbor.TimeRFC3339Nano
+	encoder, err := options.EncMode()
+	require.NoError(t, err)
+
+	headerData, err := encoder.Marshal(header)
+	require.NoError(t, err)
+	eventsData, err := cbor.Marshal(events)
+	require.NoError(t, err)
+
+	// cache returns a cache mock which is backed by a map, so that we can
+	// check whether values are retrieved from the cache or from the API.
+	cache := func(t *testing.T) *mocks.Cache {
+		entries := make(map[interface{}]interface{})
+		c := mocks.BaselineCache(t)
+		c.GetFunc = func(key interface{}) (interface{}, bool) {
+			value, ok := entries[key]
+			return value, ok
+		}
+		c.SetFunc = func(key interface{}, value interface{}, _ int64) bool {
+			entries[key] = value
+			return true
+		}
+		return c
+	}
+
+	// index returns an index with a cache, which already retrieved the given
+	// last indexed height, and which counts the calls made to the API.
+	index := func(t *testing.T, last uint64, calls map[string]int) *Index {
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = cbor.Unmarshal
+
+		client := &apiMock{
+			GetLastFunc: func(context.Context, *GetLastRequest, ...grpc.CallOption) (*GetLastResponse, error) {
+				calls["last"]++
+				return &GetLastResponse{Height: last}, nil
+			},
+			GetHeaderFunc: func(context.Context, *GetHeaderRequest, ...grpc.CallOption) (*GetHeaderResponse, error) {
+				calls["header"]++
+				return &GetHeaderResponse{Height: header.Height, Data: headerData}, nil
+			},
+			GetEventsFunc: func(_ context.Context, in *GetEventsRequest, _ ...grpc.CallOption) (*GetEventsResponse, error) {
+				calls["events"]++
+				return &GetEventsResponse{Height: in.Height, Types: in.Types, Data: eventsData}, nil
+			},
+			GetRegisterValuesFunc: func(_ context.Context, in *GetRegisterValuesRequest, _ ...grpc.CallOption) (*GetRegisterValuesResponse, error) {
+				calls["values"] += len(in.Paths)
+				lookup := make(map[string]ledger.Value)
+				for i, path := range paths {
+					lookup[string(path[:])] = values[i]
+				}
+				var vv []ledger.Value
+				for _, path := range in.Paths {
+					vv = append(vv, lookup[string(path)])
+				}
+				return &GetRegisterValuesResponse{Height: in.Height, Paths: in.Paths, Values: convert.ValuesToBytes(vv)}, nil
+			},
+		}
+
+		index := IndexFromAPI(client, codec, WithCache(cache(t)))
+		_, err := index.Last()
+		require.NoError(t, err)
+
+		return index
+	}
+
+	t.Run("caches headers below last height", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, header.Height+1, calls)
+
+		for i := 0; i < 3; i++ {
+			got, err := index.Header(header.Height)
+
+			require.NoError(t, err)
+			assert.Equal(t, header, got)
+		}
+		assert.Equal(t, 1, calls["header"])
+		assert.Equal(t, 1, calls["last"])
+	})
+
+	t.Run("does not cache headers at last height", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, header.Height, calls)
+
+		for i := 0; i < 3; i++ {
+			got, err := index.Header(header.Height)
+
+			require.NoError(t, err)
+			assert.Equal(t, header, got)
+		}
+		assert.Equal(t, 3, calls["header"])
+	})
+
+	t.Run("caches events below last height", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, mocks.GenericHeight+1, calls)
+
+		for i := 0; i < 3; i++ {
+			got, err := index.Events(mocks.GenericHeight, types...)
+
+			require.NoError(t, err)
+			assert.Equal(t, events, got)
+		}
+		assert.Equal(t, 1, calls["events"])
+
+		// Events for other types are cached separately.
+		_, err := index.Events(mocks.GenericHeight)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls["events"])
+	})
+
+	t.Run("does not cache events at last height", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, mocks.GenericHeight, calls)
+
+		for i := 0; i < 3; i++ {
+			_, err := index.Events(mocks.GenericHeight, types...)
+
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 3, calls["events"])
+	})
+
+	t.Run("only requests missing values below last height", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, mocks.GenericHeight+1, calls)
+
+		got, err := index.Values(mocks.GenericHeight, paths[:3])
+
+		require.NoError(t, err)
+		assert.Equal(t, values[:3], got)
+		assert.Equal(t, 3, calls["values"])
+
+		got, err = index.Values(mocks.GenericHeight, paths)
+
+		require.NoError(t, err)
+		assert.Equal(t, values, got)
+		assert.Equal(t, 6, calls["values"])
+
+		got, err = index.Values(mocks.GenericHeight, paths)
+
+		require.NoError(t, err)
+		assert.Equal(t, values, got)
+		assert.Equal(t, 6, calls["values"])
+	})
+
+	t.Run("does not cache values at last height", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, mocks.GenericHeight, calls)
+
+		for i := 0; i < 3; i++ {
+			got, err := index.Values(mocks.GenericHeight, paths)
+
+			require.NoError(t, err)
+			assert.Equal(t, values, got)
+		}
+		assert.Equal(t, 18, calls["values"])
+	})
+
+	t.Run("learns last height from responses", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, 0, calls)
+
+		// The header at the given height can only be cached once the index
+		// has seen data at a higher height.
+		_, err := index.Header(header.Height)
+		require.NoError(t, err)
+		_, err = index.Header(header.Height + 1)
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			got, err := index.Header(header.Height)
+
+			require.NoError(t, err)
+			assert.Equal(t, header, got)
+		}
+
+		assert.Equal(t, 3, calls["header"])
+		assert.Equal(t, 1, calls["last"])
+	})
+
+	t.Run("returns copies of cached data", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(map[string]int)
+		index := index(t, mocks.GenericHeight+1, calls)
+
+		// The values returned by the API mock share memory with the test
+		// fixtures, so we compare against freshly generated fixtures.
+		for i := 0; i < 3; i++ {
+			gotHeader, err := index.Header(header.Height)
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericHeader, gotHeader)
+			gotHeader.Height++
+
+			gotEvents, err := index.Events(mocks.GenericHeight, types...)
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericEvents(4), gotEvents)
+			gotEvents[0].EventIndex++
+
+			gotValues, err := index.Values(mocks.GenericHeight, paths)
+			require.NoError(t, err)
+			assert.Equal(t, mocks.GenericLedgerValues(6), gotValues)
+			gotValues[0][0]++
+		}
+		assert.Equal(t, 1, calls["header"])
+		assert.Equal(t, 1, calls["events"])
+		assert.Equal(t, 6, calls["values"])
+	})
+}
+
+func TestIndex_Context(t *testing.T) {
+	type key struct{}
+
+	// client returns an API mock for which all requests fail with the error
+	// of their context, after checking that it is the context of the caller.
+	client := func(t *testing.T, want context.Context) *apiMock {
+		check := func(ctx context.Context) error {
+			assert.Equal(t, want.Value(key{}), ctx.Value(key{}))
+			return ctx.Err()
+		}
+		return &apiMock{
+			GetFirstFunc: func(ctx context.Context, _ *GetFirstRequest, _ ...grpc.CallOption) (*GetFirstResponse, error) {
+				return &GetFirstResponse{}, check(ctx)
+			},
+			GetLastFunc: func(ctx context.Context, _ *GetLastRequest, _ ...grpc.CallOption) (*GetLastResponse, error) {
+				return &GetLastResponse{}, check(ctx)
+			},
+			GetHeaderFunc: func(ctx context.Context, _ *GetHeaderRequest, _ ...grpc.CallOption) (*GetHeaderResponse, error) {
+				return &GetHeaderResponse{}, check(ctx)
+			},
+			GetRegisterValuesFunc: func(ctx context.Context, _ *GetRegisterValuesRequest, _ ...grpc.CallOption) (*GetRegisterValuesResponse, error) {
+				return &GetRegisterValuesResponse{}, check(ctx)
+			},
+			GetEventsFunc: func(ctx context.Context, _ *GetEventsRequest, _ ...grpc.CallOption) (*GetEventsResponse, error) {
+				return &GetEventsResponse{}, check(ctx)
+			},
+		}
+	}
+
+	// calls are the context-aware index methods we check.
+	calls := map[string]func(ctx context.Context, index *Index) error{
+		"First": func(ctx context.Context, index *Index) error {
+			_, err := index.FirstContext(ctx)
+			return err
+		},
+		"Last": func(ctx context.Context, index *Index) error {
+			_, err := index.LastContext(ctx)
+			return err
+		},
+		"Header": func(ctx context.Context, index *Index) error {
+			_, err := index.HeaderContext(ctx, mocks.GenericHeight)
+			return err
+		},
+		"Values": func(ctx context.Context, index *Index) error {
+			_, err := index.ValuesContext(ctx, mocks.GenericHeight, mocks.GenericLedgerPaths(1))
+			return err
+		},
+		"Events": func(ctx context.Context, index *Index) error {
+			_, err := index.EventsContext(ctx, mocks.GenericHeight)
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		name, call := name, call
+		t.Run(name+" propagates cancellation", func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, name))
+			cancel()
+
+			index := Index{
+				client: client(t, ctx),
+				codec:  mocks.BaselineCodec(t),
+			}
+
+			err := call(ctx, &index)
+
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+
+		t.Run(name+" propagates deadline", func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithDeadline(context.WithValue(context.Background(), key{}, name), time.Now())
+			defer cancel()
+
+			index := Index{
+				client: client(t, ctx),
+				codec:  mocks.BaselineCodec(t),
+			}
+
+			err := call(ctx, &index)
+
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		})
+	}
+}
+
 type apiMock struct {
 	GetFirstFunc                  func(ctx context.Context, in *GetFirstRequest, opts ...grpc.CallOption) (*GetFirstResponse, error)
 	GetLastFunc                   func(ctx context.Context, in *GetLastRequest, opts ...grpc.CallOption) (*GetLastResponse, error)
@@ -810,6 +1409,7 @@ type apiMock struct {
 	GetCommitFunc                 func(ctx context.Context, in *GetCommitRequest, opts ...grpc.CallOption) (*GetCommitResponse, error)
 	GetHeaderFunc                 func(ctx context.Context, in *GetHeaderRequest, opts ...grpc.CallOption) (*GetHeaderResponse, error)
 	GetEventsFunc                 func(ctx context.Context, in *GetEventsRequest, opts ...grpc.CallOption) (*GetEventsResponse, error)
+	GetEventsForBlockFunc         func(ctx context.Context, in *GetEventsForBlockRequest, opts ...grpc.CallOption) (*GetEventsForBlockResponse, error)
 	GetRegisterValuesFunc         func(ctx context.Context, in *GetRegisterValuesRequest, opts ...grpc.CallOption) (*GetRegisterValuesResponse, error)
 	GetCollectionFunc             func(ctx context.Context, in *GetCollectionRequest, opts ...grpc.CallOption) (*GetCollectionResponse, error)
 	ListCollectionsForHeightFunc  func(ctx context.Context, in *ListCollectionsForHeightRequest, opts ...grpc.CallOption) (*ListCollectionsForHeightResponse, error)
@@ -820,6 +1420,7 @@ type apiMock struct {
 	GetResultFunc                 func(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*GetResultResponse, error)
 	GetSealFunc                   func(ctx context.Context, in *GetSealRequest, opts ...grpc.CallOption) (*GetSealResponse, error)
 	ListSealsForHeightFunc        func(ctx context.Context, in *ListSealsForHeightRequest, opts ...grpc.CallOption) (*ListSealsForHeightResponse, error)
+	GetBuildInfoFunc              func(ctx context.Context, in *GetBuildInfoRequest, opts ...grpc.CallOption) (*GetBuildInfoResponse, error)
 }
 
 func (a *apiMock) GetFirst(ctx context.Context, in *GetFirstRequest, opts ...grpc.CallOption) (*GetFirstResponse, error) {
@@ -846,6 +1447,10 @@ func (a *apiMock) GetEvents(ctx context.Context, in *GetEventsRequest, opts ...g
 	return a.GetEventsFunc(ctx, in, opts...)
 }
 
+func (a *apiMock) GetEventsForBlock(ctx context.Context, in *GetEventsForBlockRequest, opts ...grpc.CallOption) (*GetEventsForBlockResponse, error) {
+	return a.GetEventsForBlockFunc(ctx, in, opts...)
+}
+
 func (a *apiMock) GetRegisterValues(ctx context.Context, in *GetRegisterValuesRequest, opts ...grpc.CallOption) (*GetRegisterValuesResponse, error) {
 	return a.GetRegisterValuesFunc(ctx, in, opts...)
 }
@@ -885,3 +1490,7 @@ func (a *apiMock) GetSeal(ctx context.Context, in *GetSealRequest, opts ...grpc.
 func (a *apiMock) ListSealsForHeight(ctx context.Context, in *ListSealsForHeightRequest, opts ...grpc.CallOption) (*ListSealsForHeightResponse, error) {
 	return a.ListSealsForHeightFunc(ctx, in, opts...)
 }
+
+func (a *apiMock) GetBuildInfo(ctx context.Context, in *GetBuildInfoRequest, opts ...grpc.CallOption) (*GetBuildInfoResponse, error) {
+	return a.GetBuildInfoFunc(ctx, in, opts...)
+}
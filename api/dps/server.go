@@ -17,8 +17,11 @@ package dps
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/onflow/flow-go/model/flow"
 
@@ -65,8 +68,21 @@ func (s *Server) GetFirst(_ context.Context, _ *GetFirstRequest) (*GetFirstRespo
 	return &res, nil
 }
 
-// GetLast implements the `GetLast` method of the generated GRPC server.
-func (s *Server) GetLast(_ context.Context, _ *GetLastRequest) (*GetLastResponse, error) {
+// GetLast implements the `GetLast` method of the generated GRPC server. It
+// returns the last finalized height, or the last sealed height if the request
+// asks for it.
+func (s *Server) GetLast(_ context.Context, req *GetLastRequest) (*GetLastResponse, error) {
+
+	if req.Sealed {
+		height, err := s.index.Sealed()
+		if err != nil {
+			return nil, fmt.Errorf("could not get last sealed height: %w", err)
+		}
+		res := GetLastResponse{
+			Height: height,
+		}
+		return &res, nil
+	}
 
 	height, err := s.index.Last()
 	if err != nil {
@@ -111,6 +127,11 @@ func (s *Server) GetCommit(_ context.Context, req *GetCommitRequest) (*GetCommit
 		return nil, fmt.Errorf("bad request: %w", err)
 	}
 
+	err = s.checkHeight(req.Height)
+	if err != nil {
+		return nil, err
+	}
+
 	commit, err := s.index.Commit(req.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not get commit: %w", err)
@@ -132,6 +153,11 @@ func (s *Server) GetHeader(_ context.Context, req *GetHeaderRequest) (*GetHeader
 		return nil, fmt.Errorf("bad request: %w", err)
 	}
 
+	err = s.checkHeight(req.Height)
+	if err != nil {
+		return nil, err
+	}
+
 	header, err := s.index.Header(req.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not get header: %w", err)
@@ -153,6 +179,11 @@ func (s *Server) GetHeader(_ context.Context, req *GetHeaderRequest) (*GetHeader
 // GetEvents implements the `GetEvents` method of the generated GRPC server.
 func (s *Server) GetEvents(_ context.Context, req *GetEventsRequest) (*GetEventsResponse, error) {
 
+	err := s.checkHeight(req.Height)
+	if err != nil {
+		return nil, err
+	}
+
 	types := convert.StringsToTypes(req.Types)
 	events, err := s.index.Events(req.Height, types...)
 	if err != nil {
@@ -173,6 +204,52 @@ func (s *Server) GetEvents(_ context.Context, req *GetEventsRequest) (*GetEvents
 	return &res, nil
 }
 
+// GetEventsForBlock implements the `GetEventsForBlock` method of the generated
+// GRPC server. It resolves the height of the given block and returns its
+// events, ordered by transaction index and event index.
+func (s *Server) GetEventsForBlock(_ context.Context, req *GetEventsForBlockRequest) (*GetEventsForBlockResponse, error) {
+
+	err := s.validate.Struct(req)
+	if err != nil {
+		return nil, fmt.Errorf("bad request: %w", err)
+	}
+
+	blockID := flow.HashToID(req.BlockID)
+	height, err := s.index.HeightForBlock(blockID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get height for block: %w", err)
+	}
+
+	types := convert.StringsToTypes(req.Types)
+	events, err := s.index.Events(height, types...)
+	if err != nil {
+		return nil, fmt.Errorf("could not get events: %w", err)
+	}
+
+	// The index does not guarantee any ordering of the events, so we sort
+	// them here to give clients a stable order.
+	sort.SliceStable(events, func(i int, j int) bool {
+		if events[i].TransactionIndex != events[j].TransactionIndex {
+			return events[i].TransactionIndex < events[j].TransactionIndex
+		}
+		return events[i].EventIndex < events[j].EventIndex
+	})
+
+	data, err := s.codec.Marshal(events)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode events: %w", err)
+	}
+
+	res := GetEventsForBlockResponse{
+		BlockID: req.BlockID,
+		Height:  height,
+		Types:   req.Types,
+		Data:    data,
+	}
+
+	return &res, nil
+}
+
 // GetRegisterValues implements the `GetRegisterValues` method of the
 // generated GRPC server.
 func (s *Server) GetRegisterValues(_ context.Context, req *GetRegisterValuesRequest) (*GetRegisterValuesResponse, error) {
@@ -182,6 +259,11 @@ func (s *Server) GetRegisterValues(_ context.Context, req *GetRegisterValuesRequ
 		return nil, fmt.Errorf("bad request: %w", err)
 	}
 
+	err = s.checkHeight(req.Height)
+	if err != nil {
+		return nil, err
+	}
+
 	paths, err := convert.BytesToPaths(req.Paths)
 	if err != nil {
 		return nil, fmt.Errorf("could not convert paths: %w", err)
@@ -237,6 +319,11 @@ func (s *Server) ListCollectionsForHeight(_ context.Context, req *ListCollection
 	if err != nil {
 		return nil, fmt.Errorf("bad request: %w", err)
 	}
+
+	err = s.checkHeight(req.Height)
+	if err != nil {
+		return nil, err
+	}
 	collIDs, err := s.index.CollectionsByHeight(req.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not list collections by height: %w", err)
@@ -343,6 +430,11 @@ func (s *Server) ListTransactionsForHeight(_ context.Context, req *ListTransacti
 		return nil, fmt.Errorf("bad request: %w", err)
 	}
 
+	err = s.checkHeight(req.Height)
+	if err != nil {
+		return nil, err
+	}
+
 	txIDs, err := s.index.TransactionsByHeight(req.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not list transactions by height: %w", err)
@@ -426,6 +518,11 @@ func (s *Server) ListSealsForHeight(_ context.Context, req *ListSealsForHeightRe
 		return nil, fmt.Errorf("bad request: %w", err)
 	}
 
+	err = s.checkHeight(req.Height)
+	if err != nil {
+		return nil, err
+	}
+
 	sealIDs, err := s.index.SealsByHeight(req.Height)
 	if err != nil {
 		return nil, fmt.Errorf("could not list seals by height: %w", err)
@@ -443,3 +540,40 @@ func (s *Server) ListSealsForHeight(_ context.Context, req *ListSealsForHeightRe
 
 	return &res, nil
 }
+
+// GetBuildInfo implements the `GetBuildInfo` method of the generated GRPC
+// server. It returns the build information of the serving binary.
+func (s *Server) GetBuildInfo(_ context.Context, _ *GetBuildInfoRequest) (*GetBuildInfoResponse, error) {
+
+	build := dps.Build()
+
+	res := GetBuildInfoResponse{
+		Version: build.Version,
+		Commit:  build.Commit,
+		Date:    build.Date,
+	}
+
+	return &res, nil
+}
+
+// checkHeight returns an error with the `OutOfRange` status code if the given
+// height is outside of the range of indexed heights, so that clients get an
+// explicit error naming the available range, instead of an error about data
+// that could not be found.
+func (s *Server) checkHeight(height uint64) error {
+
+	first, err := s.index.First()
+	if err != nil {
+		return fmt.Errorf("could not get first height: %w", err)
+	}
+	last, err := s.index.Last()
+	if err != nil {
+		return fmt.Errorf("could not get last height: %w", err)
+	}
+
+	if height < first || height > last {
+		return status.Errorf(codes.OutOfRange, "height %d is outside of indexed range (first: %d, last: %d)", height, first, last)
+	}
+
+	return nil
+}
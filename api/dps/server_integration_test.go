@@ -20,10 +20,12 @@ package dps_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/api/dps"
@@ -46,7 +48,7 @@ func TestIntegrationServer_GetFirst(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.First(mocks.GenericHeight))
@@ -94,7 +96,7 @@ func TestIntegrationServer_GetLast(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Last(mocks.GenericHeight))
@@ -144,7 +146,7 @@ func TestIntegrationServer_GetHeightForBlock(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Height(blockID, height))
@@ -198,9 +200,11 @@ func TestIntegrationServer_GetCommit(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
+		require.NoError(t, writer.First(height))
+		require.NoError(t, writer.Last(height))
 		require.NoError(t, writer.Commit(height, commit))
 		require.NoError(t, writer.Close())
 
@@ -253,9 +257,11 @@ func TestIntegrationServer_GetHeader(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
+		require.NoError(t, writer.First(height))
+		require.NoError(t, writer.Last(height))
 		require.NoError(t, writer.Header(height, header))
 		require.NoError(t, writer.Close())
 
@@ -297,6 +303,48 @@ func TestIntegrationServer_GetHeader(t *testing.T) {
 	})
 }
 
+func TestIntegrationServer_ServesFreshWrites(t *testing.T) {
+	header := mocks.GenericHeader
+	height := mocks.GenericHeader.Height
+
+	codec := zbor.NewCodec()
+
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	// The writer is not closed before serving, like when the mapper and the
+	// server run in the same process over the same database.
+	storage := storage.New(codec)
+	reader := index.NewReader(db, storage)
+	writer := index.NewWriter(mocks.NoopLogger, db, storage,
+		index.WithFlushInterval(10*time.Millisecond),
+	)
+	defer writer.Close()
+
+	server := dps.NewServer(reader, codec)
+
+	require.NoError(t, writer.Header(height, header))
+	require.NoError(t, writer.First(height))
+	require.NoError(t, writer.Last(height))
+
+	assert.Eventually(t, func() bool {
+		resp, err := server.GetLast(context.Background(), &dps.GetLastRequest{})
+		return err == nil && resp.Height == height
+	}, time.Second, 10*time.Millisecond)
+
+	req := &dps.GetHeaderRequest{
+		Height: height,
+	}
+	resp, err := server.GetHeader(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, height, resp.Height)
+
+	wantData, err := codec.Marshal(header)
+	require.NoError(t, err)
+	assert.Equal(t, wantData, resp.Data)
+}
+
 func TestIntegrationServer_GetEvents(t *testing.T) {
 	withdrawalType := mocks.GenericEventType(0)
 	depositType := mocks.GenericEventType(1)
@@ -316,7 +364,7 @@ func TestIntegrationServer_GetEvents(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.First(height))
@@ -349,7 +397,7 @@ func TestIntegrationServer_GetEvents(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.First(height))
@@ -411,7 +459,7 @@ func TestIntegrationServer_GetRegisterValues(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.First(height))
@@ -437,6 +485,46 @@ func TestIntegrationServer_GetRegisterValues(t *testing.T) {
 		assert.Equal(t, convert.PathsToBytes(paths), resp.Paths)
 	})
 
+	t.Run("batch matches single path reads", func(t *testing.T) {
+		t.Parallel()
+
+		codec := zbor.NewCodec()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		storage := storage.New(codec)
+		reader := index.NewReader(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
+
+		// Insert mock data in database.
+		require.NoError(t, writer.First(height))
+		require.NoError(t, writer.Last(height))
+		require.NoError(t, writer.Payloads(height, paths, payloads))
+		require.NoError(t, writer.Close())
+
+		server := dps.NewServer(reader, codec)
+
+		req := &dps.GetRegisterValuesRequest{
+			Height: height,
+			Paths:  convert.PathsToBytes(paths),
+		}
+		batch, err := server.GetRegisterValues(context.Background(), req)
+		require.NoError(t, err)
+		require.Len(t, batch.Values, len(paths))
+
+		for i, path := range paths {
+			req := &dps.GetRegisterValuesRequest{
+				Height: height,
+				Paths:  convert.PathsToBytes([]ledger.Path{path}),
+			}
+			single, err := server.GetRegisterValues(context.Background(), req)
+			require.NoError(t, err)
+			require.Len(t, single.Values, 1)
+			assert.Equal(t, batch.Values[i], single.Values[0])
+		}
+	})
+
 	t.Run("handles conversion error", func(t *testing.T) {
 		t.Parallel()
 
@@ -447,7 +535,7 @@ func TestIntegrationServer_GetRegisterValues(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.First(height))
@@ -504,7 +592,7 @@ func TestIntegrationServer_GetCollection(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Collections(mocks.GenericHeight, collections))
@@ -562,9 +650,11 @@ func TestIntegrationServer_ListCollectionsForHeight(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
+		require.NoError(t, writer.First(height))
+		require.NoError(t, writer.Last(height))
 		require.NoError(t, writer.Collections(height, collections))
 		require.NoError(t, writer.Close())
 
@@ -622,7 +712,7 @@ func TestIntegrationServer_GetGuarantee(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Guarantees(mocks.GenericHeight, guarantees))
@@ -680,7 +770,7 @@ func TestIntegrationServer_GetTransaction(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Transactions(mocks.GenericHeight, transactions))
@@ -738,7 +828,7 @@ func TestIntegrationServer_GetHeightForTransaction(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Transactions(mocks.GenericHeight, transactions))
@@ -793,9 +883,11 @@ func TestIntegrationServer_ListTransactionsForHeight(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
+		require.NoError(t, writer.First(height))
+		require.NoError(t, writer.Last(height))
 		require.NoError(t, writer.Transactions(height, transactions))
 		require.NoError(t, writer.Close())
 
@@ -853,7 +945,7 @@ func TestIntegrationServer_GetResult(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Results(results))
@@ -911,7 +1003,7 @@ func TestIntegrationServer_GetSeal(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
 		require.NoError(t, writer.Seals(mocks.GenericHeight, seals))
@@ -969,9 +1061,11 @@ func TestIntegrationServer_ListSealsForHeight(t *testing.T) {
 
 		storage := storage.New(codec)
 		reader := index.NewReader(db, storage)
-		writer := index.NewWriter(db, storage)
+		writer := index.NewWriter(mocks.NoopLogger, db, storage)
 
 		// Insert mock data in database.
+		require.NoError(t, writer.First(height))
+		require.NoError(t, writer.Last(height))
 		require.NoError(t, writer.Seals(height, seals))
 		require.NoError(t, writer.Close())
 
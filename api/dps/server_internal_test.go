@@ -21,11 +21,14 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/testing/mocks"
 )
 
@@ -161,6 +164,59 @@ func TestServer_GetLast(t *testing.T) {
 	}
 }
 
+func TestServer_GetLast_Sealed(t *testing.T) {
+	finalized := uint64(110)
+	sealed := uint64(107)
+
+	baselineIndex := func(t *testing.T) *mocks.Reader {
+		index := mocks.BaselineReader(t)
+		index.LastFunc = func() (uint64, error) {
+			return finalized, nil
+		}
+		index.SealedFunc = func() (uint64, error) {
+			return sealed, nil
+		}
+		return index
+	}
+
+	t.Run("finalized", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewServer(baselineIndex(t), mocks.BaselineCodec(t))
+
+		res, err := s.GetLast(context.Background(), &GetLastRequest{Sealed: false})
+
+		require.NoError(t, err)
+		assert.Equal(t, finalized, res.Height)
+	})
+
+	t.Run("sealed", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewServer(baselineIndex(t), mocks.BaselineCodec(t))
+
+		res, err := s.GetLast(context.Background(), &GetLastRequest{Sealed: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, sealed, res.Height)
+	})
+
+	t.Run("handles index failure on Sealed", func(t *testing.T) {
+		t.Parallel()
+
+		index := baselineIndex(t)
+		index.SealedFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := NewServer(index, mocks.BaselineCodec(t))
+
+		_, err := s.GetLast(context.Background(), &GetLastRequest{Sealed: true})
+
+		assert.Error(t, err)
+	})
+}
+
 func TestServer_GetHeightForBlock(t *testing.T) {
 	blockID := mocks.GenericHeader.ID()
 	tests := []struct {
@@ -483,6 +539,121 @@ func TestServer_GetEvents(t *testing.T) {
 	}
 }
 
+func TestServer_GetEventsForBlock(t *testing.T) {
+	blockID := mocks.GenericHeader.ID()
+	types := mocks.GenericEventTypes(2)
+	events := mocks.GenericEvents(4)
+
+	// The mocked index returns the events in reverse order, so that we can
+	// check that they are sorted before being encoded.
+	reversed := make([]flow.Event, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		reversed = append(reversed, events[i])
+	}
+
+	tests := []struct {
+		name string
+
+		req *GetEventsForBlockRequest
+
+		mockHeightErr error
+		mockEventsErr error
+
+		wantRes *GetEventsForBlockResponse
+
+		checkErr require.ErrorAssertionFunc
+	}{
+		{
+			name: "nominal case",
+
+			req: &GetEventsForBlockRequest{
+				BlockID: mocks.ByteSlice(blockID),
+				Types:   convert.TypesToStrings(types),
+			},
+
+			wantRes: &GetEventsForBlockResponse{
+				BlockID: mocks.ByteSlice(blockID),
+				Height:  mocks.GenericHeight,
+				Types:   convert.TypesToStrings(types),
+				Data:    mocks.GenericBytes,
+			},
+
+			checkErr: require.NoError,
+		},
+		{
+			name: "handles missing block ID",
+
+			req: &GetEventsForBlockRequest{},
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles height resolution failure",
+
+			req: &GetEventsForBlockRequest{
+				BlockID: mocks.ByteSlice(blockID),
+			},
+
+			mockHeightErr: mocks.GenericError,
+
+			checkErr: require.Error,
+		},
+		{
+			name: "handles events retrieval failure",
+
+			req: &GetEventsForBlockRequest{
+				BlockID: mocks.ByteSlice(blockID),
+			},
+
+			mockEventsErr: mocks.GenericError,
+
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			codec := mocks.BaselineCodec(t)
+			codec.MarshalFunc = func(v interface{}) ([]byte, error) {
+				assert.Equal(t, events, v)
+				return mocks.GenericBytes, nil
+			}
+
+			index := mocks.BaselineReader(t)
+			index.HeightForBlockFunc = func(gotBlockID flow.Identifier) (uint64, error) {
+				assert.Equal(t, blockID, gotBlockID)
+				return mocks.GenericHeight, test.mockHeightErr
+			}
+			index.EventsFunc = func(height uint64, gotTypes ...flow.EventType) ([]flow.Event, error) {
+				assert.Equal(t, mocks.GenericHeight, height)
+				assert.Equal(t, convert.StringsToTypes(test.req.Types), gotTypes)
+
+				// Copy the reversed events so that sorting does not affect
+				// other subtests running in parallel.
+				dup := make([]flow.Event, len(reversed))
+				copy(dup, reversed)
+				return dup, test.mockEventsErr
+			}
+
+			s := Server{
+				codec:    codec,
+				index:    index,
+				validate: validator.New(),
+			}
+
+			gotRes, gotErr := s.GetEventsForBlock(context.Background(), test.req)
+
+			test.checkErr(t, gotErr)
+			if gotErr == nil {
+				assert.Equal(t, test.wantRes, gotRes)
+			}
+		})
+	}
+}
+
 func TestServer_GetRegisterValues(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1218,3 +1389,121 @@ func TestServer_ListSealsForHeight(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_GetBuildInfo(t *testing.T) {
+	s := Server{
+		codec:    mocks.BaselineCodec(t),
+		index:    mocks.BaselineReader(t),
+		validate: validator.New(),
+	}
+
+	gotRes, err := s.GetBuildInfo(context.Background(), &GetBuildInfoRequest{})
+
+	require.NoError(t, err)
+	assert.Equal(t, dps.Version, gotRes.Version)
+	assert.Equal(t, dps.Commit, gotRes.Commit)
+	assert.Equal(t, dps.Date, gotRes.Date)
+}
+
+func TestServer_CheckHeight(t *testing.T) {
+	first := mocks.GenericHeight
+	last := mocks.GenericHeight + 10
+
+	tests := []struct {
+		name string
+
+		height uint64
+
+		wantCode codes.Code
+	}{
+		{
+			name:     "below first",
+			height:   first - 1,
+			wantCode: codes.OutOfRange,
+		},
+		{
+			name:     "at first",
+			height:   first,
+			wantCode: codes.OK,
+		},
+		{
+			name:     "in range",
+			height:   first + 5,
+			wantCode: codes.OK,
+		},
+		{
+			name:     "at last",
+			height:   last,
+			wantCode: codes.OK,
+		},
+		{
+			name:     "above last",
+			height:   last + 1,
+			wantCode: codes.OutOfRange,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			index := mocks.BaselineReader(t)
+			index.FirstFunc = func() (uint64, error) {
+				return first, nil
+			}
+			index.LastFunc = func() (uint64, error) {
+				return last, nil
+			}
+
+			s := Server{
+				codec:    mocks.BaselineCodec(t),
+				index:    index,
+				validate: validator.New(),
+			}
+
+			err := s.checkHeight(test.height)
+
+			assert.Equal(t, test.wantCode, status.Code(err))
+		})
+	}
+
+	t.Run("handles index failure on First", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.FirstFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		s := Server{
+			codec:    mocks.BaselineCodec(t),
+			index:    index,
+			validate: validator.New(),
+		}
+
+		err := s.checkHeight(first)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects out of range height before reading", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			t.Fail()
+			return nil, nil
+		}
+
+		s := Server{
+			codec:    mocks.BaselineCodec(t),
+			index:    index,
+			validate: validator.New(),
+		}
+
+		_, err := s.GetHeader(context.Background(), &GetHeaderRequest{Height: mocks.GenericHeight + 1})
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+}
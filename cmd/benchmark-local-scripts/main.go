@@ -0,0 +1,163 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/invoker"
+	"github.com/optakt/flow-dps/service/metrics"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+//go:embed script.cdc
+var defaultScript []byte
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Signal catching for clean shutdown.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+
+	// Command line parameter initialization.
+	var (
+		flagCache       uint64
+		flagConcurrency uint
+		flagFirst       uint64
+		flagIndex       string
+		flagLast        uint64
+		flagLevel       string
+		flagLogFormat   string
+		flagRepeat      uint
+		flagScript      string
+
+		flagVersion bool
+	)
+
+	pflag.Uint64VarP(&flagCache, "cache-size", "c", 100_000_000, "maximum cache size for register reads in bytes")
+	pflag.UintVarP(&flagConcurrency, "concurrency", "n", 4, "maximum number of scripts executed concurrently")
+	pflag.Uint64VarP(&flagFirst, "first", "f", 0, "first height of the benchmarked range (default first indexed height)")
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.Uint64VarP(&flagLast, "last", "t", 0, "last height of the benchmarked range (default last indexed height)")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.UintVarP(&flagRepeat, "repeat", "r", 1, "number of script executions per height")
+	pflag.StringVarP(&flagScript, "script", "s", "", "path to Cadence script file to execute (default embedded script)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	if flagConcurrency == 0 {
+		log.Error().Msg("concurrency needs to be at least one")
+		return failure
+	}
+
+	// Load the script to benchmark, falling back to the embedded one.
+	script := defaultScript
+	if flagScript != "" {
+		script, err = os.ReadFile(flagScript)
+		if err != nil {
+			log.Error().Str("script", flagScript).Err(err).Msg("could not read script file")
+			return failure
+		}
+	}
+
+	// Initialize the index core state and open database in read-only mode.
+	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer db.Close()
+
+	// Initialize the index reader and the invoker on top of it.
+	codec := zbor.NewCodec()
+	storage := storage.New(codec)
+	read := index.NewReader(db, storage)
+	invoke, err := invoker.New(read, invoker.WithCacheSize(flagCache))
+	if err != nil {
+		log.Error().Err(err).Msg("could not initialize invoker")
+		return failure
+	}
+
+	// Determine the height range, defaulting to the whole index.
+	first, err := read.First()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get first height from index")
+		return failure
+	}
+	last, err := read.Last()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get last height from index")
+		return failure
+	}
+	if flagFirst != 0 {
+		first = flagFirst
+	}
+	if flagLast != 0 {
+		last = flagLast
+	}
+	// The benchmark executes the script across the height range until it is
+	// done or interrupted.
+	bench := metrics.NewBenchmark(log, flagConcurrency, flagRepeat)
+	go func() {
+		<-sig
+		bench.Stop()
+	}()
+
+	_, err = bench.Run(first, last, func(height uint64) error {
+		_, err := invoke.Script(height, script, nil)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("could not run benchmark")
+		return failure
+	}
+
+	return success
+}
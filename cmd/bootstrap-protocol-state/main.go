@@ -0,0 +1,113 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/onflow/flow-go/model/bootstrap"
+	"github.com/onflow/flow-go/storage/badger/operation"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagBootstrap string
+		flagData      string
+		flagLevel     string
+		flagLogFormat string
+
+		flagVersion bool
+	)
+
+	pflag.StringVarP(&flagBootstrap, "bootstrap", "b", "bootstrap", "path to directory with bootstrap information for spork")
+	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Open the protocol state database and the root protocol state snapshot.
+	protocolDB, err := badger.Open(dps.DefaultOptions(flagData))
+	if err != nil {
+		log.Error().Str("data", flagData).Err(err).Msg("could not open protocol state database")
+		return failure
+	}
+	defer func() {
+		err := protocolDB.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close protocol state database")
+		}
+	}()
+	path := filepath.Join(flagBootstrap, bootstrap.PathRootProtocolStateSnapshot)
+	file, err := os.Open(path)
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("could not open protocol state snapshot")
+		return failure
+	}
+	defer file.Close()
+
+	// Bootstrapping the protocol state does nothing if it was already
+	// bootstrapped, so this can safely be run more than once.
+	err = initializer.ProtocolState(file, protocolDB)
+	if err != nil {
+		log.Error().Err(err).Msg("could not initialize protocol state")
+		return failure
+	}
+
+	var root uint64
+	err = protocolDB.View(operation.RetrieveRootHeight(&root))
+	if err != nil {
+		log.Error().Err(err).Msg("could not retrieve root height")
+		return failure
+	}
+
+	log.Info().Uint64("root", root).Msg("protocol state bootstrapped")
+
+	return success
+}
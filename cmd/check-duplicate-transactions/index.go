@@ -28,7 +28,11 @@ import (
 	"github.com/optakt/flow-dps/service/storage"
 )
 
-func indexCheck(log zerolog.Logger, dir string) (map[uint64][]flow.Identifier, error) {
+// indexCheck checks the state index for duplicate transactions between the
+// given begin and end heights, and returns the duplicates per height along with
+// the next height to check. The begin height is raised to the first indexed
+// height if it is below it, and an end height of zero checks all indexed heights.
+func indexCheck(log zerolog.Logger, dir string, begin uint64, end uint64) (map[uint64][]flow.Identifier, uint64, error) {
 
 	// We keep track of the duplicate transactions per height.
 	duplicates := make(map[uint64][]flow.Identifier)
@@ -38,7 +42,7 @@ func indexCheck(log zerolog.Logger, dir string) (map[uint64][]flow.Identifier, e
 	// Open the index database.
 	index, err := badger.Open(dps.DefaultOptions(dir).WithReadOnly(true))
 	if err != nil {
-		return nil, fmt.Errorf("could not open state index (dir: %s): %w", dir, err)
+		return nil, 0, fmt.Errorf("could not open state index (dir: %s): %w", dir, err)
 	}
 	defer index.Close()
 
@@ -49,11 +53,15 @@ func indexCheck(log zerolog.Logger, dir string) (map[uint64][]flow.Identifier, e
 	var first uint64
 	err = index.View(lib.RetrieveFirst(&first))
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve first: %w", err)
+		return nil, 0, fmt.Errorf("could not retrieve first: %w", err)
+	}
+	if begin < first {
+		begin = first
 	}
 
 	// Go through each height, retrieve the transactions from the DB and check for duplicates.
-	for height := first; ; height++ {
+	height := begin
+	for ; end == 0 || height <= end; height++ {
 		seen := make(map[flow.Identifier]struct{})
 
 		log := log.With().Uint64("height", height).Logger()
@@ -65,7 +73,7 @@ func indexCheck(log zerolog.Logger, dir string) (map[uint64][]flow.Identifier, e
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("could not look up transactions (height: %d): %w", height, err)
+			return nil, 0, fmt.Errorf("could not look up transactions (height: %d): %w", height, err)
 		}
 
 		// txID ? duplicate
@@ -91,7 +99,7 @@ func indexCheck(log zerolog.Logger, dir string) (map[uint64][]flow.Identifier, e
 		}
 	}
 
-	log.Info().Msg("index state duplicate check complete")
+	log.Info().Uint64("begin", begin).Uint64("next", height).Msg("index state duplicate check complete")
 
-	return duplicates, nil
+	return duplicates, height, nil
 }
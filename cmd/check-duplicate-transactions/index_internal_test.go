@@ -0,0 +1,124 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestIndexCheck(t *testing.T) {
+	first := mocks.GenericHeight
+	txIDs := mocks.GenericTransactionIDs(3)
+
+	// The index has five heights, with a duplicate transaction at the third
+	// and fifth ones.
+	dir := t.TempDir()
+	db, err := badger.Open(dps.DefaultOptions(dir))
+	require.NoError(t, err)
+	lib := storage.New(zbor.NewCodec())
+	err = db.Update(func(tx *badger.Txn) error {
+		err := lib.SaveFirst(first)(tx)
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < 5; i++ {
+			ids := txIDs[:2]
+			if i == 2 || i == 4 {
+				ids = []flow.Identifier{txIDs[0], txIDs[2], txIDs[0]}
+			}
+			err = lib.IndexTransactionsForHeight(first+i, ids)(tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	t.Run("checks all indexed heights", func(t *testing.T) {
+		duplicates, next, err := indexCheck(mocks.NoopLogger, dir, 0, 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, first+5, next)
+		assert.Equal(t, map[uint64][]flow.Identifier{
+			first + 2: {txIDs[0]},
+			first + 4: {txIDs[0]},
+		}, duplicates)
+	})
+
+	t.Run("checks bounded range", func(t *testing.T) {
+		duplicates, next, err := indexCheck(mocks.NoopLogger, dir, first+1, first+3)
+
+		require.NoError(t, err)
+		assert.Equal(t, first+4, next)
+		assert.Equal(t, map[uint64][]flow.Identifier{
+			first + 2: {txIDs[0]},
+		}, duplicates)
+	})
+
+	t.Run("resumes after last checked height", func(t *testing.T) {
+		begin := resumeHeight(0, first+2)
+
+		duplicates, next, err := indexCheck(mocks.NoopLogger, dir, begin, 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, first+5, next)
+		assert.Equal(t, map[uint64][]flow.Identifier{
+			first + 4: {txIDs[0]},
+		}, duplicates)
+
+		last, ok := lastChecked(begin, []uint64{next})
+		assert.True(t, ok)
+		assert.Equal(t, first+4, last)
+	})
+
+	t.Run("resumes after last indexed height", func(t *testing.T) {
+		begin := resumeHeight(0, first+4)
+
+		duplicates, next, err := indexCheck(mocks.NoopLogger, dir, begin, 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, begin, next)
+		assert.Empty(t, duplicates)
+
+		_, ok := lastChecked(begin, []uint64{next})
+		assert.False(t, ok)
+	})
+
+	t.Run("raises begin height to first indexed height", func(t *testing.T) {
+		_, next, err := indexCheck(mocks.NoopLogger, dir, first-10, first)
+
+		require.NoError(t, err)
+		assert.Equal(t, first+1, next)
+	})
+
+	t.Run("handles missing index", func(t *testing.T) {
+		_, _, err := indexCheck(mocks.NoopLogger, t.TempDir(), 0, 0)
+
+		assert.Error(t, err)
+	})
+}
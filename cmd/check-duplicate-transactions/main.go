@@ -15,13 +15,15 @@
 package main
 
 import (
+	"fmt"
 	"os"
-	"time"
 
-	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 
 	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
 )
 
 const (
@@ -37,26 +39,41 @@ func run() int {
 
 	// Parse the command line arguments.
 	var (
-		flagData  string
-		flagIndex string
-		flagLevel string
+		flagBegin     uint64
+		flagData      string
+		flagEnd       uint64
+		flagIndex     string
+		flagLevel     string
+		flagLogFormat string
+		flagResume    string
+
+		flagVersion bool
 	)
 
+	pflag.Uint64Var(&flagBegin, "begin-height", 0, "first height to check (0 for the root height)")
 	pflag.StringVarP(&flagData, "data", "d", "", "database directory for protocol state")
+	pflag.Uint64Var(&flagEnd, "end-height", 0, "last height to check (0 for the last available height)")
 	pflag.StringVarP(&flagIndex, "index", "i", "", "database directory for state index")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.StringVar(&flagResume, "resume-from", "", "file in which to persist the last checked height, to resume checking after it on the next run")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
 
 	pflag.Parse()
 
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
 	// Initialize the logger.
-	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
-	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
-	level, err := zerolog.ParseLevel(flagLevel)
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
 	if err != nil {
-		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
 		return failure
 	}
-	log = log.Level(level)
 
 	// We should have at least one of data or index directories.
 	if flagData == "" && flagIndex == "" {
@@ -64,13 +81,38 @@ func run() int {
 		return failure
 	}
 
-	// Only check the protocol state if a directory for it is given.
+	// If we have a progress file from a previous run, we resume after the last
+	// height that was checked.
+	begin := flagBegin
+	if flagResume != "" {
+		last, ok, err := readProgress(flagResume)
+		if err != nil {
+			log.Error().Str("resume_from", flagResume).Err(err).Msg("could not read progress")
+			return failure
+		}
+		if ok && resumeHeight(begin, last) != begin {
+			log.Info().Uint64("last", last).Msg("resuming duplicate check after last checked height")
+			begin = resumeHeight(begin, last)
+		}
+	}
+
+	// Make sure we have a valid range of heights to check.
+	if flagEnd != 0 && begin > flagEnd {
+		log.Error().Uint64("begin", begin).Uint64("end", flagEnd).Msg("begin height is above end height")
+		return failure
+	}
+
+	// Only check the protocol state if a directory for it is given. We keep
+	// track of the next height to check for each database, so that we only
+	// persist a height as checked once it has been checked on all of them.
+	var nexts []uint64
 	if flagData != "" {
-		err = protocolCheck(log, flagData)
+		next, err := protocolCheck(log, flagData, begin, flagEnd)
 		if err != nil {
 			log.Error().Err(err).Msg("could not execute protocol state duplicate check")
 			return failure
 		}
+		nexts = append(nexts, next)
 	}
 
 	// This keeps track of heights on the state index that have duplicate
@@ -79,11 +121,13 @@ func run() int {
 	// Only check the state index if a directory for it is given.
 	var duplicates map[uint64][]flow.Identifier
 	if flagIndex != "" {
-		duplicates, err = indexCheck(log, flagIndex)
+		var next uint64
+		duplicates, next, err = indexCheck(log, flagIndex, begin, flagEnd)
 		if err != nil {
 			log.Error().Err(err).Msg("could not execute state index duplicate check")
 			return failure
 		}
+		nexts = append(nexts, next)
 	}
 
 	// If we have both a protocol state and a state index database, we can check
@@ -96,5 +140,20 @@ func run() int {
 		}
 	}
 
+	// Persist the last height that was checked on all databases, if any.
+	if flagResume != "" {
+		last, ok := lastChecked(begin, nexts)
+		if !ok {
+			log.Info().Msg("no heights checked, keeping progress unchanged")
+			return success
+		}
+		err = writeProgress(flagResume, last)
+		if err != nil {
+			log.Error().Str("resume_from", flagResume).Err(err).Msg("could not write progress")
+			return failure
+		}
+		log.Info().Uint64("last", last).Msg("persisted last checked height")
+	}
+
 	return success
 }
@@ -0,0 +1,87 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readProgress reads the last checked height from the given file. It returns
+// false if the file does not exist yet.
+func readProgress(path string) (uint64, bool, error) {
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("could not read progress file: %w", err)
+	}
+
+	last, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not parse last checked height: %w", err)
+	}
+
+	return last, true, nil
+}
+
+// writeProgress writes the last checked height to the given file.
+func writeProgress(path string, last uint64) error {
+
+	data := strconv.FormatUint(last, 10) + "\n"
+	err := os.WriteFile(path, []byte(data), 0644)
+	if err != nil {
+		return fmt.Errorf("could not write progress file: %w", err)
+	}
+
+	return nil
+}
+
+// resumeHeight returns the height from which to check, given the begin height
+// and the last height checked by a previous run. Checking resumes after the
+// last checked height, unless the begin height is already above it.
+func resumeHeight(begin uint64, last uint64) uint64 {
+	if last+1 > begin {
+		return last + 1
+	}
+	return begin
+}
+
+// lastChecked returns the last height that was checked on all databases, given
+// the height that checking began at and the next height to check for each of
+// the databases. It returns false if no height was checked on all of them.
+func lastChecked(begin uint64, nexts []uint64) (uint64, bool) {
+
+	if len(nexts) == 0 {
+		return 0, false
+	}
+
+	next := nexts[0]
+	for _, n := range nexts[1:] {
+		if n < next {
+			next = n
+		}
+	}
+	if next <= begin {
+		return 0, false
+	}
+
+	return next - 1, true
+}
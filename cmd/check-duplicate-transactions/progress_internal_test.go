@@ -0,0 +1,134 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "progress")
+
+		err := writeProgress(path, 42)
+		require.NoError(t, err)
+
+		last, ok, err := readProgress(path)
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(42), last)
+	})
+
+	t.Run("overwrites previous progress", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "progress")
+
+		require.NoError(t, writeProgress(path, 1337))
+		require.NoError(t, writeProgress(path, 7))
+
+		last, ok, err := readProgress(path)
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(7), last)
+	})
+
+	t.Run("handles missing progress file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "progress")
+
+		last, ok, err := readProgress(path)
+
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Zero(t, last)
+	})
+
+	t.Run("handles corrupt progress file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "progress")
+		require.NoError(t, os.WriteFile(path, []byte("not a height\n"), 0644))
+
+		_, _, err := readProgress(path)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles empty progress file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "progress")
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+
+		_, _, err := readProgress(path)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles unwritable progress file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "missing", "progress")
+
+		err := writeProgress(path, 42)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestResumeHeight(t *testing.T) {
+	assert.Equal(t, uint64(11), resumeHeight(0, 10))
+	assert.Equal(t, uint64(11), resumeHeight(5, 10))
+	assert.Equal(t, uint64(11), resumeHeight(11, 10))
+	assert.Equal(t, uint64(20), resumeHeight(20, 10))
+}
+
+func TestLastChecked(t *testing.T) {
+	tests := []struct {
+		name   string
+		begin  uint64
+		nexts  []uint64
+		wantOK bool
+		want   uint64
+	}{
+		{name: "single database", begin: 10, nexts: []uint64{15}, wantOK: true, want: 14},
+		{name: "lowest of all databases", begin: 10, nexts: []uint64{15, 12}, wantOK: true, want: 11},
+		{name: "nothing checked on one database", begin: 10, nexts: []uint64{15, 10}, wantOK: false},
+		{name: "no databases", begin: 10, nexts: nil, wantOK: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := lastChecked(test.begin, test.nexts)
+
+			assert.Equal(t, test.wantOK, ok)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
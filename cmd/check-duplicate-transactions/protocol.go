@@ -28,14 +28,18 @@ import (
 	"github.com/optakt/flow-dps/models/dps"
 )
 
-func protocolCheck(log zerolog.Logger, dir string) error {
+// protocolCheck checks the protocol state for duplicate transactions between
+// the given begin and end heights, and returns the next height to check. The
+// begin height is raised to the root height if it is below it, and an end
+// height of zero checks all available heights.
+func protocolCheck(log zerolog.Logger, dir string, begin uint64, end uint64) (uint64, error) {
 
 	log.Info().Str("data", dir).Msg("starting protocol state duplicate check")
 
 	// Open the protocol state database.
 	protocol, err := badger.Open(dps.DefaultOptions(dir).WithReadOnly(true))
 	if err != nil {
-		return fmt.Errorf("could not open protocol state (dir: %s): %w", dir, err)
+		return 0, fmt.Errorf("could not open protocol state (dir: %s): %w", dir, err)
 	}
 	defer protocol.Close()
 
@@ -43,11 +47,15 @@ func protocolCheck(log zerolog.Logger, dir string) error {
 	var root uint64
 	err = protocol.View(operation.RetrieveRootHeight(&root))
 	if err != nil {
-		return fmt.Errorf("could not retrieve root height: %w", err)
+		return 0, fmt.Errorf("could not retrieve root height: %w", err)
+	}
+	if begin < root {
+		begin = root
 	}
 
 	// Go through each height, retrieve the transactions from the DB and check for duplicates.
-	for height := root; ; height++ {
+	height := begin
+	for ; end == 0 || height <= end; height++ {
 		seen := make(map[flow.Identifier]flow.Identifier)
 
 		log := log.With().Uint64("height", height).Logger()
@@ -59,7 +67,7 @@ func protocolCheck(log zerolog.Logger, dir string) error {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("could not look up block (height: %d): %w", height, err)
+			return 0, fmt.Errorf("could not look up block (height: %d): %w", height, err)
 		}
 
 		log = log.With().Hex("block", blockID[:]).Logger()
@@ -68,7 +76,7 @@ func protocolCheck(log zerolog.Logger, dir string) error {
 		var collIDs []flow.Identifier
 		err = protocol.View(operation.LookupPayloadGuarantees(blockID, &collIDs))
 		if err != nil {
-			return fmt.Errorf("could not look up payload guarantees (block: %x): %w", blockID, err)
+			return 0, fmt.Errorf("could not look up payload guarantees (block: %x): %w", blockID, err)
 		}
 
 		for _, collID := range collIDs {
@@ -79,7 +87,7 @@ func protocolCheck(log zerolog.Logger, dir string) error {
 			var collection flow.LightCollection
 			err := protocol.View(operation.RetrieveCollection(collID, &collection))
 			if err != nil {
-				return fmt.Errorf("could not retrieve collection (%x): %w", collID, err)
+				return 0, fmt.Errorf("could not retrieve collection (%x): %w", collID, err)
 			}
 
 			// txID ? duplicate
@@ -99,7 +107,7 @@ func protocolCheck(log zerolog.Logger, dir string) error {
 		}
 	}
 
-	log.Info().Msg("protocol state duplicate check complete")
+	log.Info().Uint64("begin", begin).Uint64("next", height).Msg("protocol state duplicate check complete")
 
-	return nil
+	return height, nil
 }
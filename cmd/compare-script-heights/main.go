@@ -0,0 +1,180 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/encoding/json"
+
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/invoker"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Signal catching for clean shutdown.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+
+	// Command line parameter initialization.
+	var (
+		flagAPI       string
+		flagCache     uint64
+		flagHeights   []uint
+		flagLevel     string
+		flagLogFormat string
+		flagParams    string
+		flagScript    string
+
+		flagCompress    bool
+		flagKeepalive   time.Duration
+		flagReadRetries uint
+		flagRetries     uint
+		flagVersion     bool
+	)
+
+	pflag.StringVarP(&flagAPI, "api", "a", "", "host for GRPC API server")
+	pflag.Uint64VarP(&flagCache, "cache", "e", 1_000_000_000, "maximum cache size for register reads in bytes")
+	pflag.UintSliceVarP(&flagHeights, "heights", "h", nil, "comma-separated list of block heights to execute the script at")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.StringVarP(&flagParams, "params", "p", "", "comma-separated list of Cadence parameters")
+	pflag.StringVarP(&flagScript, "script", "s", "script.cdc", "path to file with Cadence script")
+
+	pflag.BoolVar(&flagCompress, "compress", false, "request gzip compression of API requests and responses")
+	pflag.DurationVar(&flagKeepalive, "keepalive", 30*time.Second, "interval for keepalive pings on the API connection (0s for disabled)")
+	pflag.UintVar(&flagReadRetries, "read-retries", 3, "maximum number of retries for register reads that time out or are aborted by the API (0 for disabled)")
+	pflag.UintVar(&flagRetries, "retries", 5, "maximum number of retries for API requests while the API is unavailable (0 for disabled)")
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Validate the command line parameters.
+	if flagAPI == "" {
+		log.Error().Msg("API host must be specified")
+		return failure
+	}
+	if len(flagHeights) == 0 {
+		log.Error().Msg("at least one height must be specified")
+		return failure
+	}
+	heights := make([]uint64, 0, len(flagHeights))
+	for _, height := range flagHeights {
+		heights = append(heights, uint64(height))
+	}
+
+	// Initialize the API client. It keeps the connection alive and retries
+	// requests, so that transient network failures are recovered from.
+	conn, err := api.Dial(flagAPI,
+		api.WithKeepalive(flagKeepalive, api.DefaultDialConfig.KeepaliveTimeout),
+		api.WithRetries(flagRetries, api.DefaultDialConfig.RetryBackoff),
+		api.WithCompression(flagCompress),
+	)
+	if err != nil {
+		log.Error().Str("api", flagAPI).Err(err).Msg("could not dial API host")
+		return failure
+	}
+	defer conn.Close()
+
+	// Read the script.
+	script, err := os.ReadFile(flagScript)
+	if err != nil {
+		log.Error().Str("script", flagScript).Err(err).Msg("could not read script")
+		return failure
+	}
+
+	// Decode the arguments
+	var args []cadence.Value
+	if flagParams != "" {
+		params := strings.Split(flagParams, ",")
+		for _, param := range params {
+			arg, err := convert.ParseCadenceArgument(param)
+			if err != nil {
+				log.Error().Err(err).Msg("invalid Cadence value")
+				return failure
+			}
+			args = append(args, arg)
+		}
+	}
+
+	// Initialize codec.
+	codec := zbor.NewCodec()
+
+	// Execute the script at all heights using remote lookup and read.
+	client := api.NewAPIClient(conn)
+	invoke, err := invoker.New(api.IndexFromAPI(client, codec),
+		invoker.WithCacheSize(flagCache),
+		invoker.WithReadRetries(flagReadRetries, api.DefaultDialConfig.RetryBackoff),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("could not initialize invoker")
+		return failure
+	}
+	results, err := invoke.Compare(heights, script, args)
+	if err != nil {
+		log.Error().Err(err).Msg("could not compare script results")
+		return failure
+	}
+
+	// Print the results side by side, with changed results marked.
+	for _, result := range results {
+		output, err := json.Encode(result.Value)
+		if err != nil {
+			log.Error().Uint64("height", result.Height).Err(err).Msg("could not encode result")
+			return failure
+		}
+		marker := " "
+		if result.Changed {
+			marker = "*"
+		}
+		fmt.Printf("%s %d %s\n", marker, result.Height, strings.TrimSpace(string(output)))
+	}
+
+	return success
+}
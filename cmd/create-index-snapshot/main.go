@@ -18,16 +18,16 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
-	"time"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/klauspost/compress/zstd"
-	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 
 	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
 )
 
 const (
@@ -58,17 +58,34 @@ func run() int {
 		flagCompression string
 		flagEncoding    string
 		flagIndex       string
+		flagLevel       string
+		flagLogFormat   string
+
+		flagVersion bool
 	)
 
 	pflag.StringVarP(&flagCompression, "compression", "c", compressionZstd, "compression algorithm (\"none\", \"zstd\" or \"gzip\")")
 	pflag.StringVarP(&flagEncoding, "encoding", "e", encodingNone, "output encoding (\"none\", \"hex\" or \"base64\")")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
 
 	pflag.Parse()
 
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
 	// Initialize the logger.
-	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
-	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
 
 	// Open the index database.
 	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
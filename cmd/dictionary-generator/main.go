@@ -15,18 +15,18 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 	"runtime"
-	"time"
 
 	"github.com/dgraph-io/badger/v2"
-	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 
 	"github.com/optakt/flow-dps/codec/generator"
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
 )
 
 const (
@@ -45,36 +45,48 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
+		flagCount          int
 		flagDictionaryPath string
 		flagIndex          string
 		flagLevel          string
+		flagLogFormat      string
 		flagSamplePath     string
+		flagSeed           int64
 		flagStartSize      int
 		flagTolerance      float64
+
+		flagVersion bool
 	)
 
+	pflag.IntVar(&flagCount, "count", 0, "maximum number of samples used to train each dictionary (0 for no count limit)")
 	pflag.StringVar(&flagDictionaryPath, "dictionary-path", "./codec/zbor", "path to the package in which to write dictionaries")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
 	pflag.StringVar(&flagSamplePath, "sample-path", "", "path to the directory in which to store samples for dictionary training (temporary folder when left empty)")
+	pflag.Int64Var(&flagSeed, "seed", 0, "seed for the random selection of samples (0 for a time-based seed)")
 	pflag.IntVar(&flagStartSize, "start-size", 512, "minimum dictionary size in bytes to generate (will be doubled on each iteration)")
 	pflag.Float64Var(&flagTolerance, "tolerance", 0.1, "compression ratio increase tolerance, between 0 and 1")
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
 
 	pflag.Parse()
 
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
 	// Increase the GOMAXPROCS value in order to use the full IOPS available, see:
 	// https://groups.google.com/g/golang-nuts/c/jPb_h3TvlKE
 	_ = runtime.GOMAXPROCS(128)
 
 	// Logger initialization.
-	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
-	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
-	level, err := zerolog.ParseLevel(flagLevel)
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
 	if err != nil {
-		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
 		return failure
 	}
-	log = log.Level(level)
 
 	// Initialize the index core state and open database in read-only mode.
 	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
@@ -101,6 +113,8 @@ func run() int {
 		generator.WithSamplePath(samplePath),
 		generator.WithRatioImprovementTolerance(flagTolerance),
 		generator.WithStartSize(flagStartSize),
+		generator.WithSeed(flagSeed),
+		generator.WithSampleCount(flagCount),
 	)
 
 	err = generate.Dictionary(generator.KindPayloads)
@@ -121,5 +135,11 @@ func run() int {
 		return failure
 	}
 
+	err = generate.Dictionary(generator.KindResults)
+	if err != nil {
+		log.Error().Err(err).Msg("could not generate results dictionary")
+		return failure
+	}
+
 	return success
 }
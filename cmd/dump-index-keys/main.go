@@ -0,0 +1,118 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagHeight    uint64
+		flagIndex     string
+		flagLevel     string
+		flagLogFormat string
+		flagLimit     int
+		flagPrefix    string
+
+		flagVersion bool
+	)
+
+	pflag.Uint64VarP(&flagHeight, "height", "h", 0, "only dump keys for the given height, for keys starting with a height")
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.IntVarP(&flagLimit, "limit", "n", 100, "maximum number of keys to dump (0 for unlimited)")
+	pflag.StringVarP(&flagPrefix, "prefix", "p", "", "name of the key prefix to dump (e.g. header, events, payload)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Build the key prefix to iterate over from the prefix name and the
+	// optional height, which is only valid for keys that start with a height.
+	prefix, ok := storage.PrefixByName(flagPrefix)
+	if !ok {
+		log.Error().Str("prefix", flagPrefix).Msg("unknown key prefix")
+		return failure
+	}
+	key := storage.EncodeKey(prefix)
+	if pflag.CommandLine.Changed("height") {
+		switch prefix {
+		case storage.PrefixCommit, storage.PrefixHeader, storage.PrefixEvents,
+			storage.PrefixTransactionsForHeight, storage.PrefixCollectionsForHeight, storage.PrefixSealsForHeight,
+			storage.PrefixFailure, storage.PrefixClasses:
+			key = storage.EncodeKey(prefix, flagHeight)
+		default:
+			log.Error().Str("prefix", flagPrefix).Msg("height filter not supported for key prefix")
+			return failure
+		}
+	}
+
+	// Open the index database in read-only mode.
+	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer db.Close()
+
+	var infos []storage.KeyInfo
+	err = db.View(storage.DumpKeys(key, flagLimit, &infos))
+	if err != nil {
+		log.Error().Err(err).Msg("could not dump keys")
+		return failure
+	}
+
+	for _, info := range infos {
+		fmt.Println(info)
+	}
+
+	log.Info().Int("keys", len(infos)).Msg("index keys dumped")
+
+	return success
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagBlocks    uint64
+		flagFirst     uint64
+		flagIndex     string
+		flagLast      uint64
+		flagLevel     string
+		flagLogFormat string
+
+		flagVersion bool
+	)
+
+	pflag.Uint64VarP(&flagBlocks, "blocks", "b", 0, "number of blocks to project the storage for")
+	pflag.Uint64VarP(&flagFirst, "first", "f", 0, "first height of the sampled range (0 for the last 1000 indexed heights)")
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.Uint64Var(&flagLast, "last", 0, "last height of the sampled range (0 for the last indexed height)")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	if flagBlocks == 0 {
+		log.Error().Msg("number of blocks to project for must be greater than zero")
+		return failure
+	}
+
+	// Open the index database in read-only mode.
+	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer db.Close()
+
+	// Determine the sampled height range, which has to be within the indexed
+	// height range.
+	lib := storage.New(zbor.NewCodec())
+	var first, last uint64
+	err = db.View(lib.RetrieveFirst(&first))
+	if err != nil {
+		log.Error().Err(err).Msg("could not retrieve first height")
+		return failure
+	}
+	err = db.View(lib.RetrieveLast(&last))
+	if err != nil {
+		log.Error().Err(err).Msg("could not retrieve last height")
+		return failure
+	}
+	if flagLast == 0 {
+		flagLast = last
+	}
+	if flagFirst == 0 && flagLast >= first+999 {
+		flagFirst = flagLast - 999
+	}
+	if flagFirst < first {
+		flagFirst = first
+	}
+	if flagLast > last || flagFirst > flagLast {
+		log.Error().
+			Uint64("first", flagFirst).
+			Uint64("last", flagLast).
+			Uint64("indexed_first", first).
+			Uint64("indexed_last", last).
+			Msg("sampled height range is not within indexed height range")
+		return failure
+	}
+
+	log.Info().Uint64("first", flagFirst).Uint64("last", flagLast).Msg("sampling index")
+
+	var sizes storage.Sizes
+	err = db.View(lib.SampleSizes(flagFirst, flagLast, &sizes))
+	if err != nil {
+		log.Error().Err(err).Msg("could not sample index sizes")
+		return failure
+	}
+	estimates := sizes.Project(flagBlocks)
+
+	classes := []struct {
+		name  string
+		class dps.Classes
+	}{
+		{name: "headers", class: dps.ClassHeaders},
+		{name: "registers", class: dps.ClassRegisters},
+		{name: "events", class: dps.ClassEvents},
+		{name: "transactions", class: dps.ClassTransactions},
+		{name: "results", class: dps.ClassResults},
+	}
+
+	fmt.Printf("sampled heights:   %d to %d (%d blocks)\n", flagFirst, flagLast, sizes.Blocks)
+	fmt.Printf("projected blocks:  %d\n", flagBlocks)
+	fmt.Println()
+
+	fmt.Printf("%-16s %16s %12s %20s\n", "class", "bytes per block", "ratio", "projected bytes")
+	var perBlock float64
+	var total uint64
+	for _, class := range classes {
+		estimate := estimates[class.class]
+		fmt.Printf("%-16s %16.1f %12.3f %20d\n", class.name, estimate.PerBlock, estimate.Ratio, estimate.Total)
+		perBlock += estimate.PerBlock
+		total += estimate.Total
+	}
+	fmt.Printf("%-16s %16.1f %12s %20d\n", "total", perBlock, "", total)
+
+	return success
+}
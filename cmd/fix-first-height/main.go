@@ -0,0 +1,136 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagHeight    uint64
+		flagIndex     string
+		flagLevel     string
+		flagLogFormat string
+
+		flagVersion bool
+	)
+
+	pflag.Uint64VarP(&flagHeight, "height", "h", 0, "first height to persist (lowest height with indexed data when zero)")
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Open the index database.
+	db, err := badger.Open(dps.DefaultOptions(flagIndex))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index database")
+		return failure
+	}
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close index database")
+		}
+	}()
+
+	codec := zbor.NewCodec()
+	storage := storage.New(codec)
+	read := index.NewReader(db, storage)
+
+	// If no height was given, we use the lowest height for which data was
+	// indexed, which is what the first height should be.
+	height := flagHeight
+	if height == 0 {
+		height, err = read.Lowest()
+		if err != nil {
+			log.Error().Err(err).Msg("could not get lowest indexed height")
+			return failure
+		}
+	}
+
+	// We refuse to persist a first height that does not match the data that
+	// is actually in the index.
+	err = read.ValidateFirst(height)
+	if err != nil {
+		log.Error().Uint64("height", height).Err(err).Msg("invalid first height")
+		return failure
+	}
+
+	previous, err := read.First()
+	if err != nil {
+		log.Warn().Err(err).Msg("could not get previous first height")
+	}
+	if err == nil && previous == height {
+		log.Info().Uint64("first", height).Msg("first height already correct")
+		return success
+	}
+
+	// Closing the writer makes sure that the first height is committed.
+	write := index.NewWriter(log, db, storage)
+	err = write.First(height)
+	if err != nil {
+		log.Error().Err(err).Msg("could not write first height")
+		_ = write.Close()
+		return failure
+	}
+	err = write.Close()
+	if err != nil {
+		log.Error().Err(err).Msg("could not close index writer")
+		return failure
+	}
+
+	log.Info().Uint64("previous", previous).Uint64("first", height).Msg("first height fixed")
+
+	return success
+}
@@ -0,0 +1,382 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/tsdb/wal"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+
+	grpczerolog "github.com/grpc-ecosystem/go-grpc-middleware/providers/zerolog/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/engine"
+	"github.com/optakt/flow-dps/ledger/forest"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/chain"
+	"github.com/optakt/flow-dps/service/feeder"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/loader"
+	"github.com/optakt/flow-dps/service/mapper"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Signal catching for clean shutdown. The mapper is interrupted directly as
+	// well, so that it stops its ongoing transition right away, rather than
+	// once the engine gets to stopping it.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Command line parameter initialization.
+	var (
+		flagAddress    string
+		flagCheckpoint string
+		flagContinue   bool
+		flagData       string
+		flagIndex      string
+		flagLevel      string
+		flagLogFormat  string
+		flagLogSample  uint32
+		flagResume     uint64
+		flagTrie       string
+		flagSkip       bool
+
+		flagChain         string
+		flagFlushInterval time.Duration
+		flagMaxProcs      int
+		flagMaxValueSize  uint64
+		flagReadAhead     uint
+		flagSkipCorrupt   bool
+		flagSkipIndexed   bool
+		flagShutdown      time.Duration
+		flagVersion       bool
+	)
+
+	pflag.StringVarP(&flagAddress, "address", "a", "127.0.0.1:5005", "bind address for serving DPS API")
+	pflag.StringVarP(&flagCheckpoint, "checkpoint", "c", "", "path to root checkpoint file for execution state trie")
+	pflag.BoolVarP(&flagContinue, "continue-on-error", "e", false, "record blocks whose execution data is missing or invalid and continue with the next block")
+	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.Uint32Var(&flagLogSample, "log-sample", 0, "log only one out of this many per-block debug and info messages of the mapper (0 for all)")
+	pflag.Uint64VarP(&flagResume, "resume-height", "r", 0, "indexed height from which to resume indexing (bootstraps from checkpoint when zero)")
+	pflag.StringVarP(&flagTrie, "trie", "t", "", "path to data directory for execution state ledger")
+	pflag.BoolVarP(&flagSkip, "skip", "s", false, "skip indexing of execution state ledger registers")
+
+	pflag.StringVar(&flagChain, "chain", "", "expected chain ID of the indexed data, e.g. flow-mainnet (detected chain ID is only logged when left empty)")
+	pflag.DurationVar(&flagFlushInterval, "flush-interval", 1*time.Second, "interval for flushing badger transactions (0s for disabled)")
+	pflag.IntVar(&flagMaxProcs, "max-procs", 0, "maximum number of CPUs executing simultaneously (0 for the Go runtime default)")
+	pflag.Uint64Var(&flagMaxValueSize, "max-value-size", mapper.DefaultConfig.MaxValueSize, "maximum size in bytes of a single register value (0 for unlimited)")
+	pflag.UintVar(&flagReadAhead, "read-ahead", 0, "number of trie updates to read ahead from the write-ahead log (0 for disabled)")
+	pflag.BoolVar(&flagSkipCorrupt, "skip-corrupt", false, "skip corrupt write-ahead log segments by resuming from the next checkpoint instead of failing")
+	pflag.BoolVar(&flagSkipIndexed, "skip-indexed", false, "when resuming, skip replayed heights that were completely indexed with the same state commitment")
+	pflag.DurationVar(&flagShutdown, "shutdown-timeout", 30*time.Second, "maximum duration of graceful shutdown before forcing exit (0s for disabled)")
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Only override the GOMAXPROCS value if explicitly configured. Increasing
+	// it can help to use the full IOPS available on large hosts, see:
+	// https://groups.google.com/g/golang-nuts/c/jPb_h3TvlKE
+	_ = dps.SetMaxProcs(flagMaxProcs)
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Open the needed databases. The index database is shared between the
+	// mapper, which writes to it, and the DPS API, which reads from it, so we
+	// do not need any coordination between separate processes.
+	indexDB, err := badger.Open(dps.DefaultOptions(flagIndex))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index database")
+		return failure
+	}
+	defer func() {
+		err := indexDB.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close index database")
+		}
+	}()
+	protocolDB, err := badger.Open(dps.DefaultOptions(flagData))
+	if err != nil {
+		log.Error().Err(err).Msg("could not open protocol state database")
+		return failure
+	}
+	defer func() {
+		err := protocolDB.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close protocol state database")
+		}
+	}()
+
+	// The storage library is initialized with a codec and provides functions to
+	// interact with a Badger database while encoding and compressing
+	// transparently.
+	codec := zbor.NewCodec()
+	storage := storage.New(codec)
+
+	// Check if index already exists.
+	read := index.NewReader(indexDB, storage)
+	_, err = read.First()
+	empty := errors.Is(err, badger.ErrKeyNotFound)
+	if err != nil && !empty {
+		log.Error().Err(err).Msg("could not get first height from index reader")
+		return failure
+	}
+	if empty && flagResume != 0 {
+		log.Error().Msg("index doesn't exist, cannot resume from height (-r, --resume-height)")
+		return failure
+	}
+	if empty && flagCheckpoint == "" {
+		log.Error().Msg("index doesn't exist, please provide root checkpoint (-c, --checkpoint) to bootstrap")
+		return failure
+	}
+
+	// An existing index may have been written by a different release, so we
+	// bring older indexes up to the current schema version, and make sure that
+	// we can use it before writing anything.
+	if !empty {
+		applied, err := storage.Migrate(indexDB, zbor.Fingerprint())
+		if err != nil {
+			log.Error().Err(err).Msg("could not migrate index")
+			return failure
+		}
+		for _, migration := range applied {
+			log.Info().Uint32("version", migration.Version).Str("description", migration.Description).Msg("applied index migration")
+		}
+	}
+	err = read.Compatible()
+	if err != nil {
+		log.Error().Err(err).Msg("could not use existing index")
+		return failure
+	}
+
+	// The chain is responsible for reading blockchain data from the protocol state.
+	disk := chain.FromDisk(protocolDB)
+
+	// If an expected chain is given, we make sure that the protocol state
+	// belongs to it before indexing anything, so that we don't mix up the data
+	// of different chains. Otherwise, we only log the detected chain.
+	chainID := flow.ChainID(flagChain)
+	if flagChain != "" {
+		err = chain.Validate(disk, chainID)
+		if err != nil {
+			log.Error().Str("chain", flagChain).Err(err).Msg("could not validate chain of protocol state")
+			return failure
+		}
+	} else {
+		chainID, err = chain.ID(disk)
+		if err != nil {
+			log.Error().Err(err).Msg("could not detect chain of protocol state")
+			return failure
+		}
+		log.Info().Str("chain", chainID.String()).Msg("detected chain of protocol state")
+	}
+
+	// Feeder is responsible for reading the write-ahead log of the execution state.
+	segments, err := wal.NewSegmentsReader(flagTrie)
+	if err != nil {
+		log.Error().Str("trie", flagTrie).Err(err).Msg("could not open segments reader")
+		return failure
+	}
+	feedOptions := []feeder.Option{
+		feeder.WithReadAhead(flagReadAhead),
+	}
+	if flagSkipCorrupt {
+		feedOptions = append(feedOptions, feeder.WithSkipCorrupt(feeder.FromDirectory(flagTrie)))
+	}
+	feed := feeder.FromWAL(log, wal.NewReader(segments), feedOptions...)
+
+	// Writer is responsible for writing the index data to the index database.
+	// Unlike the standalone indexer, we flush at regular intervals, so that
+	// indexed heights become available on the DPS API with little latency.
+	write := index.NewWriter(log, indexDB, storage,
+		index.WithFlushInterval(flagFlushInterval),
+	)
+	defer func() {
+		err := write.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close index")
+		}
+	}()
+
+	// When bootstrapping a new index, we record its metadata first, so that
+	// binaries opening it later on can check whether they are compatible.
+	if empty {
+		meta := dps.Metadata{
+			SchemaVersion: dps.SchemaVersion,
+			Dictionary:    zbor.Fingerprint(),
+			ChainID:       chainID,
+			Created:       time.Now().UTC(),
+		}
+		err = write.Metadata(meta)
+		if err != nil {
+			log.Error().Err(err).Msg("could not write index metadata")
+			return failure
+		}
+	}
+
+	// Initialize the transitions with the dependencies and add them to the FSM.
+	// When resuming from an indexed height, the trie is restored from the
+	// registers indexed up to that height, and the mapper validates it against
+	// the state commitment indexed for that height.
+	load := mapper.Loader(loader.FromScratch())
+	options := []mapper.Option{
+		mapper.WithBootstrapState(true),
+		mapper.WithSkipRegisters(flagSkip),
+		mapper.WithLogSample(flagLogSample),
+		mapper.WithMaxValueSize(flagMaxValueSize),
+		mapper.WithContinueOnError(flagContinue),
+	}
+	if flagResume != 0 {
+		load = loader.FromIndex(log, storage, indexDB,
+			loader.WithExclude(loader.ExcludeAbove(flagResume)),
+		)
+		options = append(options, mapper.WithInitialHeight(flagResume))
+	}
+	if flagSkipIndexed {
+		options = append(options, mapper.WithSkipIndexed(read))
+	}
+
+	transitions := mapper.NewTransitions(log, load, disk, feed, read, write, options...)
+	tries := forest.New()
+	state := mapper.EmptyState(tries)
+	fsm := mapper.NewFSM(state,
+		mapper.WithTransition(mapper.StatusInitialize, transitions.InitializeMapper),
+		mapper.WithTransition(mapper.StatusBootstrap, transitions.BootstrapState),
+		mapper.WithTransition(mapper.StatusResume, transitions.ResumeIndexing),
+		mapper.WithTransition(mapper.StatusIndex, transitions.IndexChain),
+		mapper.WithTransition(mapper.StatusUpdate, transitions.UpdateTree),
+		mapper.WithTransition(mapper.StatusCollect, transitions.CollectRegisters),
+		mapper.WithTransition(mapper.StatusMap, transitions.MapRegisters),
+		mapper.WithTransition(mapper.StatusForward, transitions.ForwardHeight),
+	)
+
+	// Next, we initialize the GRPC server that will serve the DPS API on top of
+	// the index database that is written to by the mapper.
+	logOpts := []logging.Option{
+		logging.WithLevels(logging.DefaultServerCodeToLevel),
+	}
+	interceptor := grpczerolog.InterceptorLogger(log.With().Str("engine", "grpc_server").Logger())
+	gsvr := grpc.NewServer(
+		api.KeepaliveEnforcement(),
+		grpc.ChainUnaryInterceptor(
+			tags.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(interceptor, logOpts...),
+		),
+		grpc.ChainStreamInterceptor(
+			tags.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(interceptor, logOpts...),
+		),
+	)
+	server := api.NewServer(read, codec)
+
+	listener, err := net.Listen("tcp", flagAddress)
+	if err != nil {
+		log.Error().Str("address", flagAddress).Err(err).Msg("could not create listener")
+		return failure
+	}
+
+	// The mapper finishes once it has indexed all of the spork's data, but we
+	// want to keep serving the index afterwards, so it only returns once it is
+	// explicitly stopped.
+	stopped := make(chan struct{})
+	err = engine.New(log, "Flow DPS Archive", sig).
+		ShutdownTimeout(flagShutdown).
+		Component(
+			"api",
+			func() error {
+				api.RegisterAPIServer(gsvr, server)
+
+				err := gsvr.Serve(listener)
+				if err == grpc.ErrServerStopped {
+					log.Debug().Msg("grpc server stopped")
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				return nil
+			},
+			func() {
+				gsvr.GracefulStop()
+			},
+		).
+		Component(
+			"mapper",
+			func() error {
+				err := fsm.RunWithContext(interrupted)
+				if err != nil {
+					return err
+				}
+				if interrupted.Err() == nil {
+					log.Info().Msg("indexing finished, serving index until stopped")
+				}
+				<-stopped
+				return nil
+			},
+			func() {
+				fsm.Stop()
+				close(stopped)
+			},
+		).
+		Run()
+	if err != nil {
+		log.Error().Err(err).Msg("failed")
+		return failure
+	}
+
+	return success
+}
@@ -21,17 +21,18 @@ import (
 	"strings"
 	"time"
 
-	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc"
 
 	"github.com/onflow/cadence"
 	"github.com/onflow/cadence/encoding/json"
 
-	"github.com/optakt/flow-dps/api/dps"
+	api "github.com/optakt/flow-dps/api/dps"
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
 	"github.com/optakt/flow-dps/service/invoker"
+	"github.com/optakt/flow-dps/service/metrics"
 )
 
 const (
@@ -51,32 +52,57 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
-		flagAPI    string
-		flagCache  uint64
-		flagHeight uint64
-		flagLevel  string
-		flagParams string
-		flagScript string
+		flagAPI       string
+		flagCache     uint64
+		flagHeight    uint64
+		flagLevel     string
+		flagLogFormat string
+		flagParams    string
+		flagScript    string
+
+		flagBenchmark   bool
+		flagCompress    bool
+		flagConcurrency uint
+		flagKeepalive   time.Duration
+		flagLast        uint64
+		flagReadRetries uint
+		flagRepeat      uint
+		flagRetries     uint
+		flagVersion     bool
 	)
 
 	pflag.StringVarP(&flagAPI, "api", "a", "", "host for GRPC API server")
 	pflag.Uint64VarP(&flagCache, "cache", "e", 1_000_000_000, "maximum cache size for register reads in bytes")
 	pflag.Uint64VarP(&flagHeight, "height", "h", 0, "block height to execute the script at")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
 	pflag.StringVarP(&flagParams, "params", "p", "", "comma-separated list of Cadence parameters")
 	pflag.StringVarP(&flagScript, "script", "s", "script.cdc", "path to file with Cadence script")
 
+	pflag.BoolVar(&flagBenchmark, "benchmark", false, "execute the script repeatedly across a height range and log latency percentiles instead of printing the result")
+	pflag.BoolVar(&flagCompress, "compress", false, "request gzip compression of API requests and responses")
+	pflag.UintVar(&flagConcurrency, "concurrency", 4, "maximum number of scripts executed concurrently in benchmark mode")
+	pflag.DurationVar(&flagKeepalive, "keepalive", 30*time.Second, "interval for keepalive pings on the API connection (0s for disabled)")
+	pflag.Uint64Var(&flagLast, "last", 0, "last height of the benchmarked range, starting at the given height (default given height)")
+	pflag.UintVar(&flagReadRetries, "read-retries", 3, "maximum number of retries for register reads that time out or are aborted by the API (0 for disabled)")
+	pflag.UintVar(&flagRepeat, "repeat", 1, "number of script executions per height in benchmark mode")
+	pflag.UintVar(&flagRetries, "retries", 5, "maximum number of retries for API requests while the API is unavailable (0 for disabled)")
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
 	pflag.Parse()
 
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
 	// Logger initialization.
-	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
-	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
-	level, err := zerolog.ParseLevel(flagLevel)
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
 	if err != nil {
-		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
 		return failure
 	}
-	log = log.Level(level)
 
 	// If no API server is given, choose based on height.
 	if flagAPI == "" {
@@ -93,8 +119,13 @@ func run() int {
 		return failure
 	}
 
-	// Initialize the API client.
-	conn, err := grpc.Dial(flagAPI, grpc.WithInsecure())
+	// Initialize the API client. It keeps the connection alive and retries
+	// requests, so that transient network failures are recovered from.
+	conn, err := api.Dial(flagAPI,
+		api.WithKeepalive(flagKeepalive, api.DefaultDialConfig.KeepaliveTimeout),
+		api.WithRetries(flagRetries, api.DefaultDialConfig.RetryBackoff),
+		api.WithCompression(flagCompress),
+	)
 	if err != nil {
 		log.Error().Str("api", flagAPI).Err(err).Msg("could not dial API host")
 		return failure
@@ -126,12 +157,39 @@ func run() int {
 	codec := zbor.NewCodec()
 
 	// Execute the script using remote lookup and read.
-	client := dps.NewAPIClient(conn)
-	invoke, err := invoker.New(dps.IndexFromAPI(client, codec), invoker.WithCacheSize(flagCache))
+	client := api.NewAPIClient(conn)
+	invoke, err := invoker.New(api.IndexFromAPI(client, codec),
+		invoker.WithCacheSize(flagCache),
+		invoker.WithReadRetries(flagReadRetries, api.DefaultDialConfig.RetryBackoff),
+	)
 	if err != nil {
 		log.Error().Err(err).Msg("could not initialize invoker")
 		return failure
 	}
+
+	// In benchmark mode, we execute the script repeatedly across the height
+	// range to measure the latency of the API, instead of printing the result.
+	if flagBenchmark {
+		last := flagLast
+		if last == 0 {
+			last = flagHeight
+		}
+		bench := metrics.NewBenchmark(log, flagConcurrency, flagRepeat)
+		go func() {
+			<-sig
+			bench.Stop()
+		}()
+		_, err = bench.Run(flagHeight, last, func(height uint64) error {
+			_, err := invoke.Script(height, script, args)
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("could not run benchmark")
+			return failure
+		}
+		return success
+	}
+
 	result, err := invoke.Script(flagHeight, script, args)
 	if err != nil {
 		log.Error().Err(err).Msg("could not invoke script")
@@ -15,17 +15,19 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/signal"
-	"runtime"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/prometheus/tsdb/wal"
-	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 
+	"github.com/onflow/flow-go/model/flow"
+
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/engine"
 	"github.com/optakt/flow-dps/ledger/forest"
@@ -33,8 +35,10 @@ import (
 	"github.com/optakt/flow-dps/service/chain"
 	"github.com/optakt/flow-dps/service/feeder"
 	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
 	"github.com/optakt/flow-dps/service/loader"
 	"github.com/optakt/flow-dps/service/mapper"
+	"github.com/optakt/flow-dps/service/metrics"
 	"github.com/optakt/flow-dps/service/storage"
 )
 
@@ -49,45 +53,85 @@ func main() {
 
 func run() int {
 
-	// Signal catching for clean shutdown.
+	// Signal catching for clean shutdown. The mapper is interrupted directly as
+	// well, so that it stops its ongoing transition right away, rather than
+	// once the engine gets to stopping it.
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
 	// Command line parameter initialization.
 	var (
 		flagCheckpoint string
+		flagContinue   bool
 		flagData       string
 		flagIndex      string
 		flagLevel      string
+		flagLogFormat  string
+		flagLogSample  uint32
+		flagResume     uint64
 		flagTrie       string
 		flagSkip       bool
+
+		flagChain        string
+		flagCompactors   int
+		flagGCInterval   time.Duration
+		flagMaxProcs     int
+		flagMaxValueSize uint64
+		flagPushInterval time.Duration
+		flagPushgateway  string
+		flagReadAhead    uint
+		flagSkipCorrupt  bool
+		flagSkipIndexed  bool
+		flagVersion      bool
 	)
 
 	pflag.StringVarP(&flagCheckpoint, "checkpoint", "c", "", "path to root checkpoint file for execution state trie")
+	pflag.BoolVarP(&flagContinue, "continue-on-error", "e", false, "record blocks whose execution data is missing or invalid and continue with the next block")
 	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.Uint32Var(&flagLogSample, "log-sample", 0, "log only one out of this many per-block debug and info messages of the mapper (0 for all)")
+	pflag.Uint64VarP(&flagResume, "resume-height", "r", 0, "indexed height from which to resume indexing (bootstraps from checkpoint when zero)")
 	pflag.StringVarP(&flagTrie, "trie", "t", "", "path to data directory for execution state ledger")
 	pflag.BoolVarP(&flagSkip, "skip", "s", false, "skip indexing of execution state ledger registers")
 
+	pflag.StringVar(&flagChain, "chain", "", "expected chain ID of the indexed data, e.g. flow-mainnet (detected chain ID is only logged when left empty)")
+	pflag.IntVar(&flagCompactors, "compactors", 2, "number of concurrent compaction workers of the index database (at least 2, 0 for disabled)")
+	pflag.DurationVar(&flagGCInterval, "gc-interval", 0, "interval for running value log garbage collection on the index database during indexing (0s for disabled)")
+	pflag.IntVar(&flagMaxProcs, "max-procs", 0, "maximum number of CPUs executing simultaneously (0 for the Go runtime default)")
+	pflag.Uint64Var(&flagMaxValueSize, "max-value-size", mapper.DefaultConfig.MaxValueSize, "maximum size in bytes of a single register value (0 for unlimited)")
+	pflag.DurationVar(&flagPushInterval, "push-interval", time.Minute, "interval for pushing metrics to the pushgateway during indexing (0s for only on completion)")
+	pflag.StringVar(&flagPushgateway, "pushgateway", "", "URL of a Prometheus pushgateway to push metrics to (no metrics are pushed when left empty)")
+	pflag.UintVar(&flagReadAhead, "read-ahead", 0, "number of trie updates to read ahead from the write-ahead log (0 for disabled)")
+	pflag.BoolVar(&flagSkipCorrupt, "skip-corrupt", false, "skip corrupt write-ahead log segments by resuming from the next checkpoint instead of failing")
+	pflag.BoolVar(&flagSkipIndexed, "skip-indexed", false, "when resuming, skip replayed heights that were completely indexed with the same state commitment")
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
 	pflag.Parse()
 
-	// Increase the GOMAXPROCS value in order to use the full IOPS available, see:
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Only override the GOMAXPROCS value if explicitly configured. Increasing
+	// it can help to use the full IOPS available on large hosts, see:
 	// https://groups.google.com/g/golang-nuts/c/jPb_h3TvlKE
-	_ = runtime.GOMAXPROCS(128)
+	_ = dps.SetMaxProcs(flagMaxProcs)
 
 	// Logger initialization.
-	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
-	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
-	level, err := zerolog.ParseLevel(flagLevel)
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
 	if err != nil {
-		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
 		return failure
 	}
-	log = log.Level(level)
 
 	// Open the needed databases.
-	indexDB, err := badger.Open(dps.DefaultOptions(flagIndex))
+	indexDB, err := badger.Open(dps.DefaultOptions(flagIndex).WithNumCompactors(flagCompactors))
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index database")
 		return failure
@@ -124,26 +168,74 @@ func run() int {
 		log.Error().Err(err).Msg("could not get first height from index reader")
 		return failure
 	}
+	if empty && flagResume != 0 {
+		log.Error().Msg("index doesn't exist, cannot resume from height (-r, --resume-height)")
+		return failure
+	}
 	if empty && flagCheckpoint == "" {
 		log.Error().Msg("index doesn't exist, please provide root checkpoint (-c, --checkpoint) to bootstrap")
 		return failure
 	}
 
+	// An existing index may have been written by a different release, so we
+	// bring older indexes up to the current schema version, and make sure that
+	// we can use it before writing anything.
+	if !empty {
+		applied, err := storage.Migrate(indexDB, zbor.Fingerprint())
+		if err != nil {
+			log.Error().Err(err).Msg("could not migrate index")
+			return failure
+		}
+		for _, migration := range applied {
+			log.Info().Uint32("version", migration.Version).Str("description", migration.Description).Msg("applied index migration")
+		}
+	}
+	err = read.Compatible()
+	if err != nil {
+		log.Error().Err(err).Msg("could not use existing index")
+		return failure
+	}
+
 	// The chain is responsible for reading blockchain data from the protocol state.
 	disk := chain.FromDisk(protocolDB)
 
+	// If an expected chain is given, we make sure that the protocol state
+	// belongs to it before indexing anything, so that we don't mix up the data
+	// of different chains. Otherwise, we only log the detected chain.
+	chainID := flow.ChainID(flagChain)
+	if flagChain != "" {
+		err = chain.Validate(disk, chainID)
+		if err != nil {
+			log.Error().Str("chain", flagChain).Err(err).Msg("could not validate chain of protocol state")
+			return failure
+		}
+	} else {
+		chainID, err = chain.ID(disk)
+		if err != nil {
+			log.Error().Err(err).Msg("could not detect chain of protocol state")
+			return failure
+		}
+		log.Info().Str("chain", chainID.String()).Msg("detected chain of protocol state")
+	}
+
 	// Feeder is responsible for reading the write-ahead log of the execution state.
 	segments, err := wal.NewSegmentsReader(flagTrie)
 	if err != nil {
 		log.Error().Str("trie", flagTrie).Err(err).Msg("could not open segments reader")
 		return failure
 	}
-	feed := feeder.FromWAL(wal.NewReader(segments))
+	feedOptions := []feeder.Option{
+		feeder.WithReadAhead(flagReadAhead),
+	}
+	if flagSkipCorrupt {
+		feedOptions = append(feedOptions, feeder.WithSkipCorrupt(feeder.FromDirectory(flagTrie)))
+	}
+	feed := feeder.FromWAL(log, wal.NewReader(segments), feedOptions...)
 
 	// Writer is responsible for writing the index data to the index database.
 	// We explicitly disable flushing at regular intervals to improve throughput
 	// of badger transactions when indexing from static on-disk data.
-	write := index.NewWriter(indexDB, storage,
+	write := index.NewWriter(log, indexDB, storage,
 		index.WithFlushInterval(0),
 	)
 	defer func() {
@@ -153,15 +245,63 @@ func run() int {
 		}
 	}()
 
-	// Initialize the transitions with the dependencies and add them to the FSM.
-	load := loader.FromScratch()
+	// When bootstrapping a new index, we record its metadata first, so that
+	// binaries opening it later on can check whether they are compatible.
+	if empty {
+		meta := dps.Metadata{
+			SchemaVersion: dps.SchemaVersion,
+			Dictionary:    zbor.Fingerprint(),
+			ChainID:       chainID,
+			Created:       time.Now().UTC(),
+		}
+		err = write.Metadata(meta)
+		if err != nil {
+			log.Error().Err(err).Msg("could not write index metadata")
+			return failure
+		}
+	}
 
-	transitions := mapper.NewTransitions(log, load, disk, feed, read, write,
+	// If metrics are pushed to a pushgateway, the mapper should use the metrics
+	// writer. Otherwise, it can use the regular one.
+	writer := dps.Writer(write)
+	metricsEnabled := flagPushgateway != ""
+	if metricsEnabled {
+		writer = index.NewMetricsWriter(write)
+		err = metrics.RegisterBadgerMetrics()
+		if err != nil {
+			log.Error().Err(err).Msg("could not register badger metrics")
+			return failure
+		}
+	}
+
+	// Initialize the transitions with the dependencies and add them to the FSM.
+	// When resuming from an indexed height, the trie is restored from the
+	// registers indexed up to that height, and the mapper validates it against
+	// the state commitment indexed for that height.
+	load := mapper.Loader(loader.FromScratch())
+	options := []mapper.Option{
 		mapper.WithBootstrapState(true),
 		mapper.WithSkipRegisters(flagSkip),
-	)
-	forest := forest.New()
-	state := mapper.EmptyState(forest)
+		mapper.WithLogSample(flagLogSample),
+		mapper.WithMaxValueSize(flagMaxValueSize),
+		mapper.WithContinueOnError(flagContinue),
+	}
+	if flagResume != 0 {
+		load = loader.FromIndex(log, storage, indexDB,
+			loader.WithExclude(loader.ExcludeAbove(flagResume)),
+		)
+		options = append(options, mapper.WithInitialHeight(flagResume))
+	}
+	if flagSkipIndexed {
+		options = append(options, mapper.WithSkipIndexed(read))
+	}
+
+	transitions := mapper.NewTransitions(log, load, disk, feed, read, writer, options...)
+	tries := forest.New()
+	if metricsEnabled {
+		forest.RegisterMetrics(tries)
+	}
+	state := mapper.EmptyState(tries)
 	fsm := mapper.NewFSM(state,
 		mapper.WithTransition(mapper.StatusInitialize, transitions.InitializeMapper),
 		mapper.WithTransition(mapper.StatusBootstrap, transitions.BootstrapState),
@@ -173,21 +313,67 @@ func run() int {
 		mapper.WithTransition(mapper.StatusForward, transitions.ForwardHeight),
 	)
 
-	err = engine.New(log, "Flow DPS Indexer", sig).
+	// If metrics are enabled, the pusher regularly pushes them to the
+	// pushgateway while the indexer is running, and once more when indexing is
+	// done.
+	pusher := metrics.NewPusher(log, flagPushgateway, "flow_dps_indexer", flagPushInterval)
+	stopped := make(chan struct{})
+	eng := engine.New(log, "Flow DPS Indexer", sig).
 		Component(
 			"mapper",
 			func() error {
-				return fsm.Run()
+				err := fsm.RunWithContext(interrupted)
+				if err != nil {
+					return err
+				}
+				// When interrupted, the engine still has to stop the other
+				// components, so we only return once it stops the mapper.
+				if interrupted.Err() != nil {
+					<-stopped
+				}
+				return nil
 			},
 			func() {
 				fsm.Stop()
+				close(stopped)
+			},
+		)
+	if flagGCInterval > 0 {
+		gc := index.NewGarbageCollector(log, indexDB, flagGCInterval)
+		eng = eng.Component(
+			"collector",
+			func() error {
+				return gc.Start()
 			},
-		).
-		Run()
+			func() {
+				gc.Stop()
+			},
+		)
+	}
+	if metricsEnabled {
+		eng = eng.Component(
+			"pusher",
+			func() error {
+				return pusher.Start()
+			},
+			func() {
+				pusher.Stop()
+			},
+		)
+	}
+
+	err = eng.Run()
 	if err != nil {
 		log.Error().Err(err).Msg("failed")
 		return failure
 	}
 
+	if metricsEnabled {
+		err = pusher.Push()
+		if err != nil {
+			log.Warn().Str("pushgateway", flagPushgateway).Err(err).Msg("could not push final metrics")
+		}
+	}
+
 	return success
 }
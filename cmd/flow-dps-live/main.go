@@ -18,12 +18,11 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"runtime"
-	"strconv"
 	"time"
 
 	gcloud "cloud.google.com/go/storage"
@@ -31,22 +30,23 @@ import (
 	grpczerolog "github.com/grpc-ecosystem/go-grpc-middleware/providers/zerolog/v2"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tags"
-	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 
-	sdk "github.com/onflow/flow-go-sdk/crypto"
 	"github.com/onflow/flow-go/cmd/bootstrap/utils"
 	"github.com/onflow/flow-go/crypto"
 	unstaked "github.com/onflow/flow-go/follower"
 	"github.com/onflow/flow-go/model/bootstrap"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/storage/badger/operation"
 
 	api "github.com/optakt/flow-dps/api/dps"
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/engine"
 	"github.com/optakt/flow-dps/ledger/forest"
 	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/chain"
 	"github.com/optakt/flow-dps/service/cloud"
 	"github.com/optakt/flow-dps/service/index"
 	"github.com/optakt/flow-dps/service/initializer"
@@ -68,9 +68,13 @@ func main() {
 
 func run() int {
 
-	// Signal catching for clean shutdown.
+	// Signal catching for clean shutdown. The mapper is interrupted directly as
+	// well, so that it stops its ongoing transition right away, rather than
+	// once the engine gets to stopping it.
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
 	// Command line parameter initialization.
 	var (
@@ -81,12 +85,23 @@ func run() int {
 		flagData       string
 		flagIndex      string
 		flagLevel      string
+		flagLogFormat  string
+		flagLogSample  uint32
 		flagMetrics    string
 		flagSkip       bool
 
+		flagCaptureDir    string
 		flagFlushInterval time.Duration
-		flagSeedAddress   string
-		flagSeedKey       string
+		flagFlushSize     uint64
+		flagMaxCatchup    uint
+		flagMaxProcs      int
+		flagMaxValueSize  uint64
+		flagNetworkKey    string
+		flagObjectName    string
+		flagSeedAddresses []string
+		flagSeedKeys      []string
+		flagShutdown      time.Duration
+		flagVersion       bool
 	)
 
 	pflag.StringVarP(&flagAddress, "address", "a", "127.0.0.1:5005", "bind address for serving DPS API")
@@ -96,28 +111,43 @@ func run() int {
 	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.Uint32Var(&flagLogSample, "log-sample", 0, "log only one out of this many per-block debug and info messages of the mapper (0 for all)")
 	pflag.StringVarP(&flagMetrics, "metrics", "m", "", "address on which to expose metrics (no metrics are exposed when left empty)")
 	pflag.BoolVarP(&flagSkip, "skip", "s", false, "skip indexing of execution state ledger registers")
 
+	pflag.StringVar(&flagCaptureDir, "capture-dir", "", "directory in which to capture downloaded execution records for later replay (no capture when left empty)")
 	pflag.DurationVar(&flagFlushInterval, "flush-interval", 1*time.Second, "interval for flushing badger transactions (0s for disabled)")
-	pflag.StringVar(&flagSeedAddress, "seed-address", "", "host address of seed node to follow consensus")
-	pflag.StringVar(&flagSeedKey, "seed-key", "", "hex-encoded public network key of seed node to follow consensus")
+	pflag.Uint64Var(&flagFlushSize, "flush-size", 0, "estimated size in bytes for flushing badger transactions (0 for disabled)")
+	pflag.UintVar(&flagMaxCatchup, "max-catchup", 0, "maximum number of catch-up blocks queued for download at the same time (0 for no limit)")
+	pflag.IntVar(&flagMaxProcs, "max-procs", 0, "maximum number of CPUs executing simultaneously (0 for the Go runtime default)")
+	pflag.Uint64Var(&flagMaxValueSize, "max-value-size", mapper.DefaultConfig.MaxValueSize, "maximum size in bytes of a single register value (0 for unlimited)")
+	pflag.StringVar(&flagNetworkKey, "network-key-file", "", "file with private network key of consensus follower, generated on first start (new key on each start when left empty)")
+	pflag.StringVar(&flagObjectName, "object-name", "{blockID}.cbor", "template for names of execution record objects in the bucket, using {blockID} and {height} placeholders")
+	pflag.StringSliceVar(&flagSeedAddresses, "seed-addresses", nil, "comma-separated host addresses of seed nodes to follow consensus")
+	pflag.StringSliceVar(&flagSeedKeys, "seed-keys", nil, "comma-separated hex-encoded public network keys of seed nodes to follow consensus, in the same order as the addresses")
+	pflag.DurationVar(&flagShutdown, "shutdown-timeout", 30*time.Second, "maximum duration of graceful shutdown before forcing exit (0s for disabled)")
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
 
 	pflag.Parse()
 
-	// Increase the GOMAXPROCS value in order to use the full IOPS available, see:
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Only override the GOMAXPROCS value if explicitly configured. Increasing
+	// it can help to use the full IOPS available on large hosts, see:
 	// https://groups.google.com/g/golang-nuts/c/jPb_h3TvlKE
-	_ = runtime.GOMAXPROCS(128)
+	_ = dps.SetMaxProcs(flagMaxProcs)
 
 	// Logger initialization.
-	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
-	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
-	level, err := zerolog.ParseLevel(flagLevel)
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
 	if err != nil {
-		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
 		return failure
 	}
-	log = log.Level(level)
 
 	// As a first step, we will open the protocol state and the index database.
 	// The protocol state database is what the consensus follower will write to
@@ -166,16 +196,38 @@ func run() int {
 		return failure
 	}
 
+	// An existing index may have been written by a different release, so we
+	// bring older indexes up to the current schema version, and make sure that
+	// we can use it before writing anything.
+	if !empty {
+		applied, err := storage.Migrate(indexDB, zbor.Fingerprint())
+		if err != nil {
+			log.Error().Err(err).Msg("could not migrate index")
+			return failure
+		}
+		for _, migration := range applied {
+			log.Info().Uint32("version", migration.Version).Str("description", migration.Description).Msg("applied index migration")
+		}
+	}
+	err = read.Compatible()
+	if err != nil {
+		log.Error().Err(err).Msg("could not use existing index")
+		return failure
+	}
+
 	// We initialize the writer with a flush interval, which will make sure that
 	// Badger transactions are committed to the database, even if they don't
 	// fill up fast enough. This avoids having latency between when we add data
 	// to the transaction and when it becomes available on-disk for serving the
-	// DPS API.
-	write := index.NewWriter(
-		indexDB,
-		storage,
+	// DPS API. If metrics are enabled, we also record the latency of commits.
+	options := []func(*index.Config){
 		index.WithFlushInterval(flagFlushInterval),
-	)
+		index.WithFlushSize(flagFlushSize),
+	}
+	if flagMetrics != "" {
+		options = append(options, index.WithCommitObserver(index.NewCommitHistogram()))
+	}
+	write := index.NewWriter(log, indexDB, storage, options...)
 	defer func() {
 		err := write.Close()
 		if err != nil {
@@ -184,46 +236,42 @@ func run() int {
 	}()
 
 	// Next, we want to initialize the consensus follower. One needed parameter
-	// is a network key, used to secure the peer-to-peer communication. However,
-	// as we do not need any specific key, we choose to just initialize a new
-	// key on each start of the live indexer.
-	seed := make([]byte, crypto.KeyGenSeedMinLenECDSASecp256k1)
-	n, err := rand.Read(seed)
-	if err != nil || n != crypto.KeyGenSeedMinLenECDSASecp256k1 {
-		log.Error().Err(err).Msg("could not generate private key seed")
-		return failure
-	}
-	privKey, err := utils.GenerateUnstakedNetworkingKey(seed)
-	if err != nil {
-		log.Error().Err(err).Msg("could not generate private network key")
-		return failure
+	// is a network key, used to secure the peer-to-peer communication. If we
+	// have a key file, we load the key from it, so that we keep the same
+	// peer-to-peer identity across restarts. Otherwise, as we do not need any
+	// specific key, we just initialize a new key on each start.
+	var privKey crypto.PrivateKey
+	if flagNetworkKey != "" {
+		privKey, err = initializer.NetworkKey(flagNetworkKey)
+		if err != nil {
+			log.Error().Err(err).Str("network_key_file", flagNetworkKey).Msg("could not load private network key")
+			return failure
+		}
+	} else {
+		seed := make([]byte, crypto.KeyGenSeedMinLenECDSASecp256k1)
+		n, err := rand.Read(seed)
+		if err != nil || n != crypto.KeyGenSeedMinLenECDSASecp256k1 {
+			log.Error().Err(err).Msg("could not generate private key seed")
+			return failure
+		}
+		privKey, err = utils.GenerateUnstakedNetworkingKey(seed)
+		if err != nil {
+			log.Error().Err(err).Msg("could not generate private network key")
+			return failure
+		}
 	}
 
 	// Here, we finally initialize the unstaked consensus follower. It connects
 	// to a staked access node for bootstrapping the peer-to-peer network, which
 	// is shared between staked access nodes and unstaked consensus followers.
 	// For every finalized block, it calls the callback for all registered
-	// finalization listeners.
-	seedHost, port, err := net.SplitHostPort(flagSeedAddress)
+	// finalization listeners. Having multiple seed nodes allows bootstrapping
+	// even when some of them are unavailable.
+	seedNodes, err := initializer.SeedNodes(flagSeedAddresses, flagSeedKeys)
 	if err != nil {
-		log.Error().Err(err).Str("address", flagSeedAddress).Msg("could not parse seed node address")
+		log.Error().Err(err).Strs("addresses", flagSeedAddresses).Strs("keys", flagSeedKeys).Msg("could not parse seed nodes")
 		return failure
 	}
-	seedPort, err := strconv.ParseUint(port, 10, 16)
-	if err != nil {
-		log.Error().Err(err).Str("port", port).Msg("could not parse seed node port")
-		return failure
-	}
-	seedKey, err := sdk.DecodePublicKeyHex(sdk.ECDSA_P256, flagSeedKey)
-	if err != nil {
-		log.Error().Err(err).Str("key", flagSeedKey).Msg("could not parse seed node network public key")
-		return failure
-	}
-	seedNodes := []unstaked.BootstrapNodeInfo{{
-		Host:             seedHost,
-		Port:             uint(seedPort),
-		NetworkPublicKey: seedKey,
-	}}
 	follow, err := unstaked.NewConsensusFollower(
 		privKey,
 		"0.0.0.0:0", // automatically choose port, listen on all IPs
@@ -257,6 +305,28 @@ func run() int {
 		return failure
 	}
 
+	// When bootstrapping a new index, we record its metadata first, so that
+	// binaries opening it later on can check whether they are compatible. The
+	// chain ID is taken from the root of the freshly bootstrapped protocol state.
+	if empty {
+		chainID, err := chain.ID(chain.FromDisk(protocolDB))
+		if err != nil {
+			log.Error().Err(err).Msg("could not detect chain of protocol state")
+			return failure
+		}
+		meta := dps.Metadata{
+			SchemaVersion: dps.SchemaVersion,
+			Dictionary:    zbor.Fingerprint(),
+			ChainID:       chainID,
+			Created:       time.Now().UTC(),
+		}
+		err = write.Metadata(meta)
+		if err != nil {
+			log.Error().Err(err).Msg("could not write index metadata")
+			return failure
+		}
+	}
+
 	// If we are resuming, and the consensus follower has already finalized some
 	// blocks that were not yet indexed, we need to download them again in the
 	// cloud streamer. Here, we figure out which blocks these are.
@@ -283,10 +353,28 @@ func run() int {
 			log.Error().Err(err).Msg("could not close GCP client")
 		}
 	}()
+	// If the bucket uses a layout where object names contain the block
+	// height, we look up the height of finalized blocks in the protocol state.
+	lookup := func(blockID flow.Identifier) (uint64, error) {
+		var header flow.Header
+		err := protocolDB.View(operation.RetrieveHeader(blockID, &header))
+		if err != nil {
+			return 0, fmt.Errorf("could not retrieve header: %w", err)
+		}
+		return header.Height, nil
+	}
 	bucket := client.Bucket(flagBucket)
-	stream := cloud.NewGCPStreamer(log, bucket,
+	stream, err := cloud.NewGCPStreamer(log, bucket,
 		cloud.WithCatchupBlocks(blockIDs),
+		cloud.WithCatchupLimit(flagMaxCatchup),
+		cloud.WithCaptureDir(flagCaptureDir),
+		cloud.WithObjectNameTemplate(flagObjectName),
+		cloud.WithHeightLookup(lookup),
 	)
+	if err != nil {
+		log.Error().Err(err).Str("object_name", flagObjectName).Msg("could not initialize GCP streamer")
+		return failure
+	}
 
 	// Next, we can initialize our consensus and execution trackers. They are
 	// responsible for tracking changes to the available data, for the consensus
@@ -352,9 +440,14 @@ func run() int {
 	transitions := mapper.NewTransitions(log, load, consensus, execution, read, writer,
 		mapper.WithBootstrapState(empty),
 		mapper.WithSkipRegisters(flagSkip),
+		mapper.WithLogSample(flagLogSample),
+		mapper.WithMaxValueSize(flagMaxValueSize),
 	)
-	forest := forest.New()
-	state := mapper.EmptyState(forest)
+	tries := forest.New()
+	if metricsEnabled {
+		forest.RegisterMetrics(tries)
+	}
+	state := mapper.EmptyState(tries)
 	fsm := mapper.NewFSM(state,
 		mapper.WithTransition(mapper.StatusInitialize, transitions.InitializeMapper),
 		mapper.WithTransition(mapper.StatusBootstrap, transitions.BootstrapState),
@@ -373,6 +466,7 @@ func run() int {
 	}
 	interceptor := grpczerolog.InterceptorLogger(log.With().Str("engine", "grpc_server").Logger())
 	gsvr := grpc.NewServer(
+		api.KeepaliveEnforcement(),
 		grpc.ChainUnaryInterceptor(
 			tags.UnaryServerInterceptor(),
 			logging.UnaryServerInterceptor(interceptor, logOpts...),
@@ -395,8 +489,10 @@ func run() int {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	metricsSrv := metrics.NewServer(log, flagMetrics)
+	stopped := make(chan struct{})
 
 	err = engine.New(log, "Flow DPS Live", sig).
+		ShutdownTimeout(flagShutdown).
 		Component(
 			"api",
 			func() error {
@@ -433,10 +529,20 @@ func run() int {
 		Component(
 			"mapper",
 			func() error {
-				return fsm.Run()
+				err := fsm.RunWithContext(interrupted)
+				if err != nil {
+					return err
+				}
+				// When interrupted, the engine still has to stop the other
+				// components, so we only return once it stops the mapper.
+				if interrupted.Err() != nil {
+					<-stopped
+				}
+				return nil
 			},
 			func() {
 				fsm.Stop()
+				close(stopped)
 			},
 		).
 		Component(
@@ -16,6 +16,7 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -23,7 +24,6 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
-	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
 	"google.golang.org/grpc"
 
@@ -35,6 +35,7 @@ import (
 	"github.com/optakt/flow-dps/codec/zbor"
 	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
 	"github.com/optakt/flow-dps/service/storage"
 )
 
@@ -55,29 +56,65 @@ func run() int {
 
 	// Command line parameter initialization.
 	var (
-		flagAddress string
-		flagLevel   string
-		flagIndex   string
+		flagAddress   string
+		flagCacheFrac float64
+		flagCacheSize int64
+		flagLevel     string
+		flagLogFormat string
+		flagIndex     string
+
+		flagShutdown     time.Duration
+		flagVerify       bool
+		flagVerifySample uint
+		flagVersion      bool
 	)
 
 	pflag.StringVarP(&flagAddress, "address", "a", "127.0.0.1:5005", "bind address for serving DPS API")
 	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.Float64Var(&flagCacheFrac, "index-cache-fraction", 0.25, "fraction of available memory used for the index cache when its size is not set explicitly (0 for the default size)")
+	pflag.Int64Var(&flagCacheSize, "index-cache-size", 2000<<20, "size of the index cache in bytes")
 	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.DurationVar(&flagShutdown, "shutdown-timeout", 30*time.Second, "maximum duration of graceful shutdown before forcing exit (0s for disabled)")
+	pflag.BoolVar(&flagVerify, "verify-registers-on-start", false, "verify that a sample of the indexed registers can be decoded before serving")
+	pflag.UintVar(&flagVerifySample, "verify-sample-rate", 100, "verify one out of this many indexed registers on start (1 for all)")
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
 
 	pflag.Parse()
 
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
 	// Logger initialization.
-	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
-	log := zerolog.New(os.Stderr).With().Timestamp().Logger().Level(zerolog.DebugLevel)
-	level, err := zerolog.ParseLevel(flagLevel)
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
 	if err != nil {
-		log.Error().Str("level", flagLevel).Err(err).Msg("could not parse log level")
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
 		return failure
 	}
-	log = log.Level(level)
+
+	// Unless the index cache size is given explicitly, we size it according
+	// to the memory available to the process, so that it fits on small nodes
+	// and makes use of the memory of big ones.
+	if flagCacheFrac < 0 || flagCacheFrac > 1 {
+		log.Error().Float64("fraction", flagCacheFrac).Msg("index cache fraction must be between 0 and 1")
+		return failure
+	}
+	if !pflag.CommandLine.Changed("index-cache-size") && flagCacheFrac != 0 {
+		memory, err := dps.AvailableMemory()
+		if err != nil {
+			log.Warn().Err(err).Int64("cache_size", flagCacheSize).Msg("could not detect available memory, using default index cache size")
+		} else {
+			flagCacheSize = dps.CacheSize(memory, flagCacheFrac)
+			log.Info().Uint64("memory", memory).Float64("fraction", flagCacheFrac).Int64("cache_size", flagCacheSize).Msg("index cache sized from available memory")
+		}
+	}
 
 	// Initialize the index core state and open database in read-only mode.
-	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true).WithIndexCacheSize(flagCacheSize))
 	if err != nil {
 		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
 		return failure
@@ -93,6 +130,7 @@ func run() int {
 		logging.WithLevels(logging.DefaultServerCodeToLevel),
 	}
 	gsvr := grpc.NewServer(
+		api.KeepaliveEnforcement(),
 		grpc.ChainUnaryInterceptor(
 			tags.UnaryServerInterceptor(),
 			logging.UnaryServerInterceptor(grpczerolog.InterceptorLogger(log), opts...),
@@ -103,6 +141,25 @@ func run() int {
 		),
 	)
 	index := index.NewReader(db, storage)
+	err = index.Compatible()
+	if err != nil {
+		log.Error().Err(err).Msg("could not use index")
+		return failure
+	}
+
+	// If requested, we make sure that the indexed registers are not damaged
+	// before serving anything, rather than serving corrupt data.
+	if flagVerify {
+		start := time.Now()
+		var verified uint64
+		err = db.View(storage.VerifyPayloads(flagVerifySample, &verified))
+		if err != nil {
+			log.Error().Err(err).Msg("could not verify indexed registers")
+			return failure
+		}
+		log.Info().Uint64("verified", verified).Dur("duration", time.Since(start)).Msg("indexed registers verified")
+	}
+
 	server := api.NewServer(index, codec)
 
 	// This section launches the main executing components in their own
@@ -143,7 +200,26 @@ func run() int {
 		os.Exit(1)
 	}()
 
-	gsvr.GracefulStop()
+	// If a shutdown timeout is configured, we stop waiting for in-flight
+	// requests to finish once it expires and close all connections instead.
+	stopped := make(chan struct{})
+	go func() {
+		gsvr.GracefulStop()
+		close(stopped)
+	}()
+	var timeout <-chan time.Time
+	if flagShutdown > 0 {
+		timer := time.NewTimer(flagShutdown)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-stopped:
+	case <-timeout:
+		log.Warn().Dur("timeout", flagShutdown).Msg("shutdown timed out, forcing exit")
+		gsvr.Stop()
+		return failure
+	}
 
 	return success
 }
@@ -0,0 +1,108 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagIndex     string
+		flagLevel     string
+		flagLogFormat string
+
+		flagVersion bool
+	)
+
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Open the index database in read-only mode.
+	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer db.Close()
+
+	// Indexes that were bootstrapped before the metadata record was introduced
+	// do not have one, which is not an error.
+	read := index.NewReader(db, storage.New(zbor.NewCodec()))
+	meta, err := read.Metadata()
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		log.Warn().Msg("index has no metadata, it was created by an older release")
+		return success
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("could not retrieve index metadata")
+		return failure
+	}
+
+	compatible := "yes"
+	err = meta.Compatible()
+	if err != nil {
+		compatible = err.Error()
+	}
+
+	fmt.Printf("schema version:    %d\n", meta.SchemaVersion)
+	fmt.Printf("dictionary:        %s\n", meta.Dictionary)
+	fmt.Printf("chain ID:          %s\n", meta.ChainID)
+	fmt.Printf("created:           %s\n", meta.Created.Format(time.RFC3339))
+	fmt.Printf("compatible:        %s\n", compatible)
+
+	return success
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+// Summary is the summary of an index that is printed.
+type Summary struct {
+	First     uint64                   `json:"first"`
+	Last      uint64                   `json:"last"`
+	Blocks    uint64                   `json:"blocks"`
+	Registers uint64                   `json:"registers"`
+	SizeLSM   int64                    `json:"size_lsm"`
+	SizeVLog  int64                    `json:"size_vlog"`
+	Prefixes  map[string]PrefixSummary `json:"prefixes"`
+}
+
+// PrefixSummary is the summary of the keys with a given prefix in an index.
+type PrefixSummary struct {
+	Keys       uint64 `json:"keys"`
+	Size       int64  `json:"size"`
+	Dictionary uint32 `json:"dictionary"`
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagIndex     string
+		flagJSON      bool
+		flagLevel     string
+		flagLogFormat string
+
+		flagVersion bool
+	)
+
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.BoolVarP(&flagJSON, "json", "j", false, "print the summary in JSON format")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Open the index database in read-only mode.
+	db, err := badger.Open(dps.DefaultOptions(flagIndex).WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index DB")
+		return failure
+	}
+	defer db.Close()
+
+	// Retrieve the indexed height range and go through all keys of the index.
+	lib := storage.New(zbor.NewCodec())
+	var first, last uint64
+	err = db.View(lib.RetrieveFirst(&first))
+	if err != nil {
+		log.Error().Err(err).Msg("could not retrieve first height")
+		return failure
+	}
+	err = db.View(lib.RetrieveLast(&last))
+	if err != nil {
+		log.Error().Err(err).Msg("could not retrieve last height")
+		return failure
+	}
+	var stats storage.Stats
+	err = db.View(storage.CollectStats(&stats))
+	if err != nil {
+		log.Error().Err(err).Msg("could not collect index statistics")
+		return failure
+	}
+
+	summary := Summary{
+		First:     first,
+		Last:      last,
+		Registers: stats.Registers,
+		Prefixes:  make(map[string]PrefixSummary, len(stats.Prefixes)),
+	}
+	summary.SizeLSM, summary.SizeVLog = db.Size()
+	headers, ok := stats.Prefixes[storage.PrefixHeader]
+	if ok {
+		summary.Blocks = headers.Keys
+	}
+	for prefix, prefixStats := range stats.Prefixes {
+		summary.Prefixes[storage.PrefixName(prefix)] = PrefixSummary{
+			Keys:       prefixStats.Keys,
+			Size:       prefixStats.Size,
+			Dictionary: prefixStats.Dictionary,
+		}
+	}
+
+	if flagJSON {
+		output, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("could not encode summary")
+			return failure
+		}
+		fmt.Println(string(output))
+		return success
+	}
+
+	fmt.Printf("first height:      %d\n", summary.First)
+	fmt.Printf("last height:       %d\n", summary.Last)
+	fmt.Printf("blocks:            %d\n", summary.Blocks)
+	fmt.Printf("registers:         %d\n", summary.Registers)
+	fmt.Printf("LSM tree size:     %d bytes\n", summary.SizeLSM)
+	fmt.Printf("value log size:    %d bytes\n", summary.SizeVLog)
+	fmt.Println()
+
+	names := make([]string, 0, len(summary.Prefixes))
+	for name := range summary.Prefixes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("%-28s %12s %16s %12s\n", "prefix", "keys", "value bytes", "dictionary")
+	for _, name := range names {
+		prefix := summary.Prefixes[name]
+		fmt.Printf("%-28s %12d %16d %12d\n", name, prefix.Keys, prefix.Size, prefix.Dictionary)
+	}
+
+	return success
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// boundedChain wraps a chain so that the mapper finishes after the last height
+// for which we have an execution record, instead of waiting for more blocks to
+// be finalized.
+type boundedChain struct {
+	dps.Chain
+	last uint64
+}
+
+// Header returns the header for the given height, or an ErrFinished error if
+// the height is above the last replayed height.
+func (b *boundedChain) Header(height uint64) (*flow.Header, error) {
+	if height > b.last {
+		return nil, dps.ErrFinished
+	}
+	return b.Chain.Header(height)
+}
@@ -0,0 +1,287 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/storage/badger/operation"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/ledger/forest"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/cloud"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/loader"
+	"github.com/optakt/flow-dps/service/mapper"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/service/tracker"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagCheckpoint string
+		flagData       string
+		flagIndex      string
+		flagLevel      string
+		flagLogFormat  string
+		flagRecords    string
+		flagSkip       bool
+
+		flagVersion bool
+	)
+
+	pflag.StringVarP(&flagCheckpoint, "checkpoint", "c", "", "path to root checkpoint file for execution state trie")
+	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
+	pflag.StringVarP(&flagIndex, "index", "i", "", "path to empty database directory for replayed state index (temporary folder when left empty)")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+	pflag.StringVarP(&flagRecords, "records", "r", "records", "path to directory with captured execution records")
+	pflag.BoolVarP(&flagSkip, "skip", "s", false, "skip indexing of execution state ledger registers")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Replaying always starts from the root checkpoint of the spork.
+	if flagCheckpoint == "" {
+		log.Error().Msg("please provide root checkpoint (-c, --checkpoint) to bootstrap replayed index")
+		return failure
+	}
+
+	// If no index directory is given, we replay into a temporary directory,
+	// which is removed once we are done.
+	indexDir := flagIndex
+	if indexDir == "" {
+		indexDir, err = os.MkdirTemp("", "replay-index-*")
+		if err != nil {
+			log.Error().Err(err).Msg("could not create temporary index directory")
+			return failure
+		}
+		defer os.RemoveAll(indexDir)
+	}
+
+	// Open the needed databases.
+	protocolDB, err := badger.Open(dps.DefaultOptions(flagData).WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("data", flagData).Err(err).Msg("could not open protocol state database")
+		return failure
+	}
+	defer func() {
+		err := protocolDB.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close protocol state database")
+		}
+	}()
+	indexDB, err := badger.Open(dps.DefaultOptions(indexDir))
+	if err != nil {
+		log.Error().Str("index", indexDir).Err(err).Msg("could not open index database")
+		return failure
+	}
+	defer func() {
+		err := indexDB.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close index database")
+		}
+	}()
+
+	// We do not want to mix replayed data with an existing index.
+	codec := zbor.NewCodec()
+	storage := storage.New(codec)
+	read := index.NewReader(indexDB, storage)
+	_, err = read.First()
+	if err == nil {
+		log.Error().Str("index", indexDir).Msg("index database is not empty, please provide an empty directory")
+		return failure
+	}
+	if !errors.Is(err, badger.ErrKeyNotFound) {
+		log.Error().Err(err).Msg("could not check whether index database is empty")
+		return failure
+	}
+
+	// We replay the blocks after the root block for which we have a captured
+	// execution record, up to the first block without one.
+	blockIDs, err := replayBlocks(protocolDB, flagRecords)
+	if err != nil {
+		log.Error().Str("records", flagRecords).Err(err).Msg("could not determine blocks to replay")
+		return failure
+	}
+	if len(blockIDs) == 0 {
+		log.Error().Str("records", flagRecords).Msg("no execution records to replay")
+		return failure
+	}
+	var root uint64
+	err = protocolDB.View(operation.RetrieveRootHeight(&root))
+	if err != nil {
+		log.Error().Err(err).Msg("could not retrieve root height")
+		return failure
+	}
+	last := root + uint64(len(blockIDs))
+
+	log.Info().Uint64("root", root).Uint64("last", last).Msg("replaying execution records")
+
+	// The file streamer plays the role of the cloud streamer of the live
+	// indexer, so the mapper processes the records exactly like it would have
+	// when they were downloaded.
+	stream := cloud.NewFileStreamer(log, flagRecords, blockIDs)
+	execution, err := tracker.NewExecution(log, protocolDB, stream)
+	if err != nil {
+		log.Error().Err(err).Msg("could not initialize execution tracker")
+		return failure
+	}
+	consensus, err := tracker.NewConsensus(log, protocolDB, execution)
+	if err != nil {
+		log.Error().Err(err).Msg("could not initialize consensus tracker")
+		return failure
+	}
+	chain := &boundedChain{Chain: consensus, last: last}
+
+	file, err := os.Open(flagCheckpoint)
+	if err != nil {
+		log.Error().Err(err).Msg("could not open checkpoint file")
+		return failure
+	}
+	defer file.Close()
+	load := loader.FromCheckpoint(file)
+
+	write := index.NewWriter(log, indexDB, storage)
+	transitions := mapper.NewTransitions(log, load, chain, execution, read, write,
+		mapper.WithBootstrapState(true),
+		mapper.WithSkipRegisters(flagSkip),
+	)
+	state := mapper.EmptyState(forest.New())
+	fsm := mapper.NewFSM(state,
+		mapper.WithTransition(mapper.StatusInitialize, transitions.InitializeMapper),
+		mapper.WithTransition(mapper.StatusBootstrap, transitions.BootstrapState),
+		mapper.WithTransition(mapper.StatusResume, transitions.ResumeIndexing),
+		mapper.WithTransition(mapper.StatusIndex, transitions.IndexChain),
+		mapper.WithTransition(mapper.StatusUpdate, transitions.UpdateTree),
+		mapper.WithTransition(mapper.StatusCollect, transitions.CollectRegisters),
+		mapper.WithTransition(mapper.StatusMap, transitions.MapRegisters),
+		mapper.WithTransition(mapper.StatusForward, transitions.ForwardHeight),
+	)
+	// On interrupt, the replay stops after the current transition, and the
+	// heights that were completely replayed are still logged below.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	err = fsm.RunWithContext(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("could not replay execution records")
+		_ = write.Close()
+		return failure
+	}
+
+	// Closing the writer makes sure that all of the replayed data is
+	// committed before we read it back.
+	err = write.Close()
+	if err != nil {
+		log.Error().Err(err).Msg("could not close index writer")
+		return failure
+	}
+
+	// Finally, we log the state commitment of each replayed height, so that it
+	// can be compared against a reference index.
+	indexed, err := read.Last()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get last replayed height")
+		return failure
+	}
+	for height := root; height <= indexed; height++ {
+		commit, err := read.Commit(height)
+		if err != nil {
+			log.Error().Uint64("height", height).Err(err).Msg("could not get replayed state commitment")
+			return failure
+		}
+		log.Info().Uint64("height", height).Hex("commit", commit[:]).Msg("replayed state commitment")
+	}
+
+	if indexed < last {
+		log.Error().Uint64("indexed", indexed).Uint64("last", last).Msg("not all execution records were replayed")
+		return failure
+	}
+
+	log.Info().Uint64("last", indexed).Msg("execution records replayed")
+
+	return success
+}
+
+// replayBlocks returns the IDs of the consecutive finalized blocks after the
+// root block for which an execution record exists in the given directory.
+func replayBlocks(db *badger.DB, dir string) ([]flow.Identifier, error) {
+
+	var root uint64
+	err := db.View(operation.RetrieveRootHeight(&root))
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve root height: %w", err)
+	}
+	var finalized uint64
+	err = db.View(operation.RetrieveFinalizedHeight(&finalized))
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve finalized height: %w", err)
+	}
+
+	var blockIDs []flow.Identifier
+	for height := root + 1; height <= finalized; height++ {
+		var blockID flow.Identifier
+		err := db.View(operation.LookupBlockHeight(height, &blockID))
+		if err != nil {
+			return nil, fmt.Errorf("could not look up block (height: %d): %w", height, err)
+		}
+		_, err = os.Stat(filepath.Join(dir, cloud.RecordName(blockID)))
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not check execution record (block: %x): %w", blockID, err)
+		}
+		blockIDs = append(blockIDs, blockID)
+	}
+
+	return blockIDs, nil
+}
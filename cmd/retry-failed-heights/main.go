@@ -0,0 +1,142 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/chain"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/mapper"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagData      string
+		flagIndex     string
+		flagLevel     string
+		flagLogFormat string
+
+		flagVersion bool
+	)
+
+	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
+	pflag.StringVarP(&flagIndex, "index", "i", "index", "path to database directory for state index")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Open the needed databases.
+	indexDB, err := badger.Open(dps.DefaultOptions(flagIndex))
+	if err != nil {
+		log.Error().Str("index", flagIndex).Err(err).Msg("could not open index database")
+		return failure
+	}
+	defer func() {
+		err := indexDB.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close index database")
+		}
+	}()
+	protocolDB, err := badger.Open(dps.DefaultOptions(flagData))
+	if err != nil {
+		log.Error().Str("data", flagData).Err(err).Msg("could not open protocol state database")
+		return failure
+	}
+	defer func() {
+		err := protocolDB.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close protocol state database")
+		}
+	}()
+
+	// We read the heights for which indexing failed from the index, and then
+	// try to index their execution data again from the protocol state.
+	codec := zbor.NewCodec()
+	storage := storage.New(codec)
+	read := index.NewReader(indexDB, storage)
+	failures, err := read.Failures()
+	if err != nil {
+		log.Error().Err(err).Msg("could not read failed heights")
+		return failure
+	}
+	if len(failures) == 0 {
+		log.Info().Msg("no failed heights to retry")
+		return success
+	}
+
+	log.Info().Int("failures", len(failures)).Msg("retrying failed heights")
+
+	disk := chain.FromDisk(protocolDB)
+	write := index.NewWriter(log, indexDB, storage)
+	failed, err := mapper.RetryFailures(log, disk, write, failures)
+	if err != nil {
+		log.Error().Err(err).Msg("could not retry failed heights")
+		_ = write.Close()
+		return failure
+	}
+
+	// Closing the writer makes sure that all of the retried execution data and
+	// the removal of the recovered failures are committed.
+	err = write.Close()
+	if err != nil {
+		log.Error().Err(err).Msg("could not close index writer")
+		return failure
+	}
+
+	if len(failed) > 0 {
+		log.Error().Uints64("heights", failed).Msg("some failed heights could not be indexed")
+		return failure
+	}
+
+	log.Info().Int("recovered", len(failures)).Msg("all failed heights indexed successfully")
+
+	return success
+}
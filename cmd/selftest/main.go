@@ -0,0 +1,92 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/selftest"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagIndex     string
+		flagLevel     string
+		flagLogFormat string
+
+		flagVersion bool
+	)
+
+	pflag.StringVarP(&flagIndex, "index", "i", "", "path to empty database directory for the self-test index (temporary directory when left empty)")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// If no index directory is given, we use a temporary one, which we clean
+	// up once the self-test is done.
+	dir := flagIndex
+	if dir == "" {
+		dir, err = os.MkdirTemp("", "selftest-index-*")
+		if err != nil {
+			log.Error().Err(err).Msg("could not create temporary index directory")
+			return failure
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	start := time.Now()
+	err = selftest.Run(log, dir)
+	if err != nil {
+		log.Error().Str("index", dir).Err(err).Msg("self-test failed")
+		return failure
+	}
+
+	log.Info().Dur("duration", time.Since(start)).Msg("self-test passed")
+
+	return success
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/spf13/pflag"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/chain"
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/service/loader"
+)
+
+const (
+	success = 0
+	failure = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+
+	// Command line parameter initialization.
+	var (
+		flagCheckpoint string
+		flagData       string
+		flagLevel      string
+		flagLogFormat  string
+
+		flagVersion bool
+	)
+
+	pflag.StringVarP(&flagCheckpoint, "checkpoint", "c", "root.checkpoint", "path to root checkpoint file for execution state trie")
+	pflag.StringVarP(&flagData, "data", "d", "data", "path to database directory for protocol data")
+	pflag.StringVarP(&flagLevel, "level", "l", "info", "log output level")
+	pflag.StringVar(&flagLogFormat, "log-format", "json", "log output format (json or console)")
+
+	pflag.BoolVar(&flagVersion, "version", false, "print version information and exit")
+
+	pflag.Parse()
+
+	// If only the version is requested, we print it and exit right away.
+	if flagVersion {
+		fmt.Println(dps.Build())
+		return success
+	}
+
+	// Logger initialization.
+	log, err := initializer.Logger(os.Stderr, flagLevel, flagLogFormat)
+	if err != nil {
+		log.Error().Str("level", flagLevel).Str("format", flagLogFormat).Err(err).Msg("could not initialize logger")
+		return failure
+	}
+
+	// Open the protocol state database in read-only mode and detect which chain
+	// it belongs to, so that the output identifies the bootstrap data.
+	db, err := badger.Open(dps.DefaultOptions(flagData).WithReadOnly(true))
+	if err != nil {
+		log.Error().Str("data", flagData).Err(err).Msg("could not open protocol state database")
+		return failure
+	}
+	defer db.Close()
+
+	disk := chain.FromDisk(db)
+	chainID, err := chain.ID(disk)
+	if err != nil {
+		log.Error().Err(err).Msg("could not detect chain of protocol state")
+		return failure
+	}
+	root, err := disk.Root()
+	if err != nil {
+		log.Error().Err(err).Msg("could not get root height of protocol state")
+		return failure
+	}
+
+	// Load the execution state trie from the root checkpoint, which gives us
+	// the state commitment it represents.
+	file, err := os.Open(flagCheckpoint)
+	if err != nil {
+		log.Error().Str("checkpoint", flagCheckpoint).Err(err).Msg("could not open root checkpoint")
+		return failure
+	}
+	defer file.Close()
+
+	log.Info().Str("checkpoint", flagCheckpoint).Msg("loading root checkpoint, this might take a while")
+
+	tree, err := loader.FromCheckpoint(file).Trie()
+	if err != nil {
+		log.Error().Err(err).Msg("could not load root checkpoint")
+		return failure
+	}
+	commit := flow.StateCommitment(tree.RootHash())
+
+	// Finally, make sure that the checkpoint corresponds to the root block of
+	// the protocol state.
+	err = chain.VerifyRoot(disk, commit)
+	if err != nil {
+		log.Error().Str("chain", chainID.String()).Uint64("root", root).Err(err).Msg("root checkpoint does not match protocol state")
+		return failure
+	}
+
+	log.Info().
+		Str("chain", chainID.String()).
+		Uint64("root", root).
+		Hex("commit", commit[:]).
+		Msg("root checkpoint matches protocol state")
+
+	return success
+}
@@ -16,7 +16,6 @@ package generator
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/klauspost/compress/zstd"
@@ -27,7 +26,7 @@ import (
 // It then sets that information directly into the given dictionary pointer.
 func (g *Generator) benchmarkDictionary(dict *dictionary) error {
 
-	samples, err := g.getSamples(dict.kind, 10000)
+	samples, err := g.getSamples(dict.kind, 10000, 0)
 	if err != nil {
 		return fmt.Errorf("could not retrieve samples: %w", err)
 	}
@@ -52,7 +51,7 @@ func (g *Generator) benchmarkDictionary(dict *dictionary) error {
 	var compressed, uncompressed int
 	for i := 0; i < 50000; i++ {
 		// Pick a random sample.
-		sample := samples[rand.Int()%len(samples)]
+		sample := samples[g.random.Intn(len(samples))]
 
 		uncompressed += len(sample)
 		compressed += len(compressor.EncodeAll(sample, nil))
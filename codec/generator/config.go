@@ -22,6 +22,8 @@ var DefaultConfig = Config{
 	RatioImprovements: 0.1,               // 10% improvement per iteration
 	SamplePath:        "",
 	DictionaryPath:    "./codec/zbor/",
+	Seed:              0,
+	SampleCount:       0,
 }
 
 type Config struct {
@@ -40,6 +42,13 @@ type Config struct {
 	SamplePath string
 	// The path in which to store compiled Go dictionaries. Should point to the package in which they should be used.
 	DictionaryPath string
+
+	// The seed used to randomly select samples, which makes the selection reproducible. When it is zero, a
+	// time-based seed is used instead.
+	Seed int64
+	// The maximum number of samples to use for training each dictionary, in addition to the size limit. When
+	// it is zero, only the size limit applies.
+	SampleCount int
 }
 
 // Option is an option that can be given to the generator to configure optional
@@ -82,3 +91,19 @@ func WithDictionaryPath(path string) Option {
 		cfg.DictionaryPath = path
 	}
 }
+
+// WithSeed sets the seed used to randomly select samples, so that the samples used to train and benchmark
+// dictionaries are the same across runs. A zero seed uses a time-based seed.
+func WithSeed(seed int64) Option {
+	return func(cfg *Config) {
+		cfg.Seed = seed
+	}
+}
+
+// WithSampleCount sets the maximum number of samples to use for training each dictionary. A zero count
+// means that only the total size of the samples is limited.
+func WithSampleCount(count int) Option {
+	return func(cfg *Config) {
+		cfg.SampleCount = count
+	}
+}
@@ -26,6 +26,7 @@ const (
 	KindPayloads     DictionaryKind = "payloads"
 	KindEvents       DictionaryKind = "events"
 	KindTransactions DictionaryKind = "transactions"
+	KindResults      DictionaryKind = "results"
 )
 
 func (k DictionaryKind) String() string {
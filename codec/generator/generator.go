@@ -19,6 +19,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/rs/zerolog"
@@ -30,10 +31,11 @@ import (
 // Generator generates optimized Zstandard dictionaries and turns them into Go files
 // to be used for compression.
 type Generator struct {
-	cfg   Config
-	log   zerolog.Logger
-	db    *badger.DB
-	codec dps.Codec
+	cfg    Config
+	log    zerolog.Logger
+	db     *badger.DB
+	codec  dps.Codec
+	random *rand.Rand
 }
 
 // New returns a new dictionary generator.
@@ -44,13 +46,21 @@ func New(log zerolog.Logger, db *badger.DB, codec dps.Codec, opts ...Option) *Ge
 		opt(&cfg)
 	}
 
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
 	g := Generator{
-		log:   log.With().Str("component", "generator").Logger(),
-		cfg:   cfg,
-		db:    db,
-		codec: codec,
+		log:    log.With().Str("component", "generator").Logger(),
+		cfg:    cfg,
+		db:     db,
+		codec:  codec,
+		random: rand.New(rand.NewSource(seed)),
 	}
 
+	g.log.Info().Int64("seed", seed).Msg("initialized random sample selection")
+
 	return &g
 }
 
@@ -80,8 +90,9 @@ func (g *Generator) Dictionary(kind DictionaryKind) error {
 			previous = current
 		}
 
-		// Generate samples equal in size to 100 times the desired dictionary size.
-		err = g.generateSamples(kind, size*100)
+		// Generate samples equal in size to 100 times the desired dictionary size,
+		// unless the configured maximum number of samples is reached first.
+		err = g.generateSamples(kind, size*100, g.cfg.SampleCount)
 		if err != nil {
 			return fmt.Errorf("could not generate samples: %w", err)
 		}
@@ -139,8 +150,9 @@ func (g *Generator) tolerateImprovement(current, previous *dictionary) bool {
 	return betterCompressionRatio || betterSpeed
 }
 
-// generateSamples generates the right amount of samples to match the given size.
-func (g *Generator) generateSamples(kind DictionaryKind, size int) error {
+// generateSamples generates the right amount of samples to match the given size
+// or count, whichever limit is reached first. A zero count means no count limit.
+func (g *Generator) generateSamples(kind DictionaryKind, size int, count int) error {
 
 	// Create a directory in which to store the samples.
 	dirPath := filepath.Join(g.cfg.SamplePath, string(kind))
@@ -150,7 +162,7 @@ func (g *Generator) generateSamples(kind DictionaryKind, size int) error {
 	}
 
 	// Retrieve samples from the index database.
-	samples, err := g.getSamples(kind, size)
+	samples, err := g.getSamples(kind, size, count)
 	if err != nil {
 		return fmt.Errorf("could not retrieve samples: %w", err)
 	}
@@ -167,8 +179,9 @@ func (g *Generator) generateSamples(kind DictionaryKind, size int) error {
 	return nil
 }
 
-// getSamples retrieves the requested total size of samples in bytes from the index database.
-func (g *Generator) getSamples(kind DictionaryKind, size int) ([][]byte, error) {
+// getSamples retrieves the requested total size of samples in bytes from the index database. If the
+// given count is not zero, it stops once it has retrieved that many samples.
+func (g *Generator) getSamples(kind DictionaryKind, size int, count int) ([][]byte, error) {
 
 	// Create an iterator prefix based on the kind of sample we want.
 	var prefix []byte
@@ -177,15 +190,17 @@ func (g *Generator) getSamples(kind DictionaryKind, size int) ([][]byte, error)
 		prefix = storage.EncodeKey(storage.PrefixPayload)
 	case KindTransactions:
 		prefix = storage.EncodeKey(storage.PrefixTransaction)
+	case KindResults:
+		prefix = storage.EncodeKey(storage.PrefixResults)
 	case KindEvents:
 		// TODO: Select an event type in the prefix. See https://github.com/optakt/flow-dps/issues/501
 		prefix = storage.EncodeKey(storage.PrefixEvents)
 	}
 
-	key := generateRandomKey(prefix)
+	key := g.randomKey(prefix)
 
 	// Go through the entries of the index database until enough samples have been collected.
-	samples := make([][]byte, 0, size)
+	samples := make([][]byte, 0, count)
 	err := g.db.View(func(tx *badger.Txn) error {
 		it := tx.NewIterator(badger.IteratorOptions{
 			Prefix: prefix,
@@ -195,17 +210,21 @@ func (g *Generator) getSamples(kind DictionaryKind, size int) ([][]byte, error)
 		it.Seek(key)
 
 		var totalBytes int
-		for totalBytes <= size {
-			sampleKey := it.Item().Key()
+		for totalBytes <= size && (count == 0 || len(samples) < count) {
 
-			// If we're out of entries to read from, reset the iterator.
-			// This will result in duplicate entries in the samples, but should not be a big deal.
+			// If we're out of entries to read from, reset the iterator to the
+			// first entry with the prefix. This will result in duplicate
+			// entries in the samples, but should not be a big deal.
 			if !it.ValidForPrefix(prefix) {
 				g.log.Info().Msg("reached end of entries in index database, rewinding")
 
 				it.Rewind()
-				it.Seek(key)
 			}
+			if !it.ValidForPrefix(prefix) {
+				return fmt.Errorf("no entries with prefix %x in index database", prefix)
+			}
+
+			sampleKey := it.Item().KeyCopy(nil)
 
 			// Retrieve the value of the sample.
 			val, err := tx.Get(sampleKey)
@@ -246,11 +265,13 @@ func (g *Generator) getSamples(kind DictionaryKind, size int) ([][]byte, error)
 	return samples, nil
 }
 
-func generateRandomKey(prefix []byte) []byte {
-	key := make([]byte, 0, 64)
+// randomKey returns a random key with the given prefix, from which to start
+// reading samples.
+func (g *Generator) randomKey(prefix []byte) []byte {
+	key := make([]byte, 64)
 
 	// Fill key with random bytes.
-	_, _ = rand.Read(key)
+	_, _ = g.random.Read(key)
 
 	// Replace beginning of key with wanted prefix.
 	copy(key, prefix)
@@ -0,0 +1,189 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/service/storage"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestGenerator_getSamples(t *testing.T) {
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	err := db.Update(func(tx *badger.Txn) error {
+		// Spread the keys evenly over the key space, so that random keys
+		// land on different entries.
+		for i := uint64(0); i < 256; i++ {
+			key := storage.EncodeKey(storage.PrefixPayload, i<<56, mocks.GenericLedgerPath(0))
+			err := tx.Set(key, []byte{byte(i), 0x42})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	codec := mocks.BaselineCodec(t)
+	codec.DecompressFunc = func(compressed []byte) ([]byte, error) {
+		return compressed, nil
+	}
+
+	t.Run("same seed yields same samples", func(t *testing.T) {
+		first := New(mocks.NoopLogger, db, codec, WithSeed(1337))
+		second := New(mocks.NoopLogger, db, codec, WithSeed(1337))
+
+		for i := 0; i < 3; i++ {
+			want, err := first.getSamples(KindPayloads, 64, 0)
+			require.NoError(t, err)
+
+			got, err := second.getSamples(KindPayloads, 64, 0)
+			require.NoError(t, err)
+
+			assert.Equal(t, want, got)
+		}
+	})
+
+	t.Run("different seeds yield different samples", func(t *testing.T) {
+		first := New(mocks.NoopLogger, db, codec, WithSeed(1337))
+		second := New(mocks.NoopLogger, db, codec, WithSeed(7331))
+
+		want, err := first.getSamples(KindPayloads, 64, 0)
+		require.NoError(t, err)
+
+		got, err := second.getSamples(KindPayloads, 64, 0)
+		require.NoError(t, err)
+
+		assert.NotEqual(t, want, got)
+	})
+
+	t.Run("count limits number of samples", func(t *testing.T) {
+		gen := New(mocks.NoopLogger, db, codec, WithSeed(1337))
+
+		got, err := gen.getSamples(KindPayloads, 64, 10)
+		require.NoError(t, err)
+
+		assert.Len(t, got, 10)
+	})
+
+	t.Run("size limits number of samples", func(t *testing.T) {
+		gen := New(mocks.NoopLogger, db, codec, WithSeed(1337))
+
+		got, err := gen.getSamples(KindPayloads, 64, 1000)
+		require.NoError(t, err)
+
+		// Each sample is two bytes long, and sampling stops once the total
+		// size exceeds the requested size.
+		assert.Len(t, got, 33)
+	})
+
+	t.Run("handles missing entries", func(t *testing.T) {
+		gen := New(mocks.NoopLogger, db, codec, WithSeed(1337))
+
+		_, err := gen.getSamples(KindTransactions, 64, 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestGenerator_Results(t *testing.T) {
+
+	// Training dictionaries relies on the zstd command line tool.
+	_, err := exec.LookPath("zstd")
+	if err != nil {
+		t.Skip("zstd command not available")
+	}
+
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	// Most transaction results have no error message, while the others share
+	// a lot of their structure, which is what a dictionary can take advantage
+	// of on such small values.
+	codec := zbor.NewCodec()
+	lib := storage.New(codec)
+	random := rand.New(rand.NewSource(1337))
+	err = db.Update(func(tx *badger.Txn) error {
+		for i := 0; i < 2000; i++ {
+			var txID flow.Identifier
+			_, _ = random.Read(txID[:])
+			result := flow.TransactionResult{
+				TransactionID: txID,
+			}
+			if i%3 == 0 {
+				result.ErrorMessage = fmt.Sprintf("[Error Code: 1101] cadence runtime error Execution failed:\nerror: pre-condition failed: Amount withdrawn must be less than or equal than the balance of the Vault\n --> %x.FungibleToken:%d:%d", txID[:8], random.Intn(200), random.Intn(80))
+			}
+			err := lib.SaveResult(&result)(tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	t.Run("dictionary improves compression", func(t *testing.T) {
+		gen := New(mocks.NoopLogger, db, codec,
+			WithSeed(1337),
+			WithSamplePath(t.TempDir()),
+			WithDictionaryPath(t.TempDir()),
+		)
+
+		baseline := dictionary{kind: KindResults}
+		err := gen.benchmarkDictionary(&baseline)
+		require.NoError(t, err)
+
+		err = gen.generateSamples(KindResults, 100*minDictionarySize*4, 0)
+		require.NoError(t, err)
+		dict, err := gen.trainDictionary(KindResults, minDictionarySize*4)
+		require.NoError(t, err)
+		err = gen.benchmarkDictionary(dict)
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, dict.raw)
+		assert.Less(t, dict.ratio, baseline.ratio)
+	})
+
+	t.Run("produces results dictionary", func(t *testing.T) {
+		dictionaryPath := t.TempDir()
+		gen := New(mocks.NoopLogger, db, codec,
+			WithSeed(1337),
+			WithSamplePath(t.TempDir()),
+			WithDictionaryPath(dictionaryPath),
+		)
+
+		err := gen.Dictionary(KindResults)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dictionaryPath, "results.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "var resultsDictionary = []byte{")
+	})
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package zbor
+
+import (
+	"fmt"
+
+	"github.com/OneOfOne/xxhash"
+)
+
+// Fingerprint returns a short hexadecimal fingerprint of the dictionaries that
+// the codec uses to compress new values. It can be stored alongside encoded
+// data, to identify which dictionaries it was compressed with.
+func Fingerprint() string {
+	hash := xxhash.New64()
+	for _, dictionary := range [][]byte{genericDictionary, payloadDictionary, eventDictionary, transactionDictionary} {
+		_, _ = hash.Write(dictionary)
+	}
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
@@ -16,6 +16,7 @@ package engine
 
 import (
 	"os"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -27,6 +28,8 @@ type Engine struct {
 
 	interrupt chan os.Signal
 	notify    chan error
+	timeout   time.Duration
+	exit      func(code int)
 }
 
 // New creates a new engine.
@@ -34,11 +37,21 @@ func New(log zerolog.Logger, name string, interrupt chan os.Signal) *Engine {
 	e := Engine{
 		log:       log.With().Str("engine", name).Logger(),
 		interrupt: interrupt,
+		timeout:   0,
+		exit:      os.Exit,
 	}
 
 	return &e
 }
 
+// ShutdownTimeout sets the maximum duration for the graceful shutdown of the
+// engine's components, after which the engine forcefully exits. A duration of
+// zero means that the engine waits for the components indefinitely.
+func (e *Engine) ShutdownTimeout(timeout time.Duration) *Engine {
+	e.timeout = timeout
+	return e
+}
+
 // Component registers a new component for the engine. Components will be shut down
 // in the same order as the one in which they were registered.
 func (e *Engine) Component(name string, run func() error, stop func()) *Engine {
@@ -83,18 +96,34 @@ func (e *Engine) Run() error {
 func (e *Engine) stop() {
 	// Launch goroutine to listen on interrupt channel
 	// to allow force quitting while components are being
-	// gracefully stopped.
-	go e.forceQuit()
+	// gracefully stopped, or once the shutdown timeout is reached.
+	done := make(chan struct{})
+	go e.forceQuit(done)
 	// Components are stopped in the reverse order in which they were registered.
 	for i := len(e.components) - 1; i >= 0; i-- {
 		e.components[i].Stop()
 	}
+	close(done)
 }
 
-// forceQuit waits for an interrupt signal to be received and if so, forcefully
-// exits with an error status code.
-func (e *Engine) forceQuit() {
-	<-e.interrupt
-	e.log.Warn().Msg("forcing exit")
-	os.Exit(1)
+// forceQuit waits for an interrupt signal to be received or for the shutdown
+// timeout to expire and if so, forcefully exits with an error status code. It
+// returns without exiting once the given channel is closed.
+func (e *Engine) forceQuit(done <-chan struct{}) {
+	var timeout <-chan time.Time
+	if e.timeout > 0 {
+		timer := time.NewTimer(e.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-done:
+		return
+	case <-e.interrupt:
+		e.log.Warn().Msg("forcing exit")
+	case <-timeout:
+		e.log.Warn().Dur("timeout", e.timeout).Msg("shutdown timed out, forcing exit")
+	}
+	e.exit(1)
 }
@@ -0,0 +1,115 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package engine
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestEngine_ShutdownTimeout(t *testing.T) {
+
+	// setup creates an engine with a single component, which takes the given
+	// duration to stop, and which records the exit codes of the engine.
+	setup := func(delay time.Duration, timeout time.Duration) (*Engine, chan os.Signal, chan int) {
+		interrupt := make(chan os.Signal, 1)
+		exits := make(chan int, 1)
+		stopped := make(chan struct{})
+		e := New(mocks.NoopLogger, "test", interrupt).
+			ShutdownTimeout(timeout).
+			Component(
+				"test",
+				func() error {
+					<-stopped
+					return nil
+				},
+				func() {
+					time.Sleep(delay)
+					close(stopped)
+				},
+			)
+		e.exit = func(code int) {
+			exits <- code
+		}
+		return e, interrupt, exits
+	}
+
+	t.Run("forces exit once timeout expires", func(t *testing.T) {
+		t.Parallel()
+
+		e, interrupt, exits := setup(500*time.Millisecond, 50*time.Millisecond)
+
+		interrupt <- syscall.SIGINT
+		start := time.Now()
+		err := e.Run()
+
+		assert.NoError(t, err)
+		select {
+		case code := <-exits:
+			assert.Equal(t, 1, code)
+		default:
+			t.Fatal("engine did not force exit")
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("does not force exit before timeout", func(t *testing.T) {
+		t.Parallel()
+
+		e, interrupt, exits := setup(10*time.Millisecond, time.Second)
+
+		interrupt <- syscall.SIGINT
+		err := e.Run()
+
+		assert.NoError(t, err)
+		assert.Never(t, func() bool {
+			return len(exits) > 0
+		}, 100*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("waits indefinitely without timeout", func(t *testing.T) {
+		t.Parallel()
+
+		e, interrupt, exits := setup(200*time.Millisecond, 0)
+
+		interrupt <- syscall.SIGINT
+		err := e.Run()
+
+		assert.NoError(t, err)
+		assert.Empty(t, exits)
+	})
+
+	t.Run("forces exit on second interrupt", func(t *testing.T) {
+		t.Parallel()
+
+		e, interrupt, exits := setup(200*time.Millisecond, 0)
+
+		interrupt <- syscall.SIGINT
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			interrupt <- syscall.SIGINT
+		}()
+		err := e.Run()
+
+		assert.NoError(t, err)
+		assert.Len(t, exits, 1)
+	})
+}
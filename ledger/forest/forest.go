@@ -15,6 +15,8 @@
 package forest
 
 import (
+	"sync"
+
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 
@@ -29,7 +31,9 @@ type step struct {
 
 // Forest is a representation of multiple tries mapped by their state commitment hash.
 // NOTE: Contrary to the Flow Forest implementation, the forest is unlimited and never evicts any tries.
+// It is safe for concurrent use, so that its size can be observed while it is being mutated.
 type Forest struct {
+	mu    sync.RWMutex
 	steps map[flow.StateCommitment]step
 }
 
@@ -51,17 +55,27 @@ func (f *Forest) Add(tree *trie.Trie, paths []ledger.Path, parent flow.StateComm
 		paths:  paths,
 		parent: parent,
 	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	f.steps[commit] = s
 }
 
 // Has returns whether a state commitment matches one of the trees within the forest.
 func (f *Forest) Has(commit flow.StateCommitment) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	_, ok := f.steps[commit]
 	return ok
 }
 
 // Tree returns the matching tree for the given state commitment.
 func (f *Forest) Tree(commit flow.StateCommitment) (*trie.Trie, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	s, ok := f.steps[commit]
 	if !ok {
 		return nil, false
@@ -72,6 +86,9 @@ func (f *Forest) Tree(commit flow.StateCommitment) (*trie.Trie, bool) {
 
 // Paths returns the matching tree's paths for the given state commitment.
 func (f *Forest) Paths(commit flow.StateCommitment) ([]ledger.Path, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	s, ok := f.steps[commit]
 	if !ok {
 		return nil, false
@@ -82,6 +99,9 @@ func (f *Forest) Paths(commit flow.StateCommitment) ([]ledger.Path, bool) {
 
 // Parent returns the parent of the given state commitment.
 func (f *Forest) Parent(commit flow.StateCommitment) (flow.StateCommitment, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	s, ok := f.steps[commit]
 	if !ok {
 		return flow.DummyStateCommitment, false
@@ -92,6 +112,9 @@ func (f *Forest) Parent(commit flow.StateCommitment) (flow.StateCommitment, bool
 
 // Reset deletes all tries that do not match the given state commitment.
 func (f *Forest) Reset(finalized flow.StateCommitment) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	for commit := range f.steps {
 		if commit != finalized {
 			delete(f.steps, commit)
@@ -99,8 +122,103 @@ func (f *Forest) Reset(finalized flow.StateCommitment) {
 	}
 }
 
+// Prune deletes all tries that are neither the trie matching the given
+// finalized state commitment nor one of its descendants. Competing branches and
+// ancestors of the finalized trie can no longer be extended to the finalized
+// chain, while descendants might still become finalized later on.
+func (f *Forest) Prune(finalized flow.StateCommitment) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// We keep track of whether each step descends from the finalized step, so
+	// that each chain of parents is only walked once.
+	extends := make(map[flow.StateCommitment]bool, len(f.steps))
+	extends[finalized] = true
+	for commit := range f.steps {
+
+		// We walk up the chain of parents until we find a step for which we
+		// already know the answer, or until we reach the root of the forest.
+		var chain []flow.StateCommitment
+		result := false
+		for {
+			known, ok := extends[commit]
+			if ok {
+				result = known
+				break
+			}
+			chain = append(chain, commit)
+			s, ok := f.steps[commit]
+			if !ok {
+				break
+			}
+			commit = s.parent
+		}
+
+		for _, commit := range chain {
+			extends[commit] = result
+		}
+	}
+
+	for commit := range f.steps {
+		if !extends[commit] {
+			delete(f.steps, commit)
+		}
+	}
+}
+
+// Size returns the number of tries in the forest.
+func (f *Forest) Size() uint {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return uint(len(f.steps))
+}
+
+// Heights returns the number of tries on the longest chain of the forest,
+// which is the number of trie updates between its oldest and its newest trie.
+func (f *Forest) Heights() uint {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	// We remember the length of the chain ending at each step, so that each
+	// chain of parents is only walked once.
+	lengths := make(map[flow.StateCommitment]uint, len(f.steps))
+	var max uint
+	for commit := range f.steps {
+
+		var chain []flow.StateCommitment
+		var length uint
+		for {
+			known, ok := lengths[commit]
+			if ok {
+				length = known
+				break
+			}
+			s, ok := f.steps[commit]
+			if !ok {
+				break
+			}
+			chain = append(chain, commit)
+			commit = s.parent
+		}
+
+		for i := len(chain) - 1; i >= 0; i-- {
+			length++
+			lengths[chain[i]] = length
+		}
+		if length > max {
+			max = length
+		}
+	}
+
+	return max
+}
+
 // Trees returns each of the tries from the forest.
 func (f *Forest) Trees() []*trie.Trie {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	var tries []*trie.Trie
 	for _, step := range f.steps {
 		tries = append(tries, step.tree)
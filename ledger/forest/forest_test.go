@@ -0,0 +1,150 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package forest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/ledger/forest"
+	"github.com/optakt/flow-dps/ledger/trie"
+	"github.com/optakt/flow-dps/testing/helpers"
+)
+
+func TestForest_Prune(t *testing.T) {
+
+	// We build the following forest, where each trie is the result of a
+	// mutation on its parent:
+	//
+	//   A -> B -> C
+	//   |    `--> D -> E
+	//   `--> F -> G
+	paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 6)
+	mutate := func(parent *trie.Trie, index int) *trie.Trie {
+		tree, err := parent.Mutate(paths[index:index+1], payloads[index:index+1])
+		require.NoError(t, err)
+		return tree
+	}
+	a := trie.NewEmptyTrie()
+	b := mutate(a, 0)
+	c := mutate(b, 1)
+	d := mutate(b, 2)
+	e := mutate(d, 3)
+	f := mutate(a, 4)
+	g := mutate(f, 5)
+	unknown := mutate(e, 4)
+
+	commit := func(tree *trie.Trie) flow.StateCommitment {
+		return flow.StateCommitment(tree.RootHash())
+	}
+	build := func() *forest.Forest {
+		forest := forest.New()
+		forest.Add(a, nil, flow.DummyStateCommitment)
+		for _, step := range []struct{ tree, parent *trie.Trie }{
+			{b, a}, {c, b}, {d, b}, {e, d}, {f, a}, {g, f},
+		} {
+			forest.Add(step.tree, nil, commit(step.parent))
+		}
+		return forest
+	}
+	all := []*trie.Trie{a, b, c, d, e, f, g}
+
+	tests := []struct {
+		name      string
+		finalized *trie.Trie
+		want      []*trie.Trie
+	}{
+		{name: "root keeps all tries", finalized: a, want: all},
+		{name: "branch drops ancestors and competing branches", finalized: b, want: []*trie.Trie{b, c, d, e}},
+		{name: "inner node keeps only its descendants", finalized: d, want: []*trie.Trie{d, e}},
+		{name: "leaf keeps only itself", finalized: g, want: []*trie.Trie{g}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			forest := build()
+			forest.Prune(commit(test.finalized))
+
+			for _, tree := range all {
+				want := false
+				for _, kept := range test.want {
+					if kept == tree {
+						want = true
+					}
+				}
+				assert.Equal(t, want, forest.Has(commit(tree)))
+			}
+			assert.Len(t, forest.Trees(), len(test.want))
+		})
+	}
+
+	t.Run("unknown commit drops all tries", func(t *testing.T) {
+		t.Parallel()
+
+		forest := build()
+		forest.Prune(commit(unknown))
+
+		assert.Empty(t, forest.Trees())
+	})
+}
+
+func TestForest_Size(t *testing.T) {
+
+	paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 4)
+	mutate := func(parent *trie.Trie, index int) *trie.Trie {
+		tree, err := parent.Mutate(paths[index:index+1], payloads[index:index+1])
+		require.NoError(t, err)
+		return tree
+	}
+	commit := func(tree *trie.Trie) flow.StateCommitment {
+		return flow.StateCommitment(tree.RootHash())
+	}
+
+	// We build a forest with a chain of three tries and a competing branch
+	// with a single trie.
+	a := trie.NewEmptyTrie()
+	b := mutate(a, 0)
+	c := mutate(b, 1)
+	d := mutate(a, 2)
+
+	f := forest.New()
+	assert.Equal(t, uint(0), f.Size())
+	assert.Equal(t, uint(0), f.Heights())
+
+	f.Add(a, nil, flow.DummyStateCommitment)
+	assert.Equal(t, uint(1), f.Size())
+	assert.Equal(t, uint(1), f.Heights())
+
+	f.Add(b, nil, commit(a))
+	f.Add(c, nil, commit(b))
+	f.Add(d, nil, commit(a))
+	assert.Equal(t, uint(4), f.Size())
+	assert.Equal(t, uint(3), f.Heights())
+
+	f.Prune(commit(b))
+	assert.Equal(t, uint(2), f.Size())
+	assert.Equal(t, uint(2), f.Heights())
+
+	f.Prune(commit(c))
+	assert.Equal(t, uint(1), f.Size())
+	assert.Equal(t, uint(1), f.Heights())
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package forest
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RegisterMetrics exposes the size and the heights of the given forest as
+// prometheus gauges, which are updated whenever the metrics are collected.
+func RegisterMetrics(f *Forest) {
+	sizeOpts := prometheus.GaugeOpts{
+		Name: "forest_tries",
+		Help: "number of tries held in the forest",
+	}
+	promauto.NewGaugeFunc(sizeOpts, func() float64 {
+		return float64(f.Size())
+	})
+
+	heightsOpts := prometheus.GaugeOpts{
+		Name: "forest_heights",
+		Help: "number of tries on the longest chain of the forest",
+	}
+	promauto.NewGaugeFunc(heightsOpts, func() float64 {
+		return float64(f.Heights())
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package trie
+
+// DefaultConfig is the default configuration for tries.
+var DefaultConfig = Config{
+	NodePool: false,
+}
+
+// Config contains the configuration options for tries.
+type Config struct {
+	NodePool bool
+}
+
+// WithNodePool makes the trie allocate its nodes from a node pool, which
+// allocates nodes in batches rather than one by one. Tries that are derived
+// from it through mutations share the same pool.
+//
+// This is experimental: it reduces the number of allocations for large
+// mutations, but nodes are only garbage collected once all other nodes of
+// their batch are unreferenced, which can increase memory usage.
+func WithNodePool() func(*Config) {
+	return func(cfg *Config) {
+		cfg.NodePool = true
+	}
+}
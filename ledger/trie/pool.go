@@ -0,0 +1,85 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package trie
+
+import (
+	"sync"
+)
+
+// poolBatch is the number of nodes of each type that are allocated at once
+// by a node pool.
+const poolBatch = 1024
+
+// nodePool is an allocator for trie nodes, which allocates nodes of each type
+// in batches and hands them out one by one. Nodes are never put back into the
+// pool, as they might still be shared with other tries; this means a node can
+// never be handed out twice, so mutations on one trie can never corrupt the
+// nodes of another trie.
+type nodePool struct {
+	mu         sync.Mutex
+	groups     []Group
+	extensions []Extension
+	branches   []Branch
+	leaves     []Leaf
+}
+
+func newNodePool() *nodePool {
+	p := nodePool{}
+	return &p
+}
+
+func (p *nodePool) group() *Group {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.groups) == 0 {
+		p.groups = make([]Group, poolBatch)
+	}
+	group := &p.groups[0]
+	p.groups = p.groups[1:]
+	return group
+}
+
+func (p *nodePool) extension() *Extension {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.extensions) == 0 {
+		p.extensions = make([]Extension, poolBatch)
+	}
+	extension := &p.extensions[0]
+	p.extensions = p.extensions[1:]
+	return extension
+}
+
+func (p *nodePool) branch() *Branch {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.branches) == 0 {
+		p.branches = make([]Branch, poolBatch)
+	}
+	branch := &p.branches[0]
+	p.branches = p.branches[1:]
+	return branch
+}
+
+func (p *nodePool) leaf() *Leaf {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.leaves) == 0 {
+		p.leaves = make([]Leaf, poolBatch)
+	}
+	leaf := &p.leaves[0]
+	p.leaves = p.leaves[1:]
+	return leaf
+}
@@ -20,9 +20,9 @@ import (
 	"fmt"
 	"runtime"
 	"sort"
-	"sync"
 
 	"github.com/gammazero/deque"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/onflow/flow-go/ledger"
@@ -31,40 +31,39 @@ import (
 
 const maxDepth = ledger.NodeMaxHeight - 1
 
+// minParallelPaths is the minimum number of paths for which a mutation uses
+// multiple workers; below it, the overhead outweighs the gains.
+const minParallelPaths = 1024
+
 // Trie is a modified Patricia-Merkle Trie, which is the storage layer of the Flow ledger.
 // It uses a payload store to retrieve and persist ledger payloads.
 type Trie struct {
 	root Node
 
-	// TODO: Pre-allocate pools and put elements back in pool when no longer needed.
-	//  See https://github.com/optakt/flow-dps/issues/519
-	groups     *sync.Pool
-	extensions *sync.Pool
-	branches   *sync.Pool
-	leaves     *sync.Pool
+	// pool is the optional node pool used to allocate new nodes; when it is
+	// nil, nodes are allocated individually.
+	pool *nodePool
 }
 
 // NewEmptyTrie creates a new trie without a root node, with the given payload store.
-func NewEmptyTrie() *Trie {
-	t := Trie{
-		root:       nil,
-		groups:     &sync.Pool{New: func() interface{} { return new(Group) }},
-		extensions: &sync.Pool{New: func() interface{} { return new(Extension) }},
-		branches:   &sync.Pool{New: func() interface{} { return new(Branch) }},
-		leaves:     &sync.Pool{New: func() interface{} { return new(Leaf) }},
-	}
-
-	return &t
+func NewEmptyTrie(options ...func(*Config)) *Trie {
+	return NewTrie(nil, options...)
 }
 
 // NewTrie creates a new trie using the given root node and payload store.
-func NewTrie(root Node) *Trie {
+func NewTrie(root Node, options ...func(*Config)) *Trie {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
 	t := Trie{
-		root:       root,
-		groups:     &sync.Pool{New: func() interface{} { return new(Group) }},
-		extensions: &sync.Pool{New: func() interface{} { return new(Extension) }},
-		branches:   &sync.Pool{New: func() interface{} { return new(Branch) }},
-		leaves:     &sync.Pool{New: func() interface{} { return new(Leaf) }},
+		root: root,
+		pool: nil,
+	}
+	if cfg.NodePool {
+		t.pool = newNodePool()
 	}
 
 	return &t
@@ -102,7 +101,28 @@ func (t *Trie) RootHash() ledger.RootHash {
 	return hash
 }
 
+// Clone returns a new trie that shares all of its nodes with the original.
+// As mutations never modify existing nodes and copy the nodes along the
+// mutated paths instead, mutating either trie leaves the other unchanged.
+func (t *Trie) Clone() *Trie {
+	clone := Trie{
+		root: t.root,
+		pool: t.pool,
+	}
+	return &clone
+}
+
+// Mutate returns a new trie which is the result of inserting the given payloads
+// at the given paths into the trie. The original trie is left unchanged.
 func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, error) {
+	return t.MutateWithWorkers(paths, payloads, 1)
+}
+
+// MutateWithWorkers works like Mutate, but uses up to the given number of
+// workers to build the new trie concurrently when enough paths are inserted
+// for it to be worthwhile. Each worker builds a distinct subtrie, so the
+// resulting trie is the same regardless of the number of workers.
+func (t *Trie) MutateWithWorkers(paths []ledger.Path, payloads []ledger.Payload, workers int) (*Trie, error) {
 
 	// If there are no paths to be inserted, we can return right away. This will
 	// save us from dealing with some edge cases in the logic that follows.
@@ -123,11 +143,8 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 
 	// Create the new trie that will hold the mutated root.
 	target := &Trie{
-		root:       nil,
-		groups:     t.groups,
-		extensions: t.extensions,
-		branches:   t.branches,
-		leaves:     t.leaves,
+		root: nil,
+		pool: t.pool,
 	}
 
 	// We create a queue of groups, where each group represents a set of paths
@@ -138,7 +155,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 
 	// The first group of paths holds all paths, checking depth zero as the first
 	// depth, and using the root to determine what to do at that depth.
-	group := t.groups.Get().(*Group)
+	group := t.newGroup()
 	group.path = &path
 	group.source.node = &t.root
 	group.target.node = &target.root
@@ -149,9 +166,54 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 	// We then push that group into our queue and start consuming it.
 	sink.PushFront(group)
 
+	// Without multiple workers, or when there are not enough paths to make
+	// concurrency worthwhile, we simply process all groups sequentially.
+	if workers <= 1 || len(paths) < minParallelPaths {
+		err := t.process(sink, paths, payloads, 0)
+		if err != nil {
+			return nil, err
+		}
+		return target, nil
+	}
+
+	// Otherwise, we process groups sequentially until we have enough of them
+	// to keep all workers busy. As groups are processed breadth-first, each
+	// remaining group is then the root of a distinct subtrie on the target
+	// trie, which can be built without synchronization.
+	err := t.process(sink, paths, payloads, workers)
+	if err != nil {
+		return nil, err
+	}
+	sinks := make([]*deque.Deque, workers)
+	for i := range sinks {
+		sinks[i] = deque.New()
+	}
+	for i := 0; sink.Len() != 0; i++ {
+		sinks[i%workers].PushFront(sink.PopBack())
+	}
+	var eg errgroup.Group
+	for _, sink := range sinks {
+		sink := sink
+		eg.Go(func() error {
+			return t.process(sink, paths, payloads, 0)
+		})
+	}
+	err = eg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// process consumes the groups of the given queue, building the target trie for
+// each of them, until the queue is empty. If a limit is given, it stops as soon
+// as the queue holds at least that many groups.
+func (t *Trie) process(sink *deque.Deque, paths []ledger.Path, payloads []ledger.Payload, limit int) error {
+
 	// We keep processing groups that are pushed onto the queue until there are
 	// no groups left to be processed.
-	for sink.Len() != 0 {
+	for sink.Len() != 0 && (limit == 0 || sink.Len() < limit) {
 
 		// We take the next group from the queue.
 		group := sink.PopBack().(*Group)
@@ -171,13 +233,13 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 
 			// We should only have a single path left.
 			if group.end-group.start > 1 {
-				return nil, fmt.Errorf("duplicate path (%x)", path[:])
+				return fmt.Errorf("duplicate path (%x)", group.path[:])
 			}
 
 			leaf, ok := (*group.target.node).(*Leaf)
 			if !ok {
 				// Create a new leaf.
-				leaf = t.leaves.Get().(*Leaf)
+				leaf = t.newLeaf()
 				leaf.clean = false
 				leaf.path = group.path
 				leaf.payload = payloads[group.start].DeepCopy()
@@ -209,7 +271,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 			case *Leaf:
 
 				// Clone source leaf.
-				leaf := t.leaves.Get().(*Leaf)
+				leaf := t.newLeaf()
 				leaf.clean = false
 				leaf.path = n.path
 				leaf.payload = n.payload
@@ -223,7 +285,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 			case *Branch:
 
 				// Clone source branch.
-				branch := t.branches.Get().(*Branch)
+				branch := t.newBranch()
 				branch.clean = false
 				if pivot == group.start {
 					branch.left = n.left
@@ -244,7 +306,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 				if (extBit == 0 && pivot == group.end) ||
 					(extBit == 1 && pivot == group.start) {
 
-					ext := t.extensions.Get().(*Extension)
+					ext := t.newExtension()
 					ext.clean = false
 					ext.path = n.path
 					ext.count = n.count - group.source.count
@@ -254,7 +316,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 				}
 
 				// Otherwise, we want to introduce a branch at the current bit.
-				branch := t.branches.Get().(*Branch)
+				branch := t.newBranch()
 				branch.clean = false
 
 				// If we are not following both directions, point the unused side
@@ -267,7 +329,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 					leaf, ok := child.(*Leaf)
 					if ok {
 						// Clone source child leaf.
-						replace := t.leaves.Get().(*Leaf)
+						replace := t.newLeaf()
 						replace.clean = false
 						replace.path = leaf.path
 						replace.payload = leaf.payload
@@ -279,7 +341,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 					// part of it we want to keep and set the previous child as
 					// its child.
 					if n.count >= group.source.count+1 {
-						ext := t.extensions.Get().(*Extension)
+						ext := t.newExtension()
 						ext.clean = false
 						ext.path = n.path
 						ext.child = child
@@ -308,13 +370,13 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 
 			if pivot != group.start && pivot != group.end {
 				// We are going both directions, so we create a branch.
-				branch := t.branches.Get().(*Branch)
+				branch := t.newBranch()
 				branch.clean = false
 				*group.target.node = branch
 			} else {
 				// All elements of this group follow the same direction,
 				// so we need to create an extension.
-				ext := t.extensions.Get().(*Extension)
+				ext := t.newExtension()
 				ext.clean = false
 				ext.count = maxDepth - group.depth
 				ext.path = group.path
@@ -347,7 +409,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 						leaf, ok := child.(*Leaf)
 						if ok {
 							// Clone source child leaf.
-							replace := t.leaves.Get().(*Leaf)
+							replace := t.newLeaf()
 							replace.clean = false
 							replace.path = leaf.path
 							replace.payload = leaf.payload
@@ -359,7 +421,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 						// part of it we want to keep and set the previous child as
 						// its child.
 						if group.target.count != n.count {
-							ext := t.extensions.Get().(*Extension)
+							ext := t.newExtension()
 							ext.clean = false
 							ext.path = n.path
 							// We need to subtract the source count we already went through
@@ -503,7 +565,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 
 				path := paths[pivot]
 
-				split := t.groups.Get().(*Group)
+				split := t.newGroup()
 				split.path = &path
 				split.target.node = &n.right
 				split.start = pivot
@@ -559,7 +621,7 @@ func (t *Trie) Mutate(paths []ledger.Path, payloads []ledger.Payload) (*Trie, er
 		}
 	}
 
-	return target, nil
+	return nil
 }
 
 // UnsafeRead read payloads for the given paths.
@@ -715,3 +777,35 @@ func (t *Trie) Paths() []ledger.Path {
 
 	return paths
 }
+
+// newGroup returns a new group, taken from the node pool if there is one.
+func (t *Trie) newGroup() *Group {
+	if t.pool != nil {
+		return t.pool.group()
+	}
+	return new(Group)
+}
+
+// newExtension returns a new extension, taken from the node pool if there is one.
+func (t *Trie) newExtension() *Extension {
+	if t.pool != nil {
+		return t.pool.extension()
+	}
+	return new(Extension)
+}
+
+// newBranch returns a new branch, taken from the node pool if there is one.
+func (t *Trie) newBranch() *Branch {
+	if t.pool != nil {
+		return t.pool.branch()
+	}
+	return new(Branch)
+}
+
+// newLeaf returns a new leaf, taken from the node pool if there is one.
+func (t *Trie) newLeaf() *Leaf {
+	if t.pool != nil {
+		return t.pool.leaf()
+	}
+	return new(Leaf)
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -361,6 +362,140 @@ func TestTrie_InsertDoesNotMutateBaseTrie(t *testing.T) {
 	}
 }
 
+func TestTrie_MutateWithWorkers(t *testing.T) {
+
+	// We build a base trie, so that the mutations update existing registers
+	// and insert new ones alongside them. The slices are copied, because
+	// mutations sort them in place.
+	paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 12288)
+	basePaths := append([]ledger.Path{}, paths[:8192]...)
+	basePayloads := append([]ledger.Payload{}, payloads[:8192]...)
+	updates := append(append([]ledger.Path{}, paths[8192:]...), paths[:4096]...)
+	values := append(append([]ledger.Payload{}, payloads[8192:]...), payloads[4096:8192]...)
+
+	base, err := trie.NewEmptyTrie().Mutate(basePaths, basePayloads)
+	require.NoError(t, err)
+	refTr, err := reference.NewTrieWithUpdatedRegisters(reference.NewEmptyMTrie(), basePaths, basePayloads)
+	require.NoError(t, err)
+
+	refTr, err = reference.NewTrieWithUpdatedRegisters(refTr, updates, values)
+	require.NoError(t, err)
+	want := refTr.RootHash()
+
+	for _, workers := range []int{1, 2, 3, 8, 64} {
+		workers := workers
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+
+			before := base.RootHash()
+			tr, err := base.MutateWithWorkers(updates, values, workers)
+			require.NoError(t, err)
+
+			after := base.RootHash()
+			require.Equal(t, before, after, "unexpected mutation of base trie")
+
+			got := tr.RootHash()
+			assert.Equal(t, want[:], got[:])
+			assert.Equal(t, refTr.UnsafeRead(updates), tr.UnsafeRead(updates))
+		})
+	}
+}
+
+func TestTrie_Clone(t *testing.T) {
+
+	paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 8192)
+	basePaths := append([]ledger.Path{}, paths[:4096]...)
+	basePayloads := append([]ledger.Payload{}, payloads[:4096]...)
+
+	original, err := trie.NewEmptyTrie().Mutate(basePaths, basePayloads)
+	require.NoError(t, err)
+	wantRoot := original.RootHash()
+	wantValues := original.UnsafeRead(paths[:4096])
+
+	t.Run("clone has the same state", func(t *testing.T) {
+		clone := original.Clone()
+
+		gotRoot := clone.RootHash()
+		assert.Equal(t, wantRoot, gotRoot)
+		assert.Equal(t, wantValues, clone.UnsafeRead(paths[:4096]))
+	})
+
+	t.Run("updating clone leaves original unchanged", func(t *testing.T) {
+		updatePaths := append([]ledger.Path{}, paths[:2048]...)
+		updatePayloads := append([]ledger.Payload{}, payloads[2048:4096]...)
+
+		clone, err := original.Clone().Mutate(updatePaths, updatePayloads)
+		require.NoError(t, err)
+
+		cloneRoot := clone.RootHash()
+		assert.NotEqual(t, wantRoot, cloneRoot)
+		gotRoot := original.RootHash()
+		assert.Equal(t, wantRoot, gotRoot)
+		assert.Equal(t, wantValues, original.UnsafeRead(paths[:4096]))
+	})
+
+	t.Run("inserting into clone leaves original unchanged", func(t *testing.T) {
+		insertPaths := append([]ledger.Path{}, paths[4096:]...)
+		insertPayloads := append([]ledger.Payload{}, payloads[4096:]...)
+
+		clone, err := original.Clone().MutateWithWorkers(insertPaths, insertPayloads, 4)
+		require.NoError(t, err)
+
+		assert.Len(t, clone.Leaves(), 8192)
+		assert.Len(t, original.Leaves(), 4096)
+		gotRoot := original.RootHash()
+		assert.Equal(t, wantRoot, gotRoot)
+		assert.Equal(t, wantValues, original.UnsafeRead(paths[:4096]))
+	})
+}
+
+func TestTrie_WithNodePool(t *testing.T) {
+
+	// We sample a set of registers once, and then repeatedly insert, update and
+	// delete random subsets of them on a trie with and without node pool. After
+	// each round, both tries should have the same root hash, and the previous
+	// versions of the pooled trie should be left unchanged.
+	paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 16384)
+	random := rand.New(rand.NewSource(1))
+
+	tr := trie.NewEmptyTrie()
+	pooled := trie.NewEmptyTrie(trie.WithNodePool())
+	for round := 0; round < 64; round++ {
+
+		count := 1 + random.Intn(4096)
+		roundPaths := make([]ledger.Path, 0, count)
+		roundPayloads := make([]ledger.Payload, 0, count)
+		for _, index := range random.Perm(len(paths))[:count] {
+			payload := payloads[random.Intn(len(payloads))]
+			payload.Key = payloads[index].Key
+			if random.Intn(4) == 0 {
+				payload.Value = nil
+			}
+			roundPaths = append(roundPaths, paths[index])
+			roundPayloads = append(roundPayloads, payload)
+		}
+
+		before := pooled.RootHash()
+		var err error
+		tr, err = tr.Mutate(
+			append([]ledger.Path{}, roundPaths...),
+			append([]ledger.Payload{}, roundPayloads...),
+		)
+		require.NoError(t, err)
+		next, err := pooled.MutateWithWorkers(roundPaths, roundPayloads, 1+round%4)
+		require.NoError(t, err)
+
+		after := pooled.RootHash()
+		require.Equal(t, before, after, "unexpected mutation of previous trie in round %d", round)
+
+		want := tr.RootHash()
+		got := next.RootHash()
+		require.Equal(t, want[:], got[:], "root hash mismatch in round %d", round)
+		require.Equal(t, tr.UnsafeRead(roundPaths), next.UnsafeRead(roundPaths))
+
+		pooled = next
+	}
+}
+
 func BenchmarkTrie_InsertMany(b *testing.B) {
 
 	paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 1000)
@@ -450,6 +585,81 @@ func BenchmarkTrie_InsertFullTrie(b *testing.B) {
 	}
 }
 
+func BenchmarkTrie_MutateWithWorkers(b *testing.B) {
+
+	for i := 1024; i <= 32768; i *= 2 {
+		for _, workers := range []int{1, 2, 4, 8, 16} {
+
+			b.Run(fmt.Sprintf("insert %d elements into full trie with %d workers", i, workers), func(b *testing.B) {
+				tr := trie.NewEmptyTrie()
+				paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 32768)
+				tr, _ = tr.Mutate(paths, payloads)
+				_ = tr.RootHash()
+				b.ResetTimer()
+				for j := 0; j < b.N; j++ {
+					b.StopTimer()
+					paths, payloads = helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), i)
+					b.StartTimer()
+					tr, _ = tr.MutateWithWorkers(paths, payloads, workers)
+					_ = tr.RootHash()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkTrie_Clone(b *testing.B) {
+
+	paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), 32768)
+	base, _ := trie.NewEmptyTrie().Mutate(paths, payloads)
+	_ = base.RootHash()
+
+	// Cloning a trie and mutating a few registers on the clone should only
+	// allocate the nodes along the mutated paths, while rebuilding the trie
+	// allocates all of its nodes again.
+	b.Run("clone and update 16 elements", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			clone, _ := base.Clone().Mutate(paths[:16], payloads[:16])
+			_ = clone.RootHash()
+		}
+	})
+
+	b.Run("rebuild and update 16 elements", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rebuilt, _ := trie.NewEmptyTrie().Mutate(paths, payloads)
+			rebuilt, _ = rebuilt.Mutate(paths[:16], payloads[:16])
+			_ = rebuilt.RootHash()
+		}
+	})
+}
+
+func BenchmarkTrie_WithNodePool(b *testing.B) {
+
+	for i := 1024; i <= 32768; i *= 4 {
+		paths, payloads := helpers.SampleRandomRegisterWrites(helpers.NewGenerator(), i)
+
+		b.Run(fmt.Sprintf("insert %d elements into new trie (no pool)", i), func(b *testing.B) {
+			b.ReportAllocs()
+			for j := 0; j < b.N; j++ {
+				tr := trie.NewEmptyTrie()
+				tr, _ = tr.Mutate(paths, payloads)
+				_ = tr.RootHash()
+			}
+		})
+
+		b.Run(fmt.Sprintf("insert %d elements into new trie (node pool)", i), func(b *testing.B) {
+			b.ReportAllocs()
+			for j := 0; j < b.N; j++ {
+				tr := trie.NewEmptyTrie(trie.WithNodePool())
+				tr, _ = tr.Mutate(paths, payloads)
+				_ = tr.RootHash()
+			}
+		})
+	}
+}
+
 func BenchmarkTrie_InsertNeighbors(b *testing.B) {
 
 	paths := []ledger.Path{
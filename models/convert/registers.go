@@ -0,0 +1,93 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package convert
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/flow-go/engine/execution/state"
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/ledger/common/pathfinder"
+	"github.com/onflow/flow-go/ledger/complete"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// RegisterIDToKey converts a register ID into the ledger key that identifies
+// the register in the execution state.
+func RegisterIDToKey(regID flow.RegisterID) ledger.Key {
+	return state.RegisterIDToKey(regID)
+}
+
+// KeyToRegisterID converts a ledger key of the execution state back into the
+// ID of the register it identifies.
+func KeyToRegisterID(key ledger.Key) (flow.RegisterID, error) {
+
+	if len(key.KeyParts) != 3 {
+		return flow.RegisterID{}, fmt.Errorf("invalid number of key parts (%d)", len(key.KeyParts))
+	}
+
+	types := []uint16{state.KeyPartOwner, state.KeyPartController, state.KeyPartKey}
+	for index, part := range key.KeyParts {
+		if part.Type != types[index] {
+			return flow.RegisterID{}, fmt.Errorf("invalid type for key part %d (%d)", index, part.Type)
+		}
+	}
+
+	regID := flow.NewRegisterID(
+		string(key.KeyParts[0].Value),
+		string(key.KeyParts[1].Value),
+		string(key.KeyParts[2].Value),
+	)
+
+	return regID, nil
+}
+
+// RegisterIDToPath converts a register ID into the ledger path at which the
+// register is stored in the execution state trie.
+func RegisterIDToPath(regID flow.RegisterID) (ledger.Path, error) {
+
+	path, err := pathfinder.KeyToPath(RegisterIDToKey(regID), complete.DefaultPathFinderVersion)
+	if err != nil {
+		return ledger.DummyPath, fmt.Errorf("could not convert key to path: %w", err)
+	}
+
+	return path, nil
+}
+
+// HexToOwner converts a hex-encoded account address into the owner of its
+// registers, which is the raw address padded with zeroes at the front. An
+// empty string results in the empty owner of global registers.
+func HexToOwner(address string) (string, error) {
+
+	address = strings.TrimPrefix(address, "0x")
+	if address == "" {
+		return "", nil
+	}
+	if len(address)%2 != 0 {
+		address = "0" + address
+	}
+
+	b, err := hex.DecodeString(address)
+	if err != nil {
+		return "", fmt.Errorf("could not decode address: %w", err)
+	}
+	if len(b) > flow.AddressLength {
+		return "", fmt.Errorf("invalid address length (%d > %d)", len(b), flow.AddressLength)
+	}
+
+	return string(flow.BytesToAddress(b).Bytes()), nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package convert_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/ledger/common/pathfinder"
+	"github.com/onflow/flow-go/ledger/complete"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/convert"
+)
+
+func TestRegisterIDToKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		regID flow.RegisterID
+	}{
+		{
+			name:  "account register",
+			regID: flow.NewRegisterID(string(flow.HexToAddress("e467b9dd11fa00df").Bytes()), "", "public_key_0"),
+		},
+		{
+			name:  "contract register",
+			regID: flow.NewRegisterID(string(flow.HexToAddress("1654653399040a61").Bytes()), string(flow.HexToAddress("1654653399040a61").Bytes()), "code.FlowToken"),
+		},
+		{
+			name:  "global register with empty owner",
+			regID: flow.NewRegisterID("", "", "uuid"),
+		},
+		{
+			name:  "empty register",
+			regID: flow.NewRegisterID("", "", ""),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			key := convert.RegisterIDToKey(test.regID)
+
+			require.Len(t, key.KeyParts, 3)
+			assert.Equal(t, []byte(test.regID.Owner), key.KeyParts[0].Value)
+			assert.Equal(t, []byte(test.regID.Controller), key.KeyParts[1].Value)
+			assert.Equal(t, []byte(test.regID.Key), key.KeyParts[2].Value)
+
+			got, err := convert.KeyToRegisterID(key)
+
+			require.NoError(t, err)
+			assert.Equal(t, test.regID, got)
+		})
+	}
+}
+
+func TestKeyToRegisterID(t *testing.T) {
+	t.Run("handles invalid number of key parts", func(t *testing.T) {
+		t.Parallel()
+
+		key := ledger.NewKey([]ledger.KeyPart{
+			ledger.NewKeyPart(0, []byte(`owner`)),
+			ledger.NewKeyPart(2, []byte(`key`)),
+		})
+
+		_, err := convert.KeyToRegisterID(key)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid key part types", func(t *testing.T) {
+		t.Parallel()
+
+		key := ledger.NewKey([]ledger.KeyPart{
+			ledger.NewKeyPart(0, []byte(`owner`)),
+			ledger.NewKeyPart(2, []byte(`controller`)),
+			ledger.NewKeyPart(1, []byte(`key`)),
+		})
+
+		_, err := convert.KeyToRegisterID(key)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRegisterIDToPath(t *testing.T) {
+	regIDs := []flow.RegisterID{
+		flow.NewRegisterID(string(flow.HexToAddress("e467b9dd11fa00df").Bytes()), "", "public_key_0"),
+		flow.NewRegisterID("", "", "uuid"),
+	}
+
+	for _, regID := range regIDs {
+		want, err := pathfinder.KeyToPath(convert.RegisterIDToKey(regID), complete.DefaultPathFinderVersion)
+		require.NoError(t, err)
+
+		got, err := convert.RegisterIDToPath(regID)
+
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestHexToOwner(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+
+		wantOwner string
+		checkErr  require.ErrorAssertionFunc
+	}{
+		{
+			name:      "full address",
+			address:   "e467b9dd11fa00df",
+			wantOwner: string(flow.HexToAddress("e467b9dd11fa00df").Bytes()),
+			checkErr:  require.NoError,
+		},
+		{
+			name:      "full address with prefix",
+			address:   "0xe467b9dd11fa00df",
+			wantOwner: string(flow.HexToAddress("e467b9dd11fa00df").Bytes()),
+			checkErr:  require.NoError,
+		},
+		{
+			name:      "short address is padded",
+			address:   "0x1",
+			wantOwner: string([]byte{0, 0, 0, 0, 0, 0, 0, 1}),
+			checkErr:  require.NoError,
+		},
+		{
+			name:      "empty address for global registers",
+			address:   "",
+			wantOwner: "",
+			checkErr:  require.NoError,
+		},
+		{
+			name:     "invalid hex",
+			address:  "0xzz",
+			checkErr: require.Error,
+		},
+		{
+			name:     "address too long",
+			address:  "0x01e467b9dd11fa00df",
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := convert.HexToOwner(test.address)
+
+			test.checkErr(t, err)
+			if err == nil {
+				assert.Equal(t, test.wantOwner, got)
+			}
+		})
+	}
+}
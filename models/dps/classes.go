@@ -0,0 +1,34 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+// Classes is a bitmask of the classes of data that were indexed for a height.
+type Classes uint8
+
+// Classes of indexed data.
+const (
+	ClassHeaders Classes = 1 << iota
+	ClassRegisters
+	ClassEvents
+	ClassTransactions
+	ClassResults
+
+	ClassesAll = ClassHeaders | ClassRegisters | ClassEvents | ClassTransactions | ClassResults
+)
+
+// Has returns whether all of the given classes are part of the bitmask.
+func (c Classes) Has(classes Classes) bool {
+	return c&classes == classes
+}
@@ -0,0 +1,34 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+func TestClasses_Has(t *testing.T) {
+	classes := dps.ClassHeaders | dps.ClassEvents
+
+	assert.True(t, classes.Has(dps.ClassHeaders))
+	assert.True(t, classes.Has(dps.ClassEvents))
+	assert.True(t, classes.Has(dps.ClassHeaders|dps.ClassEvents))
+	assert.False(t, classes.Has(dps.ClassRegisters))
+	assert.False(t, classes.Has(dps.ClassHeaders|dps.ClassRegisters))
+	assert.True(t, dps.ClassesAll.Has(classes))
+}
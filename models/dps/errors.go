@@ -16,10 +16,25 @@ package dps
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
 )
 
 // Sentinel errors.
 var (
-	ErrFinished    = errors.New("finished")
-	ErrUnavailable = errors.New("unavailable")
+	ErrFinished     = errors.New("finished")
+	ErrUnavailable  = errors.New("unavailable")
+	ErrIncomplete   = errors.New("incomplete")
+	ErrNotIndexed   = errors.New("not indexed")
+	ErrIncompatible = errors.New("incompatible")
+)
+
+// Sentinel errors for data missing from the index. They all wrap the
+// `badger.ErrKeyNotFound` error, so that checks for missing keys keep working.
+var (
+	ErrHeightNotIndexed = fmt.Errorf("height not indexed: %w", badger.ErrKeyNotFound)
+	ErrRegisterNotFound = fmt.Errorf("register not found: %w", badger.ErrKeyNotFound)
+	ErrBlockNotFound    = fmt.Errorf("block not found: %w", badger.ErrKeyNotFound)
+	ErrEventNotFound    = fmt.Errorf("event not found: %w", badger.ErrKeyNotFound)
 )
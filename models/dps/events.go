@@ -0,0 +1,46 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// EventLocation returns the ID of the transaction that emitted the event at the
+// given index within the events of a block. The index is the position of the
+// event once the events are ordered by the index of their transaction within
+// the block, and then by their index within the transaction.
+func EventLocation(events []flow.Event, eventIndex uint32) (flow.Identifier, error) {
+
+	if uint64(eventIndex) >= uint64(len(events)) {
+		return flow.ZeroID, fmt.Errorf("invalid event index (index: %d, events: %d): %w", eventIndex, len(events), ErrEventNotFound)
+	}
+
+	// Events are stored by type, so we sort a copy of them to avoid modifying
+	// a slice that might be shared with a cache.
+	ordered := make([]flow.Event, len(events))
+	copy(ordered, events)
+	sort.Slice(ordered, func(i int, j int) bool {
+		if ordered[i].TransactionIndex != ordered[j].TransactionIndex {
+			return ordered[i].TransactionIndex < ordered[j].TransactionIndex
+		}
+		return ordered[i].EventIndex < ordered[j].EventIndex
+	})
+
+	return ordered[eventIndex].TransactionID, nil
+}
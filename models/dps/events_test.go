@@ -0,0 +1,67 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestEventLocation(t *testing.T) {
+	txIDs := mocks.GenericTransactionIDs(2)
+
+	// The events are grouped by type, rather than in the order in which they
+	// were emitted, like when they are retrieved from the index.
+	events := []flow.Event{
+		{TransactionID: txIDs[1], TransactionIndex: 1, EventIndex: 0},
+		{TransactionID: txIDs[0], TransactionIndex: 0, EventIndex: 1},
+		{TransactionID: txIDs[0], TransactionIndex: 0, EventIndex: 0},
+		{TransactionID: txIDs[1], TransactionIndex: 1, EventIndex: 1},
+		{TransactionID: txIDs[1], TransactionIndex: 1, EventIndex: 2},
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		want := []flow.Identifier{txIDs[0], txIDs[0], txIDs[1], txIDs[1], txIDs[1]}
+		for eventIndex := range events {
+			got, err := dps.EventLocation(events, uint32(eventIndex))
+
+			require.NoError(t, err)
+			assert.Equal(t, want[eventIndex], got)
+		}
+	})
+
+	t.Run("does not reorder given events", func(t *testing.T) {
+		given := make([]flow.Event, len(events))
+		copy(given, events)
+
+		_, err := dps.EventLocation(given, 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, events, given)
+	})
+
+	t.Run("handles event index out of range", func(t *testing.T) {
+		_, err := dps.EventLocation(events, uint32(len(events)))
+
+		assert.ErrorIs(t, err, dps.ErrEventNotFound)
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Paths of the files used to detect the memory available to the process,
+// relative to the root of the file system.
+const (
+	memInfo       = "proc/meminfo"
+	cgroupV2Limit = "sys/fs/cgroup/memory.max"
+	cgroupV1Limit = "sys/fs/cgroup/memory/memory.limit_in_bytes"
+)
+
+// AvailableMemory returns the amount of memory in bytes that is available to
+// the process. It is the total memory of the host, unless the process runs in
+// a cgroup (v1 or v2) with a lower memory limit, which is usually the case for
+// containers.
+func AvailableMemory() (uint64, error) {
+	return availableMemory("/")
+}
+
+// CacheSize returns the size in bytes of a cache that uses the given fraction
+// of the given amount of memory.
+func CacheSize(memory uint64, fraction float64) int64 {
+	return int64(float64(memory) * fraction)
+}
+
+func availableMemory(root string) (uint64, error) {
+
+	memory, err := hostMemory(filepath.Join(root, memInfo))
+	if err != nil {
+		return 0, fmt.Errorf("could not read host memory: %w", err)
+	}
+
+	for _, path := range []string{cgroupV2Limit, cgroupV1Limit} {
+		limit, err := cgroupLimit(filepath.Join(root, path))
+		if err != nil {
+			return 0, fmt.Errorf("could not read cgroup memory limit: %w", err)
+		}
+		if limit != 0 && limit < memory {
+			memory = limit
+		}
+	}
+
+	return memory, nil
+}
+
+// hostMemory returns the total memory of the host, as reported in kilobytes
+// by the given meminfo file.
+func hostMemory(path string) (uint64, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("could not open meminfo file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		total, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse total memory: %w", err)
+		}
+		return total << 10, nil
+	}
+	err = scanner.Err()
+	if err != nil {
+		return 0, fmt.Errorf("could not read meminfo file: %w", err)
+	}
+
+	return 0, fmt.Errorf("could not find total memory in meminfo file")
+}
+
+// cgroupLimit returns the memory limit in the given cgroup file. It returns
+// zero if the file does not exist or if there is no limit.
+func cgroupLimit(path string) (uint64, error) {
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not read cgroup file: %w", err)
+	}
+
+	// Cgroups v2 use `max` when there is no limit, while cgroups v1 use a
+	// very large number, which is handled by comparing with the host memory.
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse cgroup memory limit: %w", err)
+	}
+
+	return limit, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailableMemory(t *testing.T) {
+	const meminfo = "MemTotal:       16384000 kB\nMemFree:         1024000 kB\n"
+	const host = 16384000 << 10
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		want     uint64
+		checkErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "host memory without cgroup",
+			files:    map[string]string{memInfo: meminfo},
+			want:     host,
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "cgroup v2 limit",
+			files:    map[string]string{memInfo: meminfo, cgroupV2Limit: "4294967296\n"},
+			want:     4 << 30,
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "cgroup v2 without limit",
+			files:    map[string]string{memInfo: meminfo, cgroupV2Limit: "max\n"},
+			want:     host,
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "cgroup v1 limit",
+			files:    map[string]string{memInfo: meminfo, cgroupV1Limit: "2147483648\n"},
+			want:     2 << 30,
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "cgroup v1 without limit",
+			files:    map[string]string{memInfo: meminfo, cgroupV1Limit: "9223372036854771712\n"},
+			want:     host,
+			checkErr: assert.NoError,
+		},
+		{
+			name:     "missing meminfo",
+			files:    map[string]string{cgroupV2Limit: "4294967296\n"},
+			checkErr: assert.Error,
+		},
+		{
+			name:     "invalid cgroup limit",
+			files:    map[string]string{memInfo: meminfo, cgroupV2Limit: "invalid\n"},
+			checkErr: assert.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(root, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+			}
+
+			got, err := availableMemory(root)
+
+			test.checkErr(t, err)
+			if err == nil {
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	assert.Equal(t, int64(1<<30), CacheSize(4<<30, 0.25))
+	assert.Equal(t, int64(4<<30), CacheSize(4<<30, 1))
+	assert.Equal(t, int64(0), CacheSize(4<<30, 0))
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// SchemaVersion is the version of the index database layout that this binary
+// reads and writes. It has to be incremented whenever the layout changes in a
+// way that makes existing indexes unreadable, along with the addition of a
+// migration for existing indexes to the registry of the storage library.
+const SchemaVersion = 1
+
+// Metadata is the record that describes an index database. It is written once
+// when the index is bootstrapped, and checked by binaries that open the index.
+type Metadata struct {
+	SchemaVersion uint32
+	Dictionary    string
+	ChainID       flow.ChainID
+	Created       time.Time
+}
+
+// Compatible returns an error wrapping `ErrIncompatible` if an index with this
+// metadata can not be used by the running binary.
+func (m Metadata) Compatible() error {
+	if m.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("index schema version %d is newer than supported version %d, please upgrade to a newer release: %w", m.SchemaVersion, SchemaVersion, ErrIncompatible)
+	}
+	if m.SchemaVersion < SchemaVersion {
+		return fmt.Errorf("index schema version %d is older than supported version %d, please migrate or rebuild the index: %w", m.SchemaVersion, SchemaVersion, ErrIncompatible)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+func TestMetadata_Compatible(t *testing.T) {
+	t.Run("same version", func(t *testing.T) {
+		meta := dps.Metadata{SchemaVersion: dps.SchemaVersion}
+
+		assert.NoError(t, meta.Compatible())
+	})
+
+	t.Run("newer version", func(t *testing.T) {
+		meta := dps.Metadata{SchemaVersion: dps.SchemaVersion + 1}
+
+		err := meta.Compatible()
+		assert.ErrorIs(t, err, dps.ErrIncompatible)
+		assert.Contains(t, err.Error(), "upgrade")
+	})
+
+	t.Run("older version", func(t *testing.T) {
+		meta := dps.Metadata{SchemaVersion: dps.SchemaVersion - 1}
+
+		err := meta.Compatible()
+		assert.ErrorIs(t, err, dps.ErrIncompatible)
+		assert.Contains(t, err.Error(), "migrate")
+	})
+}
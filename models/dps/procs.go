@@ -0,0 +1,31 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"runtime"
+)
+
+// SetMaxProcs sets the maximum number of CPUs that can execute Go code
+// simultaneously to the given value and returns the resulting value. If the
+// given value is zero or negative, the current value is left unchanged, which
+// means the Go runtime's default or the `GOMAXPROCS` environment variable is
+// honored.
+func SetMaxProcs(procs int) int {
+	if procs > 0 {
+		_ = runtime.GOMAXPROCS(procs)
+	}
+	return runtime.GOMAXPROCS(0)
+}
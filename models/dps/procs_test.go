@@ -0,0 +1,52 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+func TestSetMaxProcs(t *testing.T) {
+	original := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(original)
+
+	t.Run("zero leaves default", func(t *testing.T) {
+		got := dps.SetMaxProcs(0)
+
+		assert.Equal(t, original, got)
+		assert.Equal(t, original, runtime.GOMAXPROCS(0))
+	})
+
+	t.Run("negative leaves default", func(t *testing.T) {
+		got := dps.SetMaxProcs(-1)
+
+		assert.Equal(t, original, got)
+		assert.Equal(t, original, runtime.GOMAXPROCS(0))
+	})
+
+	t.Run("positive overrides default", func(t *testing.T) {
+		defer runtime.GOMAXPROCS(original)
+
+		got := dps.SetMaxProcs(original + 3)
+
+		assert.Equal(t, original+3, got)
+		assert.Equal(t, original+3, runtime.GOMAXPROCS(0))
+	})
+}
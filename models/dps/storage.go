@@ -31,16 +31,20 @@ type Library interface {
 // ReadLibrary represents something that produces operations to read from
 // a DPS index database.
 type ReadLibrary interface {
+	RetrieveMetadata(meta *Metadata) func(*badger.Txn) error
 	RetrieveFirst(height *uint64) func(*badger.Txn) error
 	RetrieveLast(height *uint64) func(*badger.Txn) error
+	RetrieveSealed(height *uint64) func(*badger.Txn) error
 
 	LookupHeightForBlock(blockID flow.Identifier, height *uint64) func(*badger.Txn) error
 	LookupHeightForTransaction(txID flow.Identifier, height *uint64) func(*badger.Txn) error
 
 	RetrieveCommit(height uint64, commit *flow.StateCommitment) func(*badger.Txn) error
 	RetrieveHeader(height uint64, header *flow.Header) func(*badger.Txn) error
+	RetrieveLowestHeader(height *uint64) func(*badger.Txn) error
 	RetrieveEvents(height uint64, types []flow.EventType, events *[]flow.Event) func(*badger.Txn) error
 	RetrievePayload(height uint64, path ledger.Path, payload *ledger.Payload) func(*badger.Txn) error
+	IteratePayloads(path ledger.Path, from uint64, process func(height uint64, payload *ledger.Payload) error) func(*badger.Txn) error
 
 	LookupTransactionsForHeight(height uint64, txIDs *[]flow.Identifier) func(*badger.Txn) error
 	LookupTransactionsForCollection(collID flow.Identifier, txIDs *[]flow.Identifier) func(*badger.Txn) error
@@ -53,14 +57,20 @@ type ReadLibrary interface {
 	RetrieveResult(txID flow.Identifier, result *flow.TransactionResult) func(*badger.Txn) error
 	RetrieveSeal(sealID flow.Identifier, seal *flow.Seal) func(*badger.Txn) error
 
+	RetrieveFailure(height uint64, reason *string) func(*badger.Txn) error
+	IterateFailures(process func(height uint64, reason string) error) func(*badger.Txn) error
+	RetrieveClasses(height uint64, classes *Classes) func(*badger.Txn) error
+
 	IterateLedger(exclude func(height uint64) bool, process func(path ledger.Path, payload *ledger.Payload) error) func(*badger.Txn) error
 }
 
 // WriteLibrary represents something that produces operations to write on
 // a DPS index database.
 type WriteLibrary interface {
+	SaveMetadata(meta Metadata) func(*badger.Txn) error
 	SaveFirst(height uint64) func(*badger.Txn) error
 	SaveLast(height uint64) func(*badger.Txn) error
+	SaveSealed(height uint64) func(*badger.Txn) error
 
 	IndexHeightForBlock(blockID flow.Identifier, height uint64) func(*badger.Txn) error
 	IndexHeightForTransaction(txID flow.Identifier, height uint64) func(*badger.Txn) error
@@ -80,4 +90,9 @@ type WriteLibrary interface {
 	SaveTransaction(transaction *flow.TransactionBody) func(*badger.Txn) error
 	SaveResult(results *flow.TransactionResult) func(*badger.Txn) error
 	SaveSeal(seal *flow.Seal) func(*badger.Txn) error
+
+	SaveFailure(height uint64, reason string) func(*badger.Txn) error
+	SaveSkipped(height uint64, path ledger.Path, reason string) func(*badger.Txn) error
+	DeleteFailure(height uint64) func(*badger.Txn) error
+	SaveClasses(height uint64, classes Classes) func(*badger.Txn) error
 }
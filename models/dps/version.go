@@ -0,0 +1,52 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps
+
+import (
+	"fmt"
+)
+
+// The build information is set at link time, for example with:
+//
+//	go build -ldflags "-X github.com/optakt/flow-dps/models/dps.Version=v1.0.0"
+//
+// It is left at its default values for development builds.
+var (
+	Version = "undefined"
+	Commit  = "undefined"
+	Date    = "undefined"
+)
+
+// BuildInfo contains the version information of a DPS binary.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// Build returns the build information of the running binary.
+func Build() BuildInfo {
+	b := BuildInfo{
+		Version: Version,
+		Commit:  Commit,
+		Date:    Date,
+	}
+	return b
+}
+
+// String returns a human-readable representation of the build information.
+func (b BuildInfo) String() string {
+	return fmt.Sprintf("version %s (commit %s, built %s)", b.Version, b.Commit, b.Date)
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package dps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+func TestBuild(t *testing.T) {
+	version, commit, date := dps.Version, dps.Commit, dps.Date
+	defer func() {
+		dps.Version, dps.Commit, dps.Date = version, commit, date
+	}()
+
+	t.Run("default values", func(t *testing.T) {
+		got := dps.Build()
+
+		assert.Equal(t, "undefined", got.Version)
+		assert.Equal(t, "undefined", got.Commit)
+		assert.Equal(t, "undefined", got.Date)
+	})
+
+	t.Run("linked values", func(t *testing.T) {
+		dps.Version = "v1.2.3"
+		dps.Commit = "ebfb763"
+		dps.Date = "2021-10-01T12:00:00Z"
+
+		got := dps.Build()
+
+		assert.Equal(t, "v1.2.3", got.Version)
+		assert.Equal(t, "ebfb763", got.Commit)
+		assert.Equal(t, "2021-10-01T12:00:00Z", got.Date)
+		assert.Equal(t, "version v1.2.3 (commit ebfb763, built 2021-10-01T12:00:00Z)", got.String())
+	})
+}
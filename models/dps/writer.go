@@ -23,6 +23,7 @@ import (
 type Writer interface {
 	First(height uint64) error
 	Last(height uint64) error
+	Sealed(height uint64) error
 
 	Height(blockID flow.Identifier, height uint64) error
 
@@ -36,4 +37,8 @@ type Writer interface {
 	Transactions(height uint64, transactions []*flow.TransactionBody) error
 	Results(results []*flow.TransactionResult) error
 	Seals(height uint64, seals []*flow.Seal) error
+
+	Failure(height uint64, reason string) error
+	Skipped(height uint64, path ledger.Path, reason string) error
+	Classes(height uint64, classes Classes) error
 }
@@ -0,0 +1,79 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package chain
+
+import (
+	"fmt"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// ID returns the chain ID of the root block of the given chain.
+func ID(chain dps.Chain) (flow.ChainID, error) {
+
+	root, err := chain.Root()
+	if err != nil {
+		return "", fmt.Errorf("could not get root height: %w", err)
+	}
+
+	header, err := chain.Header(root)
+	if err != nil {
+		return "", fmt.Errorf("could not get root header: %w", err)
+	}
+
+	return header.ChainID, nil
+}
+
+// Validate makes sure that the root block of the given chain belongs to the
+// expected chain, so that we do not index data from one chain into the index
+// of another.
+func Validate(chain dps.Chain, expected flow.ChainID) error {
+
+	actual, err := ID(chain)
+	if err != nil {
+		return fmt.Errorf("could not get chain ID: %w", err)
+	}
+
+	if actual != expected {
+		return fmt.Errorf("mismatching chain ID (expected: %s, actual: %s)", expected, actual)
+	}
+
+	return nil
+}
+
+// VerifyRoot makes sure that the state commitment of the root block of the given
+// chain matches the given state commitment, which should be the root hash of
+// the execution state trie loaded from the root checkpoint. This catches
+// mismatched pairs of protocol state and checkpoint before indexing anything.
+func VerifyRoot(chain dps.Chain, commit flow.StateCommitment) error {
+
+	root, err := chain.Root()
+	if err != nil {
+		return fmt.Errorf("could not get root height: %w", err)
+	}
+
+	expected, err := chain.Commit(root)
+	if err != nil {
+		return fmt.Errorf("could not get root commit: %w", err)
+	}
+
+	if commit != expected {
+		return fmt.Errorf("mismatching root state commitment (height: %d, expected: %x, actual: %x)", root, expected, commit)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package chain_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/chain"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestID(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			return mocks.GenericHeader, nil
+		}
+
+		got, err := chain.ID(c)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeader.ChainID, got)
+	})
+
+	t.Run("handles chain failure on Root", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.RootFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		_, err := chain.ID(c)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles chain failure on Header", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+
+		_, err := chain.ID(c)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+
+		err := chain.Validate(c, mocks.GenericHeader.ChainID)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses mismatching chain", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+
+		err := chain.Validate(c, dps.FlowMainnet)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles chain failure", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.RootFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		err := chain.Validate(c, mocks.GenericHeader.ChainID)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestVerifyRoot(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.CommitFunc = func(height uint64) (flow.StateCommitment, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			return mocks.GenericCommit(0), nil
+		}
+
+		err := chain.VerifyRoot(c, mocks.GenericCommit(0))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses mismatching commit", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(0), nil
+		}
+
+		err := chain.VerifyRoot(c, mocks.GenericCommit(1))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles chain failure on Root", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.RootFunc = func() (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		err := chain.VerifyRoot(c, mocks.GenericCommit(0))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles chain failure on Commit", func(t *testing.T) {
+		t.Parallel()
+
+		c := mocks.BaselineChain(t)
+		c.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return flow.DummyStateCommitment, mocks.GenericError
+		}
+
+		err := chain.VerifyRoot(c, mocks.GenericCommit(0))
+
+		assert.Error(t, err)
+	})
+}
@@ -22,12 +22,20 @@ import (
 var DefaultConfig = Config{
 	BufferSize:    32,
 	CatchupBlocks: []flow.Identifier{},
+	CatchupLimit:  0,
+	CaptureDir:    "",
+	NameTemplate:  "{blockID}.cbor",
+	HeightLookup:  nil,
 }
 
 // Config is the configuration for a Google Cloud Streamer.
 type Config struct {
 	BufferSize    uint
 	CatchupBlocks []flow.Identifier
+	CatchupLimit  uint
+	CaptureDir    string
+	NameTemplate  string
+	HeightLookup  func(blockID flow.Identifier) (uint64, error)
 }
 
 // Option is a function that can be applied to a Config.
@@ -48,3 +56,40 @@ func WithCatchupBlocks(blockIDs []flow.Identifier) Option {
 		cfg.CatchupBlocks = blockIDs
 	}
 }
+
+// WithCatchupLimit can be used to limit how many of the catch-up blocks are
+// queued for download at the same time. The remaining blocks are queued
+// progressively as records are downloaded. Zero means no limit.
+func WithCatchupLimit(limit uint) Option {
+	return func(cfg *Config) {
+		cfg.CatchupLimit = limit
+	}
+}
+
+// WithCaptureDir makes the Google Cloud Streamer write a copy of each
+// downloaded execution record to the given directory, so that the records can
+// later be replayed. Records are written in the background and dropped if
+// writing falls behind.
+func WithCaptureDir(dir string) Option {
+	return func(cfg *Config) {
+		cfg.CaptureDir = dir
+	}
+}
+
+// WithObjectNameTemplate can be used to specify the name of the bucket objects
+// that contain the execution records, for buckets with a custom layout. The
+// template can use the `{blockID}` and `{height}` placeholders, and using the
+// height requires a height lookup to be set as well.
+func WithObjectNameTemplate(template string) Option {
+	return func(cfg *Config) {
+		cfg.NameTemplate = template
+	}
+}
+
+// WithHeightLookup sets the function used to look up the height of a block,
+// which is needed when the object name template uses the height.
+func WithHeightLookup(lookup func(blockID flow.Identifier) (uint64, error)) Option {
+	return func(cfg *Config) {
+		cfg.HeightLookup = lookup
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cloud
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/onflow/flow-go/engine/execution/computation/computer/uploader"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// FileStreamer is a component that reads block data from execution records
+// stored in a local directory, using the same file names and encoding as the
+// Google Cloud bucket. It streams the records for the given block IDs in
+// order, which allows replaying previously captured records.
+type FileStreamer struct {
+	log     zerolog.Logger
+	decoder cbor.DecMode
+	dir     string
+	queue   *dps.SafeDeque // queue of block identifiers for next reads
+}
+
+// NewFileStreamer returns a new file streamer reading the records for the
+// given block IDs from the given directory.
+func NewFileStreamer(log zerolog.Logger, dir string, blockIDs []flow.Identifier) *FileStreamer {
+
+	f := FileStreamer{
+		log:     log.With().Str("component", "file_streamer").Logger(),
+		decoder: recordDecoder(),
+		dir:     dir,
+		queue:   dps.NewDeque(),
+	}
+
+	for _, blockID := range blockIDs {
+		f.queue.PushFront(blockID)
+	}
+
+	return &f
+}
+
+// Next returns the block data of the next record. It returns an ErrFinished
+// once the records of all block IDs have been returned.
+func (f *FileStreamer) Next() (*uploader.BlockData, error) {
+
+	if f.queue.Len() == 0 {
+		return nil, dps.ErrFinished
+	}
+
+	blockID := f.queue.PopBack().(flow.Identifier)
+	path := filepath.Join(f.dir, RecordName(blockID))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read execution record file (path: %s): %w", path, err)
+	}
+
+	record, err := decodeRecord(f.decoder, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode execution record file (path: %s): %w", path, err)
+	}
+
+	f.log.Debug().
+		Str("path", path).
+		Uint64("height", record.Block.Header.Height).
+		Hex("block", blockID[:]).
+		Msg("execution record read from file")
+
+	return record, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cloud
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestNewFileStreamer(t *testing.T) {
+	blockIDs := mocks.GenericBlockIDs(4)
+
+	streamer := NewFileStreamer(zerolog.Nop(), "records", blockIDs)
+
+	require.NotNil(t, streamer)
+	assert.NotZero(t, streamer.log)
+	assert.NotNil(t, streamer.decoder)
+	assert.Equal(t, "records", streamer.dir)
+	require.Equal(t, len(blockIDs), streamer.queue.Len())
+	for _, blockID := range blockIDs {
+		assert.Equal(t, blockID, streamer.queue.PopBack())
+	}
+}
+
+func TestFileStreamer_Next(t *testing.T) {
+	record := mocks.GenericRecord()
+	data, err := cbor.Marshal(record)
+	require.NoError(t, err)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		blockIDs := mocks.GenericBlockIDs(3)
+		for _, blockID := range blockIDs {
+			path := filepath.Join(dir, RecordName(blockID))
+			require.NoError(t, os.WriteFile(path, data, 0644))
+		}
+
+		streamer := NewFileStreamer(zerolog.Nop(), dir, blockIDs)
+
+		for range blockIDs {
+			got, err := streamer.Next()
+			require.NoError(t, err)
+			assert.Equal(t, record, got)
+		}
+
+		_, err := streamer.Next()
+		assert.ErrorIs(t, err, dps.ErrFinished)
+	})
+
+	t.Run("handles missing record file", func(t *testing.T) {
+		t.Parallel()
+
+		streamer := NewFileStreamer(zerolog.Nop(), t.TempDir(), []flow.Identifier{record.Block.ID()})
+
+		_, err := streamer.Next()
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, dps.ErrFinished)
+	})
+
+	t.Run("handles invalid record file", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		blockID := record.Block.ID()
+		path := filepath.Join(dir, RecordName(blockID))
+		require.NoError(t, os.WriteFile(path, mocks.GenericBytes, 0644))
+
+		streamer := NewFileStreamer(zerolog.Nop(), dir, []flow.Identifier{blockID})
+
+		_, err := streamer.Next()
+		assert.Error(t, err)
+	})
+}
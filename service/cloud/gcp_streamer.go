@@ -19,6 +19,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 
 	"cloud.google.com/go/storage"
@@ -44,46 +49,83 @@ type GCPStreamer struct {
 	buffer  *dps.SafeDeque // queue of downloaded execution data records
 	limit   uint           // buffer size limit for downloaded records
 	busy    uint32         // used as a guard to avoid concurrent polling
+
+	mutex   *sync.Mutex       // guards the pending block identifiers
+	pending []flow.Identifier // block identifiers waiting to be queued
+	catchup uint              // queue size limit while blocks are pending
+
+	dir     string        // directory in which to capture records
+	capture chan captured // queue of downloaded records to capture
+
+	template string                                // template for object names
+	lookup   func(flow.Identifier) (uint64, error) // height lookup for templates
+}
+
+// captured is a downloaded execution record waiting to be written to disk.
+type captured struct {
+	name string
+	data []byte
 }
 
 // NewGCPStreamer returns a new GCP Streamer using the given bucket and options.
-func NewGCPStreamer(log zerolog.Logger, bucket *storage.BucketHandle, options ...Option) *GCPStreamer {
+func NewGCPStreamer(log zerolog.Logger, bucket *storage.BucketHandle, options ...Option) (*GCPStreamer, error) {
 
 	cfg := DefaultConfig
 	for _, option := range options {
 		option(&cfg)
 	}
 
-	decOptions := cbor.DecOptions{
-		ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
-	}
-	decoder, err := decOptions.DecMode()
+	err := validateTemplate(cfg.NameTemplate)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("invalid object name template: %w", err)
+	}
+	if strings.Contains(cfg.NameTemplate, placeholderHeight) && cfg.HeightLookup == nil {
+		return nil, fmt.Errorf("object name template uses height without height lookup")
 	}
 
 	g := GCPStreamer{
 		log:     log.With().Str("component", "gcp_streamer").Logger(),
-		decoder: decoder,
+		decoder: recordDecoder(),
 		bucket:  bucket,
 		queue:   dps.NewDeque(),
 		buffer:  dps.NewDeque(),
 		limit:   cfg.BufferSize,
 		busy:    0,
+
+		mutex:   &sync.Mutex{},
+		pending: cfg.CatchupBlocks,
+		catchup: cfg.CatchupLimit,
+
+		dir:     cfg.CaptureDir,
+		capture: make(chan captured, cfg.BufferSize),
+
+		template: cfg.NameTemplate,
+		lookup:   cfg.HeightLookup,
 	}
 
-	for _, blockID := range cfg.CatchupBlocks {
-		g.queue.PushFront(blockID)
-		g.log.Debug().Hex("block", blockID[:]).Msg("execution record queued for catch-up")
+	g.refill()
+
+	if g.dir != "" {
+		go g.captureRecords()
 	}
 
-	return &g
+	return &g, nil
 }
 
 // OnBlockFinalized is a callback for the Flow consensus follower. It is called
 // each time a block is finalized by the Flow consensus algorithm.
 func (g *GCPStreamer) OnBlockFinalized(blockID flow.Identifier) {
 
+	// If we still have catch-up blocks waiting to be queued, the block has to
+	// wait behind them, so that we download all blocks in order.
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if len(g.pending) > 0 {
+		g.pending = append(g.pending, blockID)
+		g.log.Debug().Hex("block", blockID[:]).Msg("execution record pending for download")
+		return
+	}
+
 	// We push the block ID to the front of the queue; the streamer will try to
 	// download the blocks in a FIFO manner.
 	g.queue.PushFront(blockID)
@@ -143,10 +185,29 @@ func (g *GCPStreamer) poll() {
 	}
 }
 
+// refill moves pending block identifiers into the queue, until either none
+// are left, or the queue has reached the catch-up limit.
+func (g *GCPStreamer) refill() {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for len(g.pending) > 0 && (g.catchup == 0 || uint(g.queue.Len()) < g.catchup) {
+		blockID := g.pending[0]
+		g.pending = g.pending[1:]
+		g.queue.PushFront(blockID)
+		g.log.Debug().Hex("block", blockID[:]).Msg("execution record queued for catch-up")
+	}
+}
+
 func (g *GCPStreamer) download() error {
 
 	for {
 
+		// If we have catch-up blocks that were not queued yet because of the
+		// catch-up limit, we queue as many as possible before downloading.
+		g.refill()
+
 		// We only want to retrieve and process files until the buffer is full. We
 		// do not need to have a big buffer; we just want to avoid HTTP request
 		// latency when the execution follower wants a block record.
@@ -166,13 +227,17 @@ func (g *GCPStreamer) download() error {
 			return nil
 		}
 
-		// Get the name of the file based on the block ID. The file name is
-		// made up of the block ID in hex and a `.cbor` extension, see:
+		// Get the name of the file based on the block ID. By default, the file
+		// name is made up of the block ID in hex and a `.cbor` extension, see:
 		// Maks: "thats correct. In fact the full name is `<blockID>.cbor`"
 		// If we encounter an error, such as that the file is not found, we put
 		// the block ID back into the queue and return `nil` to stop pulling.
 		blockID := g.queue.PopBack().(flow.Identifier)
-		name := blockID.String() + ".cbor"
+		name, err := g.objectName(blockID)
+		if err != nil {
+			g.queue.PushBack(blockID)
+			return fmt.Errorf("could not get object name (block: %x): %w", blockID, err)
+		}
 		record, err := g.pullRecord(name)
 		if err != nil {
 			g.queue.PushBack(blockID)
@@ -189,6 +254,24 @@ func (g *GCPStreamer) download() error {
 	}
 }
 
+// objectName returns the name of the bucket object with the execution record
+// for the given block ID, according to the object name template.
+func (g *GCPStreamer) objectName(blockID flow.Identifier) (string, error) {
+
+	name := strings.ReplaceAll(g.template, placeholderBlockID, blockID.String())
+	if !strings.Contains(name, placeholderHeight) {
+		return name, nil
+	}
+
+	height, err := g.lookup(blockID)
+	if err != nil {
+		return "", fmt.Errorf("could not look up height: %w", err)
+	}
+	name = strings.ReplaceAll(name, placeholderHeight, strconv.FormatUint(height, 10))
+
+	return name, nil
+}
+
 func (g *GCPStreamer) pullRecord(name string) (*uploader.BlockData, error) {
 
 	object := g.bucket.Object(name)
@@ -203,19 +286,36 @@ func (g *GCPStreamer) pullRecord(name string) (*uploader.BlockData, error) {
 		return nil, fmt.Errorf("could not read execution record: %w", err)
 	}
 
-	var record uploader.BlockData
-	err = g.decoder.Unmarshal(data, &record)
+	record, err := decodeRecord(g.decoder, data)
 	if err != nil {
-		return nil, fmt.Errorf("could not decode execution record: %w", err)
+		return nil, err
 	}
 
-	if record.FinalStateCommitment == flow.DummyStateCommitment {
-		return nil, fmt.Errorf("execution record contains empty state commitment")
+	// If capturing is enabled, we hand the record over to be written in the
+	// background. If the capture queue is full, we drop the record rather
+	// than slowing down the download.
+	if g.dir != "" {
+		select {
+		case g.capture <- captured{name: RecordName(record.Block.Header.ID()), data: data}:
+		default:
+			g.log.Warn().Str("name", name).Msg("capture queue full, execution record not captured")
+		}
 	}
 
-	if record.Block.Header.Height == 0 {
-		return nil, fmt.Errorf("execution record contains empty block data")
-	}
+	return record, nil
+}
 
-	return &record, nil
+// captureRecords writes the captured execution records to the capture
+// directory, using the default object name and the same encoding as in the
+// bucket.
+func (g *GCPStreamer) captureRecords() {
+	for record := range g.capture {
+		path := filepath.Join(g.dir, record.name)
+		err := os.WriteFile(path, record.data, 0644)
+		if err != nil {
+			g.log.Error().Err(err).Str("path", path).Msg("could not capture execution record")
+			continue
+		}
+		g.log.Debug().Str("path", path).Msg("execution record captured")
+	}
 }
@@ -15,9 +15,13 @@
 package cloud
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,6 +33,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/api/option"
 
+	"github.com/onflow/flow-go/model/flow"
+
 	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/testing/mocks"
 )
@@ -39,13 +45,14 @@ func TestNewGCPStreamer(t *testing.T) {
 	limit := uint(42)
 	blockIDs := mocks.GenericBlockIDs(4)
 
-	streamer := NewGCPStreamer(
+	streamer, err := NewGCPStreamer(
 		log,
 		bucket,
 		WithBufferSize(limit),
 		WithCatchupBlocks(blockIDs),
 	)
 
+	require.NoError(t, err)
 	require.NotNil(t, streamer)
 	assert.NotZero(t, streamer.log)
 	assert.Equal(t, bucket, streamer.bucket)
@@ -58,19 +65,77 @@ func TestNewGCPStreamer(t *testing.T) {
 	}
 }
 
+func TestNewGCPStreamer_CatchupLimit(t *testing.T) {
+	blockIDs := mocks.GenericBlockIDs(6)
+
+	streamer, err := NewGCPStreamer(
+		zerolog.Nop(),
+		&storage.BucketHandle{},
+		WithCatchupBlocks(blockIDs),
+		WithCatchupLimit(2),
+	)
+
+	require.NoError(t, err)
+	require.Equal(t, 2, streamer.queue.Len())
+	assert.Equal(t, blockIDs[0], streamer.queue.PopBack())
+	assert.Equal(t, blockIDs[1], streamer.queue.PopBack())
+	assert.Equal(t, blockIDs[2:], streamer.pending)
+}
+
 func TestGCPStreamer_OnBlockFinalized(t *testing.T) {
-	blockID := mocks.GenericHeader.ID()
-	queue := dps.NewDeque()
+	t.Run("nominal case", func(t *testing.T) {
+		blockID := mocks.GenericHeader.ID()
+		queue := dps.NewDeque()
 
-	streamer := &GCPStreamer{
-		log:   zerolog.Nop(),
-		queue: queue,
-	}
+		streamer := &GCPStreamer{
+			log:   zerolog.Nop(),
+			queue: queue,
+			mutex: &sync.Mutex{},
+		}
+
+		streamer.OnBlockFinalized(blockID)
+
+		require.Equal(t, 1, queue.Len())
+		assert.Equal(t, queue.PopFront(), blockID)
+	})
+
+	t.Run("keeps order with pending catch-up blocks", func(t *testing.T) {
+		blockIDs := mocks.GenericBlockIDs(6)
+		catchup, finalized := blockIDs[:4], blockIDs[4:]
+
+		streamer := &GCPStreamer{
+			log:     zerolog.Nop(),
+			queue:   dps.NewDeque(),
+			mutex:   &sync.Mutex{},
+			pending: catchup,
+			catchup: 2,
+		}
+		streamer.refill()
+
+		for _, blockID := range finalized {
+			streamer.OnBlockFinalized(blockID)
+		}
+
+		// Simulate the download loop, which pops block IDs from the queue and
+		// refills it with pending block IDs.
+		var got []flow.Identifier
+		for streamer.queue.Len() > 0 {
+			assert.LessOrEqual(t, streamer.queue.Len(), 2)
+			got = append(got, streamer.queue.PopBack().(flow.Identifier))
+			streamer.refill()
+		}
+
+		assert.Equal(t, blockIDs, got)
+		assert.Empty(t, streamer.pending)
 
-	streamer.OnBlockFinalized(blockID)
+		// Once no catch-up blocks are pending, finalized blocks are queued
+		// directly again.
+		blockID := mocks.GenericHeader.ID()
+		streamer.OnBlockFinalized(blockID)
 
-	require.Equal(t, 1, queue.Len())
-	assert.Equal(t, queue.PopFront(), blockID)
+		require.Equal(t, 1, streamer.queue.Len())
+		assert.Equal(t, blockID, streamer.queue.PopBack())
+	})
 }
 
 func TestGCPStreamer_Next(t *testing.T) {
@@ -102,6 +167,9 @@ func TestGCPStreamer_Next(t *testing.T) {
 			queue:   dps.NewDeque(),
 			buffer:  dps.NewDeque(),
 			limit:   999,
+			mutex:   &sync.Mutex{},
+
+			template: DefaultConfig.NameTemplate,
 		}
 
 		streamer.buffer.PushFront(record)
@@ -132,6 +200,9 @@ func TestGCPStreamer_Next(t *testing.T) {
 			queue:   dps.NewDeque(),
 			buffer:  dps.NewDeque(),
 			limit:   999,
+			mutex:   &sync.Mutex{},
+
+			template: DefaultConfig.NameTemplate,
 		}
 
 		_, err = streamer.Next()
@@ -162,6 +233,9 @@ func TestGCPStreamer_Next(t *testing.T) {
 			queue:   dps.NewDeque(),
 			buffer:  dps.NewDeque(),
 			limit:   999,
+			mutex:   &sync.Mutex{},
+
+			template: DefaultConfig.NameTemplate,
 		}
 
 		streamer.queue.PushFront(record.Block.ID())
@@ -180,3 +254,141 @@ func TestGCPStreamer_Next(t *testing.T) {
 		assert.Zero(t, streamer.queue.Len())
 	})
 }
+
+func TestGCPStreamer_Capture(t *testing.T) {
+	record := mocks.GenericRecord()
+	data, err := cbor.Marshal(record)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write(data)
+	}))
+	defer server.Close()
+
+	client, err := gcloud.NewClient(
+		context.Background(),
+		option.WithoutAuthentication(),
+		option.WithEndpoint(server.URL),
+	)
+	require.NoError(t, err)
+	bucket := client.Bucket("test")
+
+	dir := t.TempDir()
+	blockID := record.Block.ID()
+	streamer, err := NewGCPStreamer(
+		zerolog.Nop(),
+		bucket,
+		WithCatchupBlocks([]flow.Identifier{blockID}),
+		WithCaptureDir(dir),
+	)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, err := streamer.Next()
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	path := filepath.Join(dir, RecordName(blockID))
+	assert.Eventually(t, func() bool {
+		captured, _ := os.ReadFile(path)
+		return bytes.Equal(data, captured)
+	}, time.Second, 10*time.Millisecond)
+
+	// The captured record should be readable by the file streamer, so that it
+	// can be replayed.
+	files := NewFileStreamer(zerolog.Nop(), dir, []flow.Identifier{blockID})
+	got, err := files.Next()
+	require.NoError(t, err)
+	assert.Equal(t, record, got)
+}
+
+func TestNewGCPStreamer_NameTemplate(t *testing.T) {
+	lookup := func(flow.Identifier) (uint64, error) {
+		return mocks.GenericHeight, nil
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		options  []Option
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{name: "block ID only", template: "{blockID}.cbor", wantErr: assert.NoError},
+		{name: "height with lookup", template: "{height}/{blockID}.cbor", options: []Option{WithHeightLookup(lookup)}, wantErr: assert.NoError},
+		{name: "height without lookup", template: "{height}.cbor", wantErr: assert.Error},
+		{name: "no placeholder", template: "record.cbor", wantErr: assert.Error},
+		{name: "unknown placeholder", template: "{date}/{blockID}.cbor", wantErr: assert.Error},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			options := append([]Option{WithObjectNameTemplate(test.template)}, test.options...)
+			_, err := NewGCPStreamer(zerolog.Nop(), &storage.BucketHandle{}, options...)
+			test.wantErr(t, err)
+		})
+	}
+}
+
+func TestGCPStreamer_objectName(t *testing.T) {
+	blockID := mocks.GenericHeader.ID()
+	lookup := func(id flow.Identifier) (uint64, error) {
+		assert.Equal(t, blockID, id)
+		return 1337, nil
+	}
+
+	t.Run("default layout", func(t *testing.T) {
+		t.Parallel()
+
+		streamer, err := NewGCPStreamer(zerolog.Nop(), &storage.BucketHandle{})
+		require.NoError(t, err)
+
+		got, err := streamer.objectName(blockID)
+		require.NoError(t, err)
+		assert.Equal(t, RecordName(blockID), got)
+	})
+
+	t.Run("prefixed layout", func(t *testing.T) {
+		t.Parallel()
+
+		streamer, err := NewGCPStreamer(zerolog.Nop(), &storage.BucketHandle{},
+			WithObjectNameTemplate("mainnet-15/records/{blockID}.cbor"),
+		)
+		require.NoError(t, err)
+
+		got, err := streamer.objectName(blockID)
+		require.NoError(t, err)
+		assert.Equal(t, "mainnet-15/records/"+blockID.String()+".cbor", got)
+	})
+
+	t.Run("height partitioned layout", func(t *testing.T) {
+		t.Parallel()
+
+		streamer, err := NewGCPStreamer(zerolog.Nop(), &storage.BucketHandle{},
+			WithObjectNameTemplate("{height}/{blockID}.cbor"),
+			WithHeightLookup(lookup),
+		)
+		require.NoError(t, err)
+
+		got, err := streamer.objectName(blockID)
+		require.NoError(t, err)
+		assert.Equal(t, "1337/"+blockID.String()+".cbor", got)
+	})
+
+	t.Run("handles height lookup failure", func(t *testing.T) {
+		t.Parallel()
+
+		streamer, err := NewGCPStreamer(zerolog.Nop(), &storage.BucketHandle{},
+			WithObjectNameTemplate("{height}.cbor"),
+			WithHeightLookup(func(flow.Identifier) (uint64, error) {
+				return 0, mocks.GenericError
+			}),
+		)
+		require.NoError(t, err)
+
+		_, err = streamer.objectName(blockID)
+		assert.Error(t, err)
+	})
+}
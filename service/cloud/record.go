@@ -0,0 +1,92 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package cloud
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/onflow/flow-go/engine/execution/computation/computer/uploader"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// RecordName returns the name under which the execution record of the block
+// with the given ID is stored.
+func RecordName(blockID flow.Identifier) string {
+	return blockID.String() + ".cbor"
+}
+
+// Placeholders that can be used in object name templates.
+const (
+	placeholderBlockID = "{blockID}"
+	placeholderHeight  = "{height}"
+)
+
+// placeholder matches anything that looks like a placeholder in a template.
+var placeholder = regexp.MustCompile(`{[^{}]*}`)
+
+// validateTemplate makes sure that the given object name template only uses
+// known placeholders, and uses at least one of them to identify the block.
+func validateTemplate(template string) error {
+
+	placeholders := placeholder.FindAllString(template, -1)
+	if len(placeholders) == 0 {
+		return fmt.Errorf("template does not contain block ID or height placeholder")
+	}
+	for _, p := range placeholders {
+		if p != placeholderBlockID && p != placeholderHeight {
+			return fmt.Errorf("unknown placeholder in template (%s)", p)
+		}
+	}
+
+	return nil
+}
+
+// recordDecoder returns the CBOR decoder used for execution records, which
+// rejects records with unknown fields.
+func recordDecoder() cbor.DecMode {
+
+	decOptions := cbor.DecOptions{
+		ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+	}
+	decoder, err := decOptions.DecMode()
+	if err != nil {
+		panic(err)
+	}
+
+	return decoder
+}
+
+// decodeRecord decodes an execution record and makes sure it is not empty.
+func decodeRecord(decoder cbor.DecMode, data []byte) (*uploader.BlockData, error) {
+
+	var record uploader.BlockData
+	err := decoder.Unmarshal(data, &record)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode execution record: %w", err)
+	}
+
+	if record.FinalStateCommitment == flow.DummyStateCommitment {
+		return nil, fmt.Errorf("execution record contains empty state commitment")
+	}
+
+	if record.Block.Header.Height == 0 {
+		return nil, fmt.Errorf("execution record contains empty block data")
+	}
+
+	return &record, nil
+}
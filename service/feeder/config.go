@@ -0,0 +1,64 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package feeder
+
+// DefaultConfig is the default configuration for the feeder. It is used when no
+// options are specified.
+var DefaultConfig = Config{
+	ReadAhead:     0,
+	ReadAheadSize: 256 * 1024 * 1024, // 256 MiB
+}
+
+// Config contains the configuration options for the feeder.
+type Config struct {
+	ReadAhead     uint
+	ReadAheadSize uint64
+	SkipCorrupt   Segments
+}
+
+// Option is a configuration option for the feeder. It can be passed to the
+// feeder's construction function to set optional parameters.
+type Option func(*Config)
+
+// WithReadAhead sets the number of trie updates that are read from the
+// write-ahead log in advance, while the consumer is still processing previous
+// trie updates. Zero disables read-ahead, so that trie updates are only read
+// when they are requested.
+func WithReadAhead(n uint) Option {
+	return func(cfg *Config) {
+		cfg.ReadAhead = n
+	}
+}
+
+// WithReadAheadSize sets the maximum size in bytes of the trie updates that are
+// held in memory by the read-ahead buffer. Trie updates that are bigger than
+// the maximum size are still read, but only once the buffer is empty.
+func WithReadAheadSize(size uint64) Option {
+	return func(cfg *Config) {
+		cfg.ReadAheadSize = size
+	}
+}
+
+// WithSkipCorrupt makes the feeder skip corrupt records of the write-ahead log
+// instead of failing. As the trie updates of a corrupt record are lost, the
+// feeder skips to the first checkpoint that includes the corrupt segment, and
+// returns a `mapper.Gap` error with its tries, so that the consumer can rebuild
+// its state from them. It then continues with the segment that follows the
+// checkpoint. If there is no such checkpoint, it fails.
+func WithSkipCorrupt(segments Segments) Option {
+	return func(cfg *Config) {
+		cfg.SkipCorrupt = segments
+	}
+}
@@ -15,69 +15,176 @@
 package feeder
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	tsdb "github.com/prometheus/tsdb/wal"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/semaphore"
+
 	"github.com/onflow/flow-go/ledger"
 
 	"github.com/optakt/flow-dps/ledger/wal"
 	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/mapper"
 )
 
 // Feeder is a component that retrieves trie updates and feeds them to its consumer.
 type Feeder struct {
-	reader WALReader
+	log     zerolog.Logger
+	cfg     Config
+	reader  WALReader
+	segment int
+	results chan result
+	memory  *semaphore.Weighted
+}
+
+type result struct {
+	update *ledger.TrieUpdate
+	size   int64
+	err    error
 }
 
 // FromWAL creates a trie update feeder that sources state deltas from a WAL reader.
-func FromWAL(reader WALReader) *Feeder {
+// When read-ahead is enabled, the feeder reads the next trie updates in the
+// background, so that the consumer does not have to wait on disk I/O between
+// blocks.
+func FromWAL(log zerolog.Logger, reader WALReader, options ...Option) *Feeder {
+
+	cfg := DefaultConfig
+	for _, option := range options {
+		option(&cfg)
+	}
 
 	f := Feeder{
+		log:    log.With().Str("component", "feeder").Logger(),
+		cfg:    cfg,
 		reader: reader,
 	}
 
+	if cfg.ReadAhead > 0 && cfg.ReadAheadSize > 0 {
+		f.memory = semaphore.NewWeighted(int64(cfg.ReadAheadSize))
+	}
+
 	return &f
 }
 
 // Update returns the next trie update.
 func (f *Feeder) Update() (*ledger.TrieUpdate, error) {
 
+	// Without read-ahead, we simply read the next trie update when it is
+	// requested.
+	if f.cfg.ReadAhead == 0 {
+		return f.read()
+	}
+
+	// Otherwise, we start reading ahead in the background, if we are not
+	// already doing so, and take the next result from the buffer. The reading
+	// stops on the first error, including the write-ahead log being exhausted,
+	// so we restart it on the next call after an error.
+	if f.results == nil {
+		f.results = make(chan result, f.cfg.ReadAhead)
+		go f.prefetch(f.results)
+	}
+	res := <-f.results
+	if f.memory != nil {
+		f.memory.Release(res.size)
+	}
+	if res.err != nil {
+		f.results = nil
+		return nil, res.err
+	}
+
+	return res.update, nil
+}
+
+// prefetch reads trie updates into the given channel until it encounters an
+// error, which is also sent on the channel. When a maximum size is configured,
+// it waits for enough of the buffered trie updates to be consumed before
+// reading more.
+func (f *Feeder) prefetch(results chan<- result) {
+	for {
+		update, err := f.read()
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+
+		size := int64(0)
+		if f.memory != nil {
+			size = updateSize(update)
+			if size > int64(f.cfg.ReadAheadSize) {
+				size = int64(f.cfg.ReadAheadSize)
+			}
+			_ = f.memory.Acquire(context.Background(), size)
+		}
+
+		results <- result{update: update, size: size}
+	}
+}
+
+// read reads the next trie update from the write-ahead log.
+func (f *Feeder) read() (*ledger.TrieUpdate, error) {
+
 	// We read in a loop because the WAL contains entries that are not trie
 	// updates; we don't really need to care about them, so we can just skip
 	// them until we find a trie update.
 	for {
 
+		// If we skipped to a checkpoint after a corrupt segment, we first need
+		// to open a new reader that starts at the segment following it.
+		if f.reader == nil {
+			reader, err := f.cfg.SkipCorrupt.Open(f.segment)
+			if errors.Is(err, dps.ErrUnavailable) {
+				return nil, dps.ErrUnavailable
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not open next segment (segment: %d): %w", f.segment, err)
+			}
+			f.reader = reader
+		}
+
 		// This part reads the next entry from the WAL, makes sure we didn't
 		// encounter an error when reading or decoding and ensures that it's a
-		// trie update.
+		// trie update. When the reader encounters a corrupt or truncated
+		// record, it can not continue reading the current segment, so we
+		// either fail or skip to the next checkpoint.
 		next := f.reader.Next()
 		err := f.reader.Err()
+		var corrupt *tsdb.CorruptionErr
+		if !next && errors.As(err, &corrupt) {
+			err = fmt.Errorf("could not read corrupt record (segment: %d, offset: %d): %w", corrupt.Segment, corrupt.Offset, corrupt.Err)
+			if f.cfg.SkipCorrupt == nil || corrupt.Segment < 0 {
+				return nil, err
+			}
+			return nil, f.skip(corrupt.Segment, err)
+		}
 		if !next && err != nil {
 			return nil, fmt.Errorf("could not read next record: %w", err)
 		}
 		if !next {
 			return nil, dps.ErrUnavailable
 		}
+
+		// Records with an intact checksum can still fail to decode, in which
+		// case the trie update they hold is lost all the same.
 		record := f.reader.Record()
 		operation, _, update, err := wal.Decode(record)
+		if err == nil && operation == wal.OperationUpdate {
+			err = validate(update)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("could not decode record: %w", err)
+			err = fmt.Errorf("could not decode record (segment: %d, offset: %d): %w", f.reader.Segment(), f.reader.Offset(), err)
+			if f.cfg.SkipCorrupt == nil {
+				return nil, err
+			}
+			return nil, f.skip(f.reader.Segment(), err)
 		}
 		if operation != wal.OperationUpdate {
 			continue
 		}
 
-		// For older versions, we need to verify the length of types that are aliased
-		// to the hash.Hash type from Flow Go, because it is a slice instead of
-		// a fixed-length byte array.
-		if len(update.RootHash) != 32 {
-			return nil, fmt.Errorf("invalid ledger root hash length in trie update: got %d want 32", len(update.RootHash))
-		}
-		for _, path := range update.Paths {
-			if len(path) != 32 {
-				return nil, fmt.Errorf("invalid ledger path length in trie update: got %d want 32", len(path))
-			}
-		}
-
 		// However, we need to make sure that all slices are copied, because the
 		// decode function will reuse the underlying slices later.
 		update = clone(update)
@@ -85,3 +192,56 @@ func (f *Feeder) Update() (*ledger.TrieUpdate, error) {
 		return update, nil
 	}
 }
+
+// validate verifies the length of types in the given trie update that are
+// aliased to the hash.Hash type from Flow Go. For older versions, it is a slice
+// instead of a fixed-length byte array.
+// skip moves the feeder to the first checkpoint that includes the given corrupt
+// segment, so that it continues reading from the segment following it. It
+// returns a gap error with the tries of the checkpoint, unless there is no such
+// checkpoint, in which case it returns the given error.
+func (f *Feeder) skip(segment int, cause error) error {
+
+	number, tries, err := f.cfg.SkipCorrupt.Checkpoint(segment)
+	if errors.Is(err, dps.ErrUnavailable) {
+		return fmt.Errorf("could not skip corrupt segment without later checkpoint: %w", cause)
+	}
+	if err != nil {
+		return fmt.Errorf("could not load checkpoint after corrupt segment (segment: %d): %w", segment, err)
+	}
+
+	f.log.Warn().
+		Int("segment", segment).
+		Int("checkpoint", number).
+		Err(cause).
+		Msg("skipping to checkpoint after corrupt write-ahead log segment")
+
+	f.reader = nil
+	f.segment = number + 1
+
+	return &mapper.Gap{Tries: tries, Err: cause}
+}
+
+func validate(update *ledger.TrieUpdate) error {
+	if len(update.RootHash) != 32 {
+		return fmt.Errorf("invalid ledger root hash length in trie update: got %d want 32", len(update.RootHash))
+	}
+	for _, path := range update.Paths {
+		if len(path) != 32 {
+			return fmt.Errorf("invalid ledger path length in trie update: got %d want 32", len(path))
+		}
+	}
+	return nil
+}
+
+// updateSize estimates the memory used by the given trie update.
+func updateSize(update *ledger.TrieUpdate) int64 {
+	size := int64(len(update.RootHash))
+	for _, path := range update.Paths {
+		size += int64(len(path))
+	}
+	for _, payload := range update.Payloads {
+		size += int64(payload.Size())
+	}
+	return size
+}
@@ -15,25 +15,47 @@
 package feeder
 
 import (
+	"errors"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	tsdb "github.com/prometheus/tsdb/wal"
+
+	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/ledger/common/encoding"
 
+	"github.com/optakt/flow-dps/ledger/trie"
 	"github.com/optakt/flow-dps/ledger/wal"
 	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/mapper"
 	"github.com/optakt/flow-dps/testing/mocks"
 )
 
 func TestFromWAL(t *testing.T) {
-	reader := mocks.BaselineWALReader(t)
+	t.Run("nominal case", func(t *testing.T) {
+		reader := mocks.BaselineWALReader(t)
+
+		feeder := FromWAL(mocks.NoopLogger, reader)
 
-	feeder := FromWAL(reader)
+		assert.Equal(t, reader, feeder.reader)
+		assert.Equal(t, DefaultConfig, feeder.cfg)
+		assert.Nil(t, feeder.memory)
+	})
+
+	t.Run("with read-ahead", func(t *testing.T) {
+		reader := mocks.BaselineWALReader(t)
 
-	assert.Equal(t, reader, feeder.reader)
+		feeder := FromWAL(mocks.NoopLogger, reader, WithReadAhead(16), WithReadAheadSize(1024))
+
+		assert.Equal(t, uint(16), feeder.cfg.ReadAhead)
+		assert.Equal(t, uint64(1024), feeder.cfg.ReadAheadSize)
+		assert.NotNil(t, feeder.memory)
+	})
 }
 
 func TestFeeder_Update(t *testing.T) {
@@ -129,3 +151,372 @@ func TestFeeder_Update(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestFeeder_UpdateReadAhead(t *testing.T) {
+	updates := []*ledger.TrieUpdate{
+		mocks.GenericTrieUpdate(0),
+		mocks.GenericTrieUpdate(1),
+		mocks.GenericTrieUpdate(2),
+		mocks.GenericTrieUpdate(3),
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		reader, available := walReader(t, updates)
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithReadAhead(2))
+
+		*available = 3
+		for i := 0; i < 3; i++ {
+			got, err := feeder.Update()
+			require.NoError(t, err)
+			assert.Equal(t, updates[i], got)
+		}
+
+		_, err := feeder.Update()
+		assert.ErrorIs(t, err, dps.ErrUnavailable)
+
+		// Once more trie updates become available, reading ahead should
+		// resume where it left off.
+		*available = 4
+		got, err := feeder.Update()
+		require.NoError(t, err)
+		assert.Equal(t, updates[3], got)
+	})
+
+	t.Run("handles trie updates bigger than maximum size", func(t *testing.T) {
+		t.Parallel()
+
+		reader, available := walReader(t, updates)
+		*available = len(updates)
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithReadAhead(2), WithReadAheadSize(1))
+
+		for i := range updates {
+			got, err := feeder.Update()
+			require.NoError(t, err)
+			assert.Equal(t, updates[i], got)
+		}
+	})
+
+	t.Run("handles reader failure", func(t *testing.T) {
+		t.Parallel()
+
+		reader := mocks.BaselineWALReader(t)
+		reader.NextFunc = func() bool {
+			return false
+		}
+		reader.ErrFunc = func() error {
+			return mocks.GenericError
+		}
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithReadAhead(2))
+
+		_, err := feeder.Update()
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+}
+
+func TestFeeder_UpdateCorrupt(t *testing.T) {
+	updates := []*ledger.TrieUpdate{
+		mocks.GenericTrieUpdate(0),
+		mocks.GenericTrieUpdate(1),
+		mocks.GenericTrieUpdate(2),
+		mocks.GenericTrieUpdate(3),
+	}
+	tries := []*trie.Trie{trie.NewEmptyTrie()}
+
+	// We write the first three trie updates to the first segment and the last
+	// one to the second segment, and then corrupt the second record of the first
+	// segment.
+	dir := t.TempDir()
+	writeSegment(t, dir, 0, updates[:3])
+	writeSegment(t, dir, 1, updates[3:])
+	corruptRecord(t, dir, 0, 1)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		reader := segmentsReader(t, dir, 0)
+		segments := checkpointSegments(dir, 0, tries)
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithSkipCorrupt(segments))
+
+		got, err := feeder.Update()
+		require.NoError(t, err)
+		assert.Equal(t, updates[0], got)
+
+		// The corrupt record should result in a gap, with the tries of the
+		// checkpoint that includes the first segment.
+		_, err = feeder.Update()
+		var gap *mapper.Gap
+		require.ErrorAs(t, err, &gap)
+		assert.Equal(t, tries, gap.Tries)
+		assert.Contains(t, gap.Error(), "segment: 0")
+
+		// We should then continue with the trie update from the segment that
+		// follows the checkpoint.
+		got, err = feeder.Update()
+		require.NoError(t, err)
+		assert.Equal(t, updates[3], got)
+
+		_, err = feeder.Update()
+		assert.ErrorIs(t, err, dps.ErrUnavailable)
+	})
+
+	t.Run("with read-ahead", func(t *testing.T) {
+		t.Parallel()
+
+		reader := segmentsReader(t, dir, 0)
+		segments := checkpointSegments(dir, 0, tries)
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithReadAhead(2), WithSkipCorrupt(segments))
+
+		got, err := feeder.Update()
+		require.NoError(t, err)
+		assert.Equal(t, updates[0], got)
+
+		_, err = feeder.Update()
+		var gap *mapper.Gap
+		require.ErrorAs(t, err, &gap)
+
+		got, err = feeder.Update()
+		require.NoError(t, err)
+		assert.Equal(t, updates[3], got)
+	})
+
+	t.Run("handles corrupt record without skipping", func(t *testing.T) {
+		t.Parallel()
+
+		reader := segmentsReader(t, dir, 0)
+
+		feeder := FromWAL(mocks.NoopLogger, reader)
+
+		got, err := feeder.Update()
+		require.NoError(t, err)
+		assert.Equal(t, updates[0], got)
+
+		_, err = feeder.Update()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "segment: 0")
+		var gap *mapper.Gap
+		assert.False(t, errors.As(err, &gap))
+	})
+
+	t.Run("skips to checkpoint after invalid record", func(t *testing.T) {
+		t.Parallel()
+
+		var index int
+		reader := mocks.BaselineWALReader(t)
+		reader.NextFunc = func() bool {
+			index++
+			return index <= 2
+		}
+		reader.RecordFunc = func() []byte {
+			if index == 1 {
+				return mocks.GenericBytes
+			}
+			data := encoding.EncodeTrieUpdate(updates[0])
+			return append([]byte{byte(wal.OperationUpdate)}, data...)
+		}
+		segments := checkpointSegments(dir, 0, tries)
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithSkipCorrupt(segments))
+
+		_, err := feeder.Update()
+		var gap *mapper.Gap
+		require.ErrorAs(t, err, &gap)
+
+		// The record following the invalid one is not read, as we continue
+		// after the checkpoint instead.
+		got, err := feeder.Update()
+		require.NoError(t, err)
+		assert.Equal(t, updates[3], got)
+	})
+
+	t.Run("handles missing checkpoint", func(t *testing.T) {
+		t.Parallel()
+
+		reader := segmentsReader(t, dir, 0)
+		segments := checkpointSegments(dir, 0, tries)
+		segments.err = dps.ErrUnavailable
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithSkipCorrupt(segments))
+
+		_, err := feeder.Update()
+		require.NoError(t, err)
+
+		_, err = feeder.Update()
+		require.Error(t, err)
+		var gap *mapper.Gap
+		assert.False(t, errors.As(err, &gap))
+	})
+
+	t.Run("handles checkpoint failure", func(t *testing.T) {
+		t.Parallel()
+
+		reader := segmentsReader(t, dir, 0)
+		segments := checkpointSegments(dir, 0, tries)
+		segments.err = mocks.GenericError
+
+		feeder := FromWAL(mocks.NoopLogger, reader, WithSkipCorrupt(segments))
+
+		_, err := feeder.Update()
+		require.NoError(t, err)
+
+		_, err = feeder.Update()
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+}
+
+func BenchmarkFeeder_Update(b *testing.B) {
+
+	// We simulate a slow disk for reading and a consumer that takes about the
+	// same time to process each trie update.
+	delay := 100 * time.Microsecond
+	data := encoding.EncodeTrieUpdate(mocks.GenericTrieUpdate(0))
+	record := append([]byte{byte(wal.OperationUpdate)}, data...)
+	reader := &mocks.WALReader{
+		NextFunc: func() bool {
+			time.Sleep(delay)
+			return true
+		},
+		ErrFunc: func() error {
+			return nil
+		},
+		RecordFunc: func() []byte {
+			return record
+		},
+	}
+
+	b.Run("without read-ahead", func(b *testing.B) {
+		feeder := FromWAL(mocks.NoopLogger, reader)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = feeder.Update()
+			time.Sleep(delay)
+		}
+	})
+
+	b.Run("with read-ahead", func(b *testing.B) {
+		feeder := FromWAL(mocks.NoopLogger, reader, WithReadAhead(64))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = feeder.Update()
+			time.Sleep(delay)
+		}
+	})
+}
+
+// walReader returns a WAL reader mock that returns the given trie updates in
+// order, up to the number of available trie updates, which can be changed by
+// the caller between calls to the feeder.
+func walReader(t *testing.T, updates []*ledger.TrieUpdate) (*mocks.WALReader, *int) {
+	t.Helper()
+
+	var index int
+	available := 0
+
+	reader := mocks.BaselineWALReader(t)
+	reader.NextFunc = func() bool {
+		if index >= available {
+			return false
+		}
+		index++
+		return true
+	}
+	reader.RecordFunc = func() []byte {
+		data := encoding.EncodeTrieUpdate(updates[index-1])
+		return append([]byte{byte(wal.OperationUpdate)}, data...)
+	}
+
+	return reader, &available
+}
+
+// writeSegment writes the given trie updates to a new segment of the
+// write-ahead log in the given directory.
+func writeSegment(t *testing.T, dir string, segment int, updates []*ledger.TrieUpdate) {
+	t.Helper()
+
+	// The write-ahead log appends to the last existing segment, so we create
+	// the segment beforehand.
+	seg, err := tsdb.CreateSegment(dir, segment)
+	require.NoError(t, err)
+	require.NoError(t, seg.Close())
+
+	log, err := tsdb.New(nil, nil, dir)
+	require.NoError(t, err)
+	for _, update := range updates {
+		data := encoding.EncodeTrieUpdate(update)
+		err = log.Log(append([]byte{byte(wal.OperationUpdate)}, data...))
+		require.NoError(t, err)
+	}
+	require.NoError(t, log.Close())
+}
+
+// corruptRecord flips a byte in the payload of the record with the given index
+// in the given segment of the write-ahead log. It assumes that all records of
+// the segment fit into a single page.
+func corruptRecord(t *testing.T, dir string, segment int, record int) {
+	t.Helper()
+
+	name := tsdb.SegmentName(dir, segment)
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+
+	// Each record starts with a header of one byte for the type, two bytes for
+	// the length and four bytes for the checksum.
+	offset := 0
+	for i := 0; i < record; i++ {
+		length := int(data[offset+1])<<8 | int(data[offset+2])
+		offset += 7 + length
+	}
+	data[offset+7] ^= 0xff
+
+	err = os.WriteFile(name, data, 0644)
+	require.NoError(t, err)
+}
+
+// segmentsReader returns a reader for the write-ahead log in the given
+// directory, starting at the given segment.
+func segmentsReader(t *testing.T, dir string, segment int) WALReader {
+	t.Helper()
+
+	reader, err := FromDirectory(dir).Open(segment)
+	require.NoError(t, err)
+
+	return reader
+}
+
+// segments gives access to the segments of a write-ahead log in a directory,
+// along with a single checkpoint that includes the segments up to a given one.
+type segments struct {
+	*Directory
+	checkpoint int
+	tries      []*trie.Trie
+	err        error
+}
+
+// checkpointSegments returns access to the segments in the given directory,
+// with a checkpoint holding the given tries that includes the segments up to
+// the given one.
+func checkpointSegments(dir string, checkpoint int, tries []*trie.Trie) *segments {
+	s := segments{
+		Directory:  FromDirectory(dir),
+		checkpoint: checkpoint,
+		tries:      tries,
+	}
+	return &s
+}
+
+func (s *segments) Checkpoint(segment int) (int, []*trie.Trie, error) {
+	if s.err != nil {
+		return 0, nil, s.err
+	}
+	if segment > s.checkpoint {
+		return 0, nil, dps.ErrUnavailable
+	}
+	return s.checkpoint, s.tries, nil
+}
@@ -19,4 +19,6 @@ type WALReader interface {
 	Next() bool
 	Err() error
 	Record() []byte
+	Segment() int
+	Offset() int64
 }
@@ -0,0 +1,133 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package feeder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tsdb "github.com/prometheus/tsdb/wal"
+
+	"github.com/optakt/flow-dps/ledger/forest"
+	"github.com/optakt/flow-dps/ledger/trie"
+	"github.com/optakt/flow-dps/ledger/wal"
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// checkpointPrefix is the prefix of the file names of the checkpoints that the
+// execution node writes next to the segments of its write-ahead log. It is
+// followed by the number of the last segment included in the checkpoint.
+const checkpointPrefix = "checkpoint."
+
+// Segments represents something that gives access to the segments and the
+// checkpoints of a write-ahead log, so that the feeder can skip corrupt
+// segments and continue from the next checkpoint.
+type Segments interface {
+	Checkpoint(segment int) (int, []*trie.Trie, error)
+	Open(segment int) (WALReader, error)
+}
+
+// Directory gives access to the segments and checkpoints of the write-ahead log
+// in a directory.
+type Directory struct {
+	dir string
+}
+
+// FromDirectory returns access to the segments and checkpoints of the
+// write-ahead log in the given directory.
+func FromDirectory(dir string) *Directory {
+
+	d := Directory{
+		dir: dir,
+	}
+
+	return &d
+}
+
+// Checkpoint loads the tries of the first checkpoint that includes the given
+// segment, and returns them along with the number of the last segment the
+// checkpoint includes. It returns `dps.ErrUnavailable` if there is no such
+// checkpoint.
+func (d *Directory) Checkpoint(segment int) (int, []*trie.Trie, error) {
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not read directory: %w", err)
+	}
+
+	// Checkpoints that are still being written have an additional suffix, so
+	// they fail to parse and are ignored.
+	number := -1
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, checkpointPrefix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(name, checkpointPrefix))
+		if err != nil {
+			continue
+		}
+		if n < segment || (number >= 0 && n >= number) {
+			continue
+		}
+		number = n
+	}
+	if number < 0 {
+		return 0, nil, dps.ErrUnavailable
+	}
+
+	file, err := os.Open(filepath.Join(d.dir, fmt.Sprintf("%s%08d", checkpointPrefix, number)))
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not open checkpoint file: %w", err)
+	}
+	defer file.Close()
+
+	checkpoint, err := wal.ReadCheckpoint(file)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not read checkpoint (checkpoint: %d): %w", number, err)
+	}
+	tries, err := forest.RebuildTries(checkpoint)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not rebuild tries (checkpoint: %d): %w", number, err)
+	}
+
+	return number, tries, nil
+}
+
+// Open opens a reader for the write-ahead log that starts reading at the given
+// segment. It returns `dps.ErrUnavailable` if the segment does not exist yet.
+func (d *Directory) Open(segment int) (WALReader, error) {
+
+	// The segments reader can not be created without any segment, so we check
+	// whether the segment exists first.
+	_, err := os.Stat(tsdb.SegmentName(d.dir, segment))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, dps.ErrUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not check segment: %w", err)
+	}
+
+	segments, err := tsdb.NewSegmentsRangeReader(tsdb.SegmentRange{Dir: d.dir, First: segment, Last: -1})
+	if err != nil {
+		return nil, fmt.Errorf("could not open segments reader: %w", err)
+	}
+
+	return tsdb.NewReader(segments), nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package feeder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestDirectory_Checkpoint(t *testing.T) {
+
+	// The checkpoint files are not valid, so loading them fails, but the error
+	// tells us which one was picked.
+	dir := t.TempDir()
+	for _, name := range []string{"checkpoint.00000001", "checkpoint.00000004", "checkpoint.00000007.tmp", "root.checkpoint"} {
+		err := os.WriteFile(filepath.Join(dir, name), mocks.GenericBytes, 0644)
+		require.NoError(t, err)
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := FromDirectory(dir).Checkpoint(2)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("checkpoint: %d", 4))
+	})
+
+	t.Run("includes checkpoint of same segment", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := FromDirectory(dir).Checkpoint(1)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("checkpoint: %d", 1))
+	})
+
+	t.Run("handles missing checkpoint", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := FromDirectory(dir).Checkpoint(5)
+
+		assert.ErrorIs(t, err, dps.ErrUnavailable)
+	})
+
+	t.Run("handles missing directory", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := FromDirectory(filepath.Join(dir, "missing")).Checkpoint(0)
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, dps.ErrUnavailable)
+	})
+}
+
+func TestDirectory_Open(t *testing.T) {
+	dir := t.TempDir()
+	writeSegment(t, dir, 0, nil)
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		reader, err := FromDirectory(dir).Open(0)
+
+		require.NoError(t, err)
+		assert.NotNil(t, reader)
+	})
+
+	t.Run("handles missing segment", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := FromDirectory(dir).Open(1)
+
+		assert.ErrorIs(t, err, dps.ErrUnavailable)
+	})
+}
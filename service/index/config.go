@@ -16,18 +16,26 @@ package index
 
 import (
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // DefaultConfig is the default configuration for the DPS index.
 var DefaultConfig = Config{
-	ConcurrentTransactions: 16,          // same value as used for batches in badger
-	FlushInterval:          time.Second, // maximum idle time before flushing transaction
+	ConcurrentTransactions: 16,              // same value as used for batches in badger
+	FlushInterval:          time.Second,     // maximum idle time before flushing transaction
+	FlushSize:              0,               // no size-based flushing by default
+	SlowCommit:             5 * time.Second, // commit duration after which we log a warning
+	CommitObserver:         nil,             // no commit latency recording by default
 }
 
 // Config is the configuration of a DPS index.
 type Config struct {
 	ConcurrentTransactions uint
 	FlushInterval          time.Duration
+	FlushSize              uint64
+	SlowCommit             time.Duration
+	CommitObserver         prometheus.Observer
 }
 
 // WithConcurrentTransactions specifies the maximum concurrent transactions
@@ -46,3 +54,30 @@ func WithFlushInterval(interval time.Duration) func(*Config) {
 		cfg.FlushInterval = interval
 	}
 }
+
+// WithFlushSize sets an estimated transaction size in bytes after which we
+// will flush Badger transactions, regardless of the flush interval. This
+// bounds the size of transactions when indexing data comes in bursts. A size
+// of zero disables size-based flushing.
+func WithFlushSize(size uint64) func(*Config) {
+	return func(cfg *Config) {
+		cfg.FlushSize = size
+	}
+}
+
+// WithSlowCommit sets the duration after which a transaction commit is
+// considered slow, which causes a warning to be logged. A duration of zero
+// disables the warning.
+func WithSlowCommit(threshold time.Duration) func(*Config) {
+	return func(cfg *Config) {
+		cfg.SlowCommit = threshold
+	}
+}
+
+// WithCommitObserver sets an observer, such as a Prometheus histogram, which
+// records the duration of each transaction commit in seconds.
+func WithCommitObserver(observer prometheus.Observer) func(*Config) {
+	return func(cfg *Config) {
+		cfg.CommitObserver = observer
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package index
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/rs/zerolog"
+)
+
+// gcDiscardRatio is the ratio of discardable data in a value log file above
+// which Badger rewrites the file during value log garbage collection.
+const gcDiscardRatio = 0.5
+
+// GarbageCollector runs the value log garbage collection of a Badger database
+// at regular intervals, so that long indexing runs do not accumulate value log
+// files that mostly contain outdated data.
+type GarbageCollector struct {
+	log      zerolog.Logger
+	db       *badger.DB
+	interval time.Duration
+	stop     chan struct{}
+	cycles   uint64 // number of completed garbage collection cycles
+}
+
+// NewGarbageCollector creates a new garbage collector that runs the value log
+// garbage collection of the given database once per interval while it is
+// running.
+func NewGarbageCollector(log zerolog.Logger, db *badger.DB, interval time.Duration) *GarbageCollector {
+
+	g := GarbageCollector{
+		log:      log.With().Str("component", "garbage_collector").Logger(),
+		db:       db,
+		interval: interval,
+		stop:     make(chan struct{}),
+		cycles:   0,
+	}
+
+	return &g
+}
+
+// Start runs the garbage collection at regular intervals until the garbage
+// collector is stopped. Failures are logged, so that they do not interrupt
+// indexing.
+func (g *GarbageCollector) Start() error {
+	if g.interval == 0 {
+		<-g.stop
+		return nil
+	}
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		g.collect()
+	}
+}
+
+// Stop stops the regular garbage collection.
+func (g *GarbageCollector) Stop() {
+	close(g.stop)
+}
+
+// collect rewrites value log files until none of them has enough discardable
+// data left, and logs how much space was reclaimed. Badger only updates the
+// size of its files periodically, so the reclaimed space is an estimate that
+// can lag behind by a cycle.
+func (g *GarbageCollector) collect() {
+
+	start := time.Now()
+	_, before := g.db.Size()
+
+	rewrites := 0
+	var err error
+	for {
+		err = g.db.RunValueLogGC(gcDiscardRatio)
+		if err != nil {
+			break
+		}
+		rewrites++
+	}
+	if !errors.Is(err, badger.ErrNoRewrite) {
+		g.log.Warn().Err(err).Msg("could not run value log garbage collection")
+	}
+
+	_, after := g.db.Size()
+	reclaimed := int64(0)
+	if before > after {
+		reclaimed = before - after
+	}
+
+	atomic.AddUint64(&g.cycles, 1)
+
+	g.log.Info().
+		Int("rewrites", rewrites).
+		Int64("reclaimed", reclaimed).
+		Dur("duration", time.Since(start)).
+		Msg("value log garbage collection completed")
+}
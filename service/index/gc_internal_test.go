@@ -0,0 +1,91 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package index
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestGarbageCollector_Start(t *testing.T) {
+
+	// Value log garbage collection is not supported for in-memory databases.
+	setupDB := func(t *testing.T) *badger.DB {
+		t.Helper()
+
+		db, err := badger.Open(badger.DefaultOptions(t.TempDir()).WithLogger(nil))
+		require.NoError(t, err)
+
+		return db
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		db := setupDB(t)
+		defer db.Close()
+
+		gc := NewGarbageCollector(mocks.NoopLogger, db, 10*time.Millisecond)
+
+		done := make(chan error)
+		go func() {
+			done <- gc.Start()
+		}()
+
+		assert.Eventually(t, func() bool {
+			return atomic.LoadUint64(&gc.cycles) >= 2
+		}, time.Second, 5*time.Millisecond)
+
+		gc.Stop()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("garbage collector did not stop")
+		}
+	})
+
+	t.Run("disabled interval", func(t *testing.T) {
+		t.Parallel()
+
+		db := setupDB(t)
+		defer db.Close()
+
+		gc := NewGarbageCollector(mocks.NoopLogger, db, 0)
+
+		done := make(chan error)
+		go func() {
+			done <- gc.Start()
+		}()
+
+		gc.Stop()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("garbage collector did not stop")
+		}
+		assert.Zero(t, atomic.LoadUint64(&gc.cycles))
+	})
+}
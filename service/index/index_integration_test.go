@@ -18,15 +18,26 @@
 package index_test
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/onflow/flow-go/fvm/state"
+	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/index"
 	"github.com/optakt/flow-dps/service/storage"
 	"github.com/optakt/flow-dps/testing/helpers"
@@ -66,6 +77,22 @@ func TestIndex(t *testing.T) {
 		assert.Equal(t, mocks.GenericHeight, got)
 	})
 
+	t.Run("sealed", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		assert.NoError(t, writer.Sealed(mocks.GenericHeight))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		got, err := reader.Sealed()
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got)
+	})
+
 	t.Run("height", func(t *testing.T) {
 		t.Parallel()
 
@@ -137,6 +164,203 @@ func TestIndex(t *testing.T) {
 		assert.ElementsMatch(t, values, got)
 	})
 
+	t.Run("missing data errors", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		assert.NoError(t, writer.Last(mocks.GenericHeight))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		_, err := reader.Header(mocks.GenericHeight)
+		assert.ErrorIs(t, err, dps.ErrHeightNotIndexed)
+
+		_, err = reader.HeightForBlock(mocks.GenericHeader.ID())
+		assert.ErrorIs(t, err, dps.ErrBlockNotFound)
+
+		_, err = reader.Values(mocks.GenericHeight+1, mocks.GenericLedgerPaths(1))
+		assert.ErrorIs(t, err, dps.ErrHeightNotIndexed)
+
+		// All of them are still reported as missing keys.
+		_, err = reader.Header(mocks.GenericHeight)
+		assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+	})
+
+	t.Run("skipped register errors", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		paths := mocks.GenericLedgerPaths(1)
+		payloads := mocks.GenericLedgerPayloads(1)
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		assert.NoError(t, writer.Last(mocks.GenericHeight+1))
+		assert.NoError(t, writer.Payloads(mocks.GenericHeight, paths, payloads))
+		assert.NoError(t, writer.Skipped(mocks.GenericHeight+1, paths[0], mocks.GenericError.Error()))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		_, err := reader.Values(mocks.GenericHeight, paths)
+		assert.NoError(t, err)
+
+		_, err = reader.Values(mocks.GenericHeight+1, paths)
+		assert.ErrorIs(t, err, dps.ErrIncomplete)
+	})
+
+	t.Run("contract code", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		address := mocks.GenericAccount.Address
+		owner := string(address.Bytes())
+		regID := flow.NewRegisterID(owner, owner, state.ContractKey("Test"))
+		path, err := convert.RegisterIDToPath(regID)
+		require.NoError(t, err)
+		key := convert.RegisterIDToKey(regID)
+
+		deployed := []byte("pub contract Test {}")
+		updated := []byte("pub contract Test { pub let value: UInt64 }")
+
+		first := mocks.GenericHeight
+		deploy := first + 10
+		update := first + 20
+		remove := first + 30
+
+		assert.NoError(t, writer.First(first))
+		assert.NoError(t, writer.Last(remove+10))
+		assert.NoError(t, writer.Payloads(deploy, []ledger.Path{path}, []*ledger.Payload{ledger.NewPayload(key, deployed)}))
+		assert.NoError(t, writer.Payloads(update, []ledger.Path{path}, []*ledger.Payload{ledger.NewPayload(key, updated)}))
+		assert.NoError(t, writer.Payloads(remove, []ledger.Path{path}, []*ledger.Payload{ledger.NewPayload(key, nil)}))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		t.Run("not yet deployed", func(t *testing.T) {
+			_, err := reader.ContractCode(address, "Test", deploy-1)
+			assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+		})
+
+		t.Run("deployed", func(t *testing.T) {
+			got, err := reader.ContractCode(address, "Test", deploy)
+			require.NoError(t, err)
+			assert.Equal(t, deployed, got)
+
+			got, err = reader.ContractCode(address, "Test", update-1)
+			require.NoError(t, err)
+			assert.Equal(t, deployed, got)
+		})
+
+		t.Run("updated", func(t *testing.T) {
+			got, err := reader.ContractCode(address, "Test", update)
+			require.NoError(t, err)
+			assert.Equal(t, updated, got)
+		})
+
+		t.Run("removed", func(t *testing.T) {
+			_, err := reader.ContractCode(address, "Test", remove)
+			assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+		})
+
+		t.Run("other contract name", func(t *testing.T) {
+			_, err := reader.ContractCode(address, "Other", update)
+			assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+		})
+	})
+
+	t.Run("contract history", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		address := mocks.GenericAccount.Address
+		owner := string(address.Bytes())
+		regID := flow.NewRegisterID(owner, owner, state.ContractKey("Test"))
+		path, err := convert.RegisterIDToPath(regID)
+		require.NoError(t, err)
+		key := convert.RegisterIDToKey(regID)
+
+		first := mocks.GenericHeight
+		codes := map[uint64][]byte{
+			first + 10: []byte("pub contract Test {}"),
+			first + 20: []byte("pub contract Test { pub let a: UInt64 }"),
+			first + 25: []byte("pub contract Test { pub let a: UInt64 }"), // unchanged
+			first + 30: []byte("pub contract Test { pub let b: UInt64 }"),
+			first + 40: nil, // removed
+			first + 50: []byte("pub contract Test { pub let c: UInt64 }"),
+		}
+
+		assert.NoError(t, writer.First(first))
+		assert.NoError(t, writer.Last(first+60))
+		for height, code := range codes {
+			payload := ledger.NewPayload(key, code)
+			assert.NoError(t, writer.Payloads(height, []ledger.Path{path}, []*ledger.Payload{payload}))
+		}
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		t.Run("all changes with code", func(t *testing.T) {
+			got, err := reader.ContractHistory(address, "Test", 0, 100, true)
+
+			require.NoError(t, err)
+			want := []index.ContractChange{
+				{Height: first + 10, Code: codes[first+10]},
+				{Height: first + 20, Code: codes[first+20]},
+				{Height: first + 30, Code: codes[first+30]},
+				{Height: first + 40, Removed: true},
+				{Height: first + 50, Code: codes[first+50]},
+			}
+			assert.Equal(t, want, got)
+		})
+
+		t.Run("paged changes without code", func(t *testing.T) {
+			var heights []uint64
+			from := uint64(0)
+			for {
+				got, err := reader.ContractHistory(address, "Test", from, 2, false)
+				require.NoError(t, err)
+				if len(got) == 0 {
+					break
+				}
+				assert.LessOrEqual(t, len(got), 2)
+				for _, change := range got {
+					assert.Empty(t, change.Code)
+					heights = append(heights, change.Height)
+				}
+				from = got[len(got)-1].Height + 1
+			}
+
+			assert.Equal(t, []uint64{first + 10, first + 20, first + 30, first + 40, first + 50}, heights)
+		})
+
+		t.Run("starting after unchanged update", func(t *testing.T) {
+			got, err := reader.ContractHistory(address, "Test", first+21, 100, false)
+
+			require.NoError(t, err)
+			require.NotEmpty(t, got)
+			assert.Equal(t, first+30, got[0].Height)
+		})
+
+		t.Run("unknown contract", func(t *testing.T) {
+			got, err := reader.ContractHistory(address, "Other", 0, 100, false)
+
+			require.NoError(t, err)
+			assert.Empty(t, got)
+		})
+
+		t.Run("invalid limit", func(t *testing.T) {
+			_, err := reader.ContractHistory(address, "Test", 0, 0, false)
+
+			assert.Error(t, err)
+		})
+	})
+
 	t.Run("collections", func(t *testing.T) {
 		t.Parallel()
 
@@ -227,6 +451,33 @@ func TestIndex(t *testing.T) {
 			require.NoError(t, err)
 			assert.Equal(t, mocks.GenericHeight, gotTx)
 		})
+
+		t.Run("retrieve heights for transactions", func(t *testing.T) {
+			got, err := reader.HeightsForTransactions(txIDs)
+
+			require.NoError(t, err)
+			require.Len(t, got, len(txIDs))
+			for _, txID := range txIDs {
+				assert.Equal(t, mocks.GenericHeight, got[txID])
+			}
+		})
+
+		t.Run("retrieve heights for transactions with missing ones", func(t *testing.T) {
+			unknown := mocks.GenericTransaction(4).ID()
+			got, err := reader.HeightsForTransactions(append(txIDs, unknown))
+
+			assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+			assert.Contains(t, err.Error(), unknown.String())
+			require.Len(t, got, len(txIDs))
+			assert.NotContains(t, got, unknown)
+		})
+
+		t.Run("retrieve heights for no transactions", func(t *testing.T) {
+			got, err := reader.HeightsForTransactions(nil)
+
+			require.NoError(t, err)
+			assert.Empty(t, got)
+		})
 	})
 
 	t.Run("results", func(t *testing.T) {
@@ -289,6 +540,55 @@ func TestIndex(t *testing.T) {
 		})
 	})
 
+	t.Run("event location", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		// Both transactions emit one event of each type, so that the events
+		// of each transaction are stored under different keys.
+		withdrawalType := mocks.GenericEventType(0)
+		depositType := mocks.GenericEventType(1)
+		txIDs := mocks.GenericTransactionIDs(2)
+		var events []flow.Event
+		for index, txID := range txIDs {
+			events = append(events,
+				flow.Event{Type: withdrawalType, TransactionID: txID, TransactionIndex: uint32(index), EventIndex: 0},
+				flow.Event{Type: depositType, TransactionID: txID, TransactionIndex: uint32(index), EventIndex: 1},
+			)
+		}
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		assert.NoError(t, writer.Last(mocks.GenericHeight))
+		assert.NoError(t, writer.Events(mocks.GenericHeight, events))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		// NOTE: The following subtests should NOT be run in parallel, because of the deferral
+		// to close the database above.
+		t.Run("nominal case", func(t *testing.T) {
+			for eventIndex, want := range []flow.Identifier{txIDs[0], txIDs[0], txIDs[1], txIDs[1]} {
+				got, err := reader.EventLocation(mocks.GenericHeight, uint32(eventIndex))
+
+				require.NoError(t, err)
+				assert.Equal(t, want, got)
+			}
+		})
+
+		t.Run("handles event index out of range", func(t *testing.T) {
+			_, err := reader.EventLocation(mocks.GenericHeight, uint32(len(events)))
+
+			assert.ErrorIs(t, err, dps.ErrEventNotFound)
+		})
+
+		t.Run("handles height without events", func(t *testing.T) {
+			_, err := reader.EventLocation(mocks.GenericHeight+1, 0)
+
+			assert.ErrorIs(t, err, dps.ErrHeightNotIndexed)
+		})
+	})
+
 	t.Run("seals", func(t *testing.T) {
 		t.Parallel()
 
@@ -317,9 +617,428 @@ func TestIndex(t *testing.T) {
 			assert.ElementsMatch(t, got, mocks.GenericSealIDs(4))
 		})
 	})
+
+	t.Run("failure", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		failed := mocks.GenericHeight + 1
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		assert.NoError(t, writer.Last(failed))
+		assert.NoError(t, writer.Events(mocks.GenericHeight, mocks.GenericEvents(2)))
+		assert.NoError(t, writer.Failure(failed, mocks.GenericError.Error()))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		// NOTE: The following subtests should NOT be run in parallel, because of the deferral
+		// to close the database above.
+		t.Run("complete height", func(t *testing.T) {
+			_, err := reader.Events(mocks.GenericHeight)
+
+			assert.NoError(t, err)
+		})
+
+		t.Run("events for failed height", func(t *testing.T) {
+			_, err := reader.Events(failed)
+
+			assert.ErrorIs(t, err, dps.ErrIncomplete)
+		})
+
+		t.Run("transactions for failed height", func(t *testing.T) {
+			_, err := reader.TransactionsByHeight(failed)
+
+			assert.ErrorIs(t, err, dps.ErrIncomplete)
+		})
+
+		t.Run("collections for failed height", func(t *testing.T) {
+			_, err := reader.CollectionsByHeight(failed)
+
+			assert.ErrorIs(t, err, dps.ErrIncomplete)
+		})
+
+		t.Run("list failures", func(t *testing.T) {
+			got, err := reader.Failures()
+
+			require.NoError(t, err)
+			assert.Equal(t, map[uint64]string{failed: mocks.GenericError.Error()}, got)
+		})
+	})
+
+	t.Run("classes", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		skipped := mocks.GenericHeight + 1
+		legacy := mocks.GenericHeight + 2
+		paths := mocks.GenericLedgerPaths(1)
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		assert.NoError(t, writer.Last(legacy))
+		assert.NoError(t, writer.Payloads(mocks.GenericHeight, paths, mocks.GenericLedgerPayloads(1)))
+		assert.NoError(t, writer.Events(skipped, mocks.GenericEvents(2)))
+		assert.NoError(t, writer.Classes(mocks.GenericHeight, dps.ClassesAll))
+		assert.NoError(t, writer.Classes(skipped, dps.ClassesAll&^dps.ClassRegisters))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		// NOTE: The following subtests should NOT be run in parallel, because of the deferral
+		// to close the database above.
+		t.Run("fully indexed height", func(t *testing.T) {
+			classes, err := reader.IndexedClasses(mocks.GenericHeight)
+			require.NoError(t, err)
+			assert.Equal(t, dps.ClassesAll, classes)
+
+			_, err = reader.Values(mocks.GenericHeight, paths)
+			assert.NoError(t, err)
+		})
+
+		t.Run("height with registers skipped", func(t *testing.T) {
+			classes, err := reader.IndexedClasses(skipped)
+			require.NoError(t, err)
+			assert.False(t, classes.Has(dps.ClassRegisters))
+			assert.True(t, classes.Has(dps.ClassEvents))
+
+			_, err = reader.Values(skipped, paths)
+			assert.ErrorIs(t, err, dps.ErrNotIndexed)
+
+			events, err := reader.Events(skipped)
+			require.NoError(t, err)
+			assert.Len(t, events, 2)
+		})
+
+		t.Run("height without recorded classes", func(t *testing.T) {
+			classes, err := reader.IndexedClasses(legacy)
+			require.NoError(t, err)
+			assert.Equal(t, dps.ClassesAll, classes)
+
+			_, err = reader.Values(legacy, paths)
+			assert.NoError(t, err)
+		})
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		complete := mocks.GenericHeight
+		skipped := mocks.GenericHeight + 1
+		failed := mocks.GenericHeight + 2
+		legacy := mocks.GenericHeight + 3
+
+		assert.NoError(t, writer.Classes(complete, dps.ClassesAll))
+		assert.NoError(t, writer.Classes(skipped, dps.ClassesAll&^dps.ClassRegisters))
+		assert.NoError(t, writer.Classes(failed, dps.ClassesAll))
+		assert.NoError(t, writer.Failure(failed, mocks.GenericError.Error()))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		ok, err := reader.Complete(complete, dps.ClassesAll)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = reader.Complete(skipped, dps.ClassesAll)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = reader.Complete(skipped, dps.ClassesAll&^dps.ClassRegisters)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = reader.Complete(failed, dps.ClassesAll)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = reader.Complete(legacy, dps.ClassesAll)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		assert.NoError(t, writer.Last(mocks.GenericHeight))
+		assert.NoError(t, writer.Failure(mocks.GenericHeight, mocks.GenericError.Error()))
+		require.NoError(t, writer.Sync())
+
+		assert.NoError(t, writer.Events(mocks.GenericHeight, mocks.GenericEvents(2)))
+		assert.NoError(t, writer.Recover(mocks.GenericHeight))
+		// Close the writer to make it commit its transactions.
+		require.NoError(t, writer.Close())
+
+		got, err := reader.Failures()
+		require.NoError(t, err)
+		assert.Empty(t, got)
+
+		events, err := reader.Events(mocks.GenericHeight)
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+	})
+
+	t.Run("flush size", func(t *testing.T) {
+		t.Parallel()
+
+		// The flush interval is long enough for it to never elapse during the
+		// test, so only the size threshold can cause the flush.
+		reader, writer, db := setupIndex(t,
+			index.WithFlushInterval(time.Hour),
+			index.WithFlushSize(1),
+		)
+		defer db.Close()
+		defer writer.Close()
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+
+		assert.Eventually(t, func() bool {
+			got, err := reader.First()
+			return err == nil && got == mocks.GenericHeight
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		lib := storage.New(zbor.NewCodec())
+
+		db, err := badger.Open(dps.DefaultOptions(dir))
+		require.NoError(t, err)
+		defer db.Close()
+
+		writer := index.NewWriter(mocks.NoopLogger, db, lib, index.WithFlushInterval(0))
+		defer writer.Close()
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		require.NoError(t, writer.Sync())
+
+		// We copy the database files, like a filesystem snapshot would, and
+		// open the copy as a fresh database. Badger refuses to open databases
+		// that were not closed in read-only mode, as it has to replay the
+		// value log, so the copy is opened in read-write mode instead.
+		snapshot := t.TempDir()
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			require.NoError(t, err)
+			err = os.WriteFile(filepath.Join(snapshot, entry.Name()), data, 0600)
+			require.NoError(t, err)
+		}
+
+		fresh, err := badger.Open(dps.DefaultOptions(snapshot))
+		require.NoError(t, err)
+		defer fresh.Close()
+
+		got, err := index.NewReader(fresh, lib).First()
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got)
+	})
+
+	t.Run("slow commit", func(t *testing.T) {
+		t.Parallel()
+
+		// With a threshold of one nanosecond, every commit is slow.
+		var buf bytes.Buffer
+		log := zerolog.New(&buf)
+
+		var observed []float64
+		observer := prometheus.ObserverFunc(func(seconds float64) {
+			observed = append(observed, seconds)
+		})
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		writer := index.NewWriter(log, db, storage.New(zbor.NewCodec()),
+			index.WithFlushInterval(0),
+			index.WithSlowCommit(time.Nanosecond),
+			index.WithCommitObserver(observer),
+		)
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+		assert.NoError(t, writer.Last(mocks.GenericHeight+1))
+		require.NoError(t, writer.Close())
+
+		assert.Len(t, observed, 1)
+		assert.Contains(t, buf.String(), "slow index transaction commit")
+		assert.Contains(t, buf.String(), fmt.Sprintf(`"first":%d`, mocks.GenericHeight))
+		assert.Contains(t, buf.String(), fmt.Sprintf(`"last":%d`, mocks.GenericHeight+1))
+	})
+
+	t.Run("no flush below size", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t,
+			index.WithFlushInterval(time.Hour),
+			index.WithFlushSize(1_000_000),
+		)
+		defer db.Close()
+		defer writer.Close()
+
+		assert.NoError(t, writer.First(mocks.GenericHeight))
+
+		assert.Never(t, func() bool {
+			_, err := reader.First()
+			return err == nil
+		}, 100*time.Millisecond, 10*time.Millisecond)
+	})
+}
+
+func TestReader_ValidateFirst(t *testing.T) {
+	header := mocks.GenericHeader
+	lowest := mocks.GenericHeight
+	last := mocks.GenericHeight + 2
+
+	setup := func(t *testing.T) (*index.Reader, *badger.DB) {
+		reader, writer, db := setupIndex(t)
+
+		// The first height was overwritten with a height above the lowest
+		// height with indexed data.
+		require.NoError(t, writer.First(lowest+1))
+		require.NoError(t, writer.Last(last))
+		for height := lowest; height <= last; height++ {
+			require.NoError(t, writer.Header(height, header))
+		}
+		require.NoError(t, writer.Close())
+
+		return reader, db
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		reader, db := setup(t)
+		defer db.Close()
+
+		got, err := reader.Lowest()
+		require.NoError(t, err)
+		assert.Equal(t, lowest, got)
+
+		err = reader.ValidateFirst(lowest)
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses height without data", func(t *testing.T) {
+		t.Parallel()
+
+		reader, db := setup(t)
+		defer db.Close()
+
+		err := reader.ValidateFirst(lowest - 1)
+		assert.ErrorIs(t, err, dps.ErrHeightNotIndexed)
+	})
+
+	t.Run("refuses height with data below", func(t *testing.T) {
+		t.Parallel()
+
+		reader, db := setup(t)
+		defer db.Close()
+
+		err := reader.ValidateFirst(lowest + 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("refuses height above last", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		require.NoError(t, writer.Last(lowest-1))
+		require.NoError(t, writer.Header(lowest, header))
+		require.NoError(t, writer.Close())
+
+		err := reader.ValidateFirst(lowest)
+		assert.Error(t, err)
+	})
+
+	t.Run("handles empty index", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+		require.NoError(t, writer.Close())
+
+		_, err := reader.Lowest()
+		assert.ErrorIs(t, err, dps.ErrHeightNotIndexed)
+
+		err = reader.ValidateFirst(lowest)
+		assert.Error(t, err)
+	})
+}
+
+func TestReader_Compatible(t *testing.T) {
+	t.Run("compatible version", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		meta := dps.Metadata{
+			SchemaVersion: dps.SchemaVersion,
+			Dictionary:    zbor.Fingerprint(),
+			ChainID:       dps.FlowTestnet,
+			Created:       time.Now().UTC(),
+		}
+		require.NoError(t, writer.Metadata(meta))
+		require.NoError(t, writer.Close())
+
+		got, err := reader.Metadata()
+		require.NoError(t, err)
+		assert.Equal(t, meta.SchemaVersion, got.SchemaVersion)
+		assert.Equal(t, meta.Dictionary, got.Dictionary)
+		assert.Equal(t, meta.ChainID, got.ChainID)
+
+		err = reader.Compatible()
+		assert.NoError(t, err)
+	})
+
+	t.Run("incompatible version", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+
+		meta := dps.Metadata{
+			SchemaVersion: dps.SchemaVersion + 1,
+			Dictionary:    zbor.Fingerprint(),
+			ChainID:       dps.FlowTestnet,
+			Created:       time.Now().UTC(),
+		}
+		require.NoError(t, writer.Metadata(meta))
+		require.NoError(t, writer.Close())
+
+		err := reader.Compatible()
+		assert.ErrorIs(t, err, dps.ErrIncompatible)
+	})
+
+	t.Run("handles index without metadata", func(t *testing.T) {
+		t.Parallel()
+
+		reader, writer, db := setupIndex(t)
+		defer db.Close()
+		require.NoError(t, writer.Close())
+
+		_, err := reader.Metadata()
+		assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+
+		err = reader.Compatible()
+		assert.NoError(t, err)
+	})
 }
 
-func setupIndex(t *testing.T) (*index.Reader, *index.Writer, *badger.DB) {
+func setupIndex(t *testing.T, options ...func(*index.Config)) (*index.Reader, *index.Writer, *badger.DB) {
 	t.Helper()
 
 	codec := zbor.NewCodec()
@@ -329,7 +1048,8 @@ func setupIndex(t *testing.T) (*index.Reader, *index.Writer, *badger.DB) {
 	lib := storage.New(codec)
 
 	reader := index.NewReader(db, lib)
-	writer := index.NewWriter(db, lib, index.WithConcurrentTransactions(4))
+	options = append([]func(*index.Config){index.WithConcurrentTransactions(4)}, options...)
+	writer := index.NewWriter(mocks.NoopLogger, db, lib, options...)
 
 	return reader, writer, db
 }
@@ -20,6 +20,8 @@ import (
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
 )
 
 // MetricsWriter wraps the writer and records metrics for the data it writes.
@@ -117,6 +119,18 @@ func (w *MetricsWriter) Seals(height uint64, seals []*flow.Seal) error {
 	return w.write.Seals(height, seals)
 }
 
+func (w *MetricsWriter) Failure(height uint64, reason string) error {
+	return w.write.Failure(height, reason)
+}
+
+func (w *MetricsWriter) Skipped(height uint64, path ledger.Path, reason string) error {
+	return w.write.Skipped(height, path, reason)
+}
+
+func (w *MetricsWriter) Classes(height uint64, classes dps.Classes) error {
+	return w.write.Classes(height, classes)
+}
+
 func (w *MetricsWriter) First(height uint64) error {
 	return w.write.First(height)
 }
@@ -125,6 +139,10 @@ func (w *MetricsWriter) Last(height uint64) error {
 	return w.write.Last(height)
 }
 
+func (w *MetricsWriter) Sealed(height uint64) error {
+	return w.write.Sealed(height)
+}
+
 func (w *MetricsWriter) Height(blockID flow.Identifier, height uint64) error {
 	return w.write.Height(blockID, height)
 }
@@ -140,3 +158,15 @@ func (w *MetricsWriter) Guarantees(height uint64, guarantees []*flow.CollectionG
 func (w *MetricsWriter) Results(results []*flow.TransactionResult) error {
 	return w.write.Results(results)
 }
+
+// NewCommitHistogram creates a histogram that records the duration of index
+// transaction commits and exposes it as a prometheus metric. It can be given to
+// the writer using the `WithCommitObserver` option.
+func NewCommitHistogram() prometheus.Histogram {
+	opts := prometheus.HistogramOpts{
+		Name:    "index_commit_duration_seconds",
+		Help:    "duration of index transaction commits",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	}
+	return promauto.NewHistogram(opts)
+}
@@ -15,14 +15,18 @@
 package index
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/dgraph-io/badger/v2"
 
+	"github.com/onflow/flow-go/fvm/state"
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 
+	"github.com/optakt/flow-dps/models/convert"
 	"github.com/optakt/flow-dps/models/dps"
 )
 
@@ -60,6 +64,15 @@ func (r *Reader) Last() (uint64, error) {
 	return height, err
 }
 
+// Sealed returns the height of the last indexed block that was sealed by one of
+// the indexed blocks. As sealing lags behind finalization, it is usually a few
+// blocks below the last indexed height.
+func (r *Reader) Sealed() (uint64, error) {
+	var height uint64
+	err := r.db.View(r.lib.RetrieveSealed(&height))
+	return height, err
+}
+
 // HeightForBlock returns the height for the given block identifier.
 func (r *Reader) HeightForBlock(blockID flow.Identifier) (uint64, error) {
 	var height uint64
@@ -75,6 +88,48 @@ func (r *Reader) Commit(height uint64) (flow.StateCommitment, error) {
 	return commit, err
 }
 
+// IndexedClasses returns which classes of data were indexed for the block at the
+// given height. For heights that were indexed before the classes were recorded,
+// all classes are assumed to be indexed.
+func (r *Reader) IndexedClasses(height uint64) (dps.Classes, error) {
+	var classes dps.Classes
+	err := r.db.View(r.lib.RetrieveClasses(height, &classes))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return dps.ClassesAll, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not retrieve classes: %w", err)
+	}
+	return classes, nil
+}
+
+// Complete returns whether the block at the given height was completely indexed
+// with the given classes of data. Unlike `IndexedClasses`, it does not assume
+// anything for heights without recorded classes; those, as well as heights with
+// a recorded failure, are reported as incomplete.
+func (r *Reader) Complete(height uint64, classes dps.Classes) (bool, error) {
+	var indexed dps.Classes
+	err := r.db.View(r.lib.RetrieveClasses(height, &indexed))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not retrieve classes: %w", err)
+	}
+	if !indexed.Has(classes) {
+		return false, nil
+	}
+	var reason string
+	err = r.db.View(r.lib.RetrieveFailure(height, &reason))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not retrieve failure: %w", err)
+	}
+	return false, nil
+}
+
 // Header returns the header for the finalized block at the given height.
 func (r *Reader) Header(height uint64) (*flow.Header, error) {
 	var header flow.Header
@@ -86,6 +141,8 @@ func (r *Reader) Header(height uint64) (*flow.Header, error) {
 // as they were after the execution of the finalized block at the given height.
 // For compatibility with existing Flow execution node code, a path that is not
 // found within the indexed execution state returns a nil value without error.
+// A path whose payload was skipped while indexing returns an error wrapping
+// `dps.ErrIncomplete`, rather than the value it had before.
 func (r *Reader) Values(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
 	first, err := r.First()
 	if err != nil {
@@ -96,7 +153,11 @@ func (r *Reader) Values(height uint64, paths []ledger.Path) ([]ledger.Value, err
 		return nil, fmt.Errorf("could not check last height: %w", err)
 	}
 	if height < first || height > last {
-		return nil, fmt.Errorf("invalid height (given: %d, first: %d, last: %d)", height, first, last)
+		return nil, fmt.Errorf("invalid height (given: %d, first: %d, last: %d): %w", height, first, last, dps.ErrHeightNotIndexed)
+	}
+	err = r.indexed(height, dps.ClassRegisters)
+	if err != nil {
+		return nil, err
 	}
 	values := make([]ledger.Value, 0, len(paths))
 	err = r.db.View(func(tx *badger.Txn) error {
@@ -117,6 +178,92 @@ func (r *Reader) Values(height uint64, paths []ledger.Path) ([]ledger.Value, err
 	return values, err
 }
 
+// ContractCode returns the code of the contract with the given name on the
+// account with the given address, as it was at the given height. If the
+// contract was not deployed at that height, or had been removed, an error
+// wrapping `badger.ErrKeyNotFound` is returned.
+func (r *Reader) ContractCode(address flow.Address, name string, height uint64) ([]byte, error) {
+
+	path, err := contractPath(address, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not get contract path: %w", err)
+	}
+
+	values, err := r.Values(height, []ledger.Path{path})
+	if err != nil {
+		return nil, fmt.Errorf("could not read contract register: %w", err)
+	}
+
+	// Contracts that were never deployed have no register, while removed ones
+	// have an empty register; both cases are treated as not found.
+	if len(values[0]) == 0 {
+		return nil, fmt.Errorf("could not find contract (address: %s, name: %s): %w", address, name, badger.ErrKeyNotFound)
+	}
+
+	return values[0], nil
+}
+
+// ContractHistory returns the changes to the code of the contract with the
+// given name on the account with the given address, starting at the given
+// height, in ascending order of height. At most `limit` changes are returned;
+// to retrieve the next page, the function should be called again with a start
+// height right above the height of the last returned change. The code of the
+// contract at each change is only included if requested.
+func (r *Reader) ContractHistory(address flow.Address, name string, from uint64, limit uint, code bool) ([]ContractChange, error) {
+
+	if limit == 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	path, err := contractPath(address, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not get contract path: %w", err)
+	}
+
+	var changes []ContractChange
+	err = r.db.View(func(tx *badger.Txn) error {
+
+		// We need the code as it was right before the start height, so that
+		// we only report actual changes to the code.
+		var previous []byte
+		if from > 0 {
+			var payload ledger.Payload
+			err := r.lib.RetrievePayload(from-1, path, &payload)(tx)
+			if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				return fmt.Errorf("could not retrieve previous payload: %w", err)
+			}
+			previous = payload.Value
+		}
+
+		return r.lib.IteratePayloads(path, from, func(height uint64, payload *ledger.Payload) error {
+			if bytes.Equal(payload.Value, previous) {
+				return nil
+			}
+			previous = payload.Value
+
+			change := ContractChange{
+				Height:  height,
+				Removed: len(payload.Value) == 0,
+			}
+			if code {
+				change.Code = payload.Value
+			}
+			changes = append(changes, change)
+
+			if uint(len(changes)) >= limit {
+				return dps.ErrFinished
+			}
+
+			return nil
+		})(tx)
+	})
+	if err != nil && !errors.Is(err, dps.ErrFinished) {
+		return nil, fmt.Errorf("could not iterate contract payloads: %w", err)
+	}
+
+	return changes, nil
+}
+
 // Collection returns the collection with the given ID.
 func (r *Reader) Collection(collID flow.Identifier) (*flow.LightCollection, error) {
 	var collection flow.LightCollection
@@ -126,8 +273,13 @@ func (r *Reader) Collection(collID flow.Identifier) (*flow.LightCollection, erro
 
 // CollectionsByHeight returns the collection IDs at the given height.
 func (r *Reader) CollectionsByHeight(height uint64) ([]flow.Identifier, error) {
+	err := r.complete(height)
+	if err != nil {
+		return nil, err
+	}
+
 	var collIDs []flow.Identifier
-	err := r.db.View(r.lib.LookupCollectionsForHeight(height, &collIDs))
+	err = r.db.View(r.lib.LookupCollectionsForHeight(height, &collIDs))
 	return collIDs, err
 }
 
@@ -153,10 +305,54 @@ func (r *Reader) HeightForTransaction(txID flow.Identifier) (uint64, error) {
 	return height, err
 }
 
+// HeightsForTransactions returns the heights of the blocks within which the
+// given transaction identifiers are, looked up within a single database
+// transaction. If some of the transactions can't be found, the heights of the
+// other transactions are still returned, along with an error listing the
+// missing transactions and wrapping `badger.ErrKeyNotFound`.
+func (r *Reader) HeightsForTransactions(txIDs []flow.Identifier) (map[flow.Identifier]uint64, error) {
+
+	heights := make(map[flow.Identifier]uint64, len(txIDs))
+	var missing []string
+	err := r.db.View(func(tx *badger.Txn) error {
+		for _, txID := range txIDs {
+			var height uint64
+			err := r.lib.LookupHeightForTransaction(txID, &height)(tx)
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				missing = append(missing, txID.String())
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("could not look up height for transaction (tx: %x): %w", txID, err)
+			}
+			heights[txID] = height
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		return heights, fmt.Errorf("could not find heights for transactions (%s): %w", strings.Join(missing, ", "), badger.ErrKeyNotFound)
+	}
+
+	return heights, nil
+}
+
 // TransactionsByHeight returns the transaction IDs within the block with the given ID.
 func (r *Reader) TransactionsByHeight(height uint64) ([]flow.Identifier, error) {
+	err := r.complete(height)
+	if err != nil {
+		return nil, err
+	}
+	err = r.indexed(height, dps.ClassTransactions)
+	if err != nil {
+		return nil, err
+	}
+
 	var txIDs []flow.Identifier
-	err := r.db.View(r.lib.LookupTransactionsForHeight(height, &txIDs))
+	err = r.db.View(r.lib.LookupTransactionsForHeight(height, &txIDs))
 	return txIDs, err
 }
 
@@ -180,7 +376,15 @@ func (r *Reader) Events(height uint64, types ...flow.EventType) ([]flow.Event, e
 		return nil, fmt.Errorf("could not check last height: %w", err)
 	}
 	if height < first || height > last {
-		return nil, fmt.Errorf("invalid height (given: %d, first: %d, last: %d)", height, first, last)
+		return nil, fmt.Errorf("invalid height (given: %d, first: %d, last: %d): %w", height, first, last, dps.ErrHeightNotIndexed)
+	}
+	err = r.complete(height)
+	if err != nil {
+		return nil, err
+	}
+	err = r.indexed(height, dps.ClassEvents)
+	if err != nil {
+		return nil, err
 	}
 
 	var events []flow.Event
@@ -192,6 +396,18 @@ func (r *Reader) Events(height uint64, types ...flow.EventType) ([]flow.Event, e
 	return events, nil
 }
 
+// EventLocation returns the ID of the transaction that emitted the event at the
+// given index within the events of the finalized block at the given height.
+// Events are indexed in the order of their transactions within the block, and
+// then in the order in which they were emitted by each transaction.
+func (r *Reader) EventLocation(height uint64, eventIndex uint32) (flow.Identifier, error) {
+	events, err := r.Events(height)
+	if err != nil {
+		return flow.ZeroID, fmt.Errorf("could not get events: %w", err)
+	}
+	return dps.EventLocation(events, eventIndex)
+}
+
 // Seal returns the seal with the given ID.
 func (r *Reader) Seal(sealID flow.Identifier) (*flow.Seal, error) {
 	var seal flow.Seal
@@ -205,3 +421,122 @@ func (r *Reader) SealsByHeight(height uint64) ([]flow.Identifier, error) {
 	err := r.db.View(r.lib.LookupSealsForHeight(height, &sealIDs))
 	return sealIDs, err
 }
+
+// Failures returns the reason for each height at which the block could not be
+// fully indexed, mapped by height.
+func (r *Reader) Failures() (map[uint64]string, error) {
+	failures := make(map[uint64]string)
+	process := func(height uint64, reason string) error {
+		failures[height] = reason
+		return nil
+	}
+	err := r.db.View(r.lib.IterateFailures(process))
+	if err != nil {
+		return nil, fmt.Errorf("could not iterate failures: %w", err)
+	}
+	return failures, nil
+}
+
+// Metadata returns the metadata record of the index.
+func (r *Reader) Metadata() (dps.Metadata, error) {
+	var meta dps.Metadata
+	err := r.db.View(r.lib.RetrieveMetadata(&meta))
+	return meta, err
+}
+
+// Compatible returns an error wrapping `dps.ErrIncompatible` if the index was
+// written with a schema version that this binary does not support. Indexes
+// that were created before the metadata record was introduced have none, and
+// are considered compatible.
+func (r *Reader) Compatible() error {
+	meta, err := r.Metadata()
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not get metadata: %w", err)
+	}
+	return meta.Compatible()
+}
+
+// Lowest returns the lowest height for which a header was indexed. It can be
+// lower than the first indexed height, for example if the first height was
+// overwritten when resuming indexing.
+func (r *Reader) Lowest() (uint64, error) {
+	var height uint64
+	err := r.db.View(r.lib.RetrieveLowestHeader(&height))
+	return height, err
+}
+
+// ValidateFirst checks whether the given height can be used as the first
+// indexed height. It returns an error if no header was indexed at the given
+// height, if a header was indexed below it, or if it is above the last indexed
+// height.
+func (r *Reader) ValidateFirst(height uint64) error {
+
+	lowest, err := r.Lowest()
+	if err != nil {
+		return fmt.Errorf("could not get lowest indexed height: %w", err)
+	}
+	if height < lowest {
+		return fmt.Errorf("no data indexed at height (height: %d, lowest: %d): %w", height, lowest, dps.ErrHeightNotIndexed)
+	}
+	if height > lowest {
+		return fmt.Errorf("data indexed below height (height: %d, lowest: %d)", height, lowest)
+	}
+
+	last, err := r.Last()
+	if err != nil {
+		return fmt.Errorf("could not get last height: %w", err)
+	}
+	if height > last {
+		return fmt.Errorf("height above last indexed height (height: %d, last: %d)", height, last)
+	}
+
+	return nil
+}
+
+// complete returns an error wrapping `dps.ErrIncomplete` if the block at the
+// given height could not be fully indexed, in which case its execution data is
+// missing from the index.
+func (r *Reader) complete(height uint64) error {
+	var reason string
+	err := r.db.View(r.lib.RetrieveFailure(height, &reason))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not check for indexing failure: %w", err)
+	}
+
+	return fmt.Errorf("block was not fully indexed (height: %d, reason: %s): %w", height, reason, dps.ErrIncomplete)
+}
+
+// indexed returns an error wrapping `dps.ErrNotIndexed` if the given classes of
+// data were skipped when indexing the block at the given height.
+func (r *Reader) indexed(height uint64, classes dps.Classes) error {
+	indexed, err := r.IndexedClasses(height)
+	if err != nil {
+		return fmt.Errorf("could not check indexed classes: %w", err)
+	}
+	if !indexed.Has(classes) {
+		return fmt.Errorf("data was not indexed (height: %d, classes: %05b): %w", height, classes, dps.ErrNotIndexed)
+	}
+	return nil
+}
+
+// contractPath returns the ledger path of the register holding the code of the
+// contract with the given name on the account with the given address.
+func contractPath(address flow.Address, name string) (ledger.Path, error) {
+
+	// Contract code is stored in a register owned and controlled by the
+	// account, keyed by the name of the contract.
+	owner := string(address.Bytes())
+	regID := flow.NewRegisterID(owner, owner, state.ContractKey(name))
+	path, err := convert.RegisterIDToPath(regID)
+	if err != nil {
+		return ledger.Path{}, fmt.Errorf("could not convert register to path: %w", err)
+	}
+
+	return path, nil
+}
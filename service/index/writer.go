@@ -18,11 +18,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/hashicorp/go-multierror"
+	"github.com/rs/zerolog"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/onflow/flow-go/ledger"
@@ -35,6 +37,7 @@ import (
 // an underlying Badger database.
 type Writer struct {
 	sync.RWMutex
+	log  zerolog.Logger
 	db   *badger.DB
 	lib  dps.WriteLibrary
 	cfg  Config
@@ -45,11 +48,23 @@ type Writer struct {
 	done  chan struct{}   // signals when no more new operations will be added
 	mutex *sync.Mutex     // guards the current transaction against concurrent access
 	wg    *sync.WaitGroup // keeps track of when the flush goroutine should exit
+	size  uint64          // estimated size of the current transaction in bytes
+	first uint64          // lowest height of data in the current transaction
+	last  uint64          // highest height of data in the current transaction
 }
 
+// entryOverhead is the estimated size in bytes that each operation adds to a
+// transaction on top of its variable-length data, which accounts for the key
+// and the encoding of fixed-size values. It only needs to be a rough estimate,
+// as it is used to decide when to flush transactions by size.
+const entryOverhead = 64
+
+// noHeight is used when applying operations that are not related to a height.
+const noHeight = math.MaxUint64
+
 // NewWriter creates a new index writer that writes new indexing data to the
 // given Badger database.
-func NewWriter(db *badger.DB, lib dps.WriteLibrary, options ...func(*Config)) *Writer {
+func NewWriter(log zerolog.Logger, db *badger.DB, lib dps.WriteLibrary, options ...func(*Config)) *Writer {
 
 	cfg := DefaultConfig
 	for _, option := range options {
@@ -57,6 +72,7 @@ func NewWriter(db *badger.DB, lib dps.WriteLibrary, options ...func(*Config)) *W
 	}
 
 	w := Writer{
+		log:  log.With().Str("component", "index_writer").Logger(),
 		db:   db,
 		lib:  lib,
 		cfg:  cfg,
@@ -67,6 +83,9 @@ func NewWriter(db *badger.DB, lib dps.WriteLibrary, options ...func(*Config)) *W
 		done:  make(chan struct{}),
 		mutex: &sync.Mutex{},
 		wg:    &sync.WaitGroup{},
+		size:  0,
+		first: noHeight,
+		last:  0,
 	}
 
 	// No flush interval means that flushing is disabled, and we only commit
@@ -81,30 +100,40 @@ func NewWriter(db *badger.DB, lib dps.WriteLibrary, options ...func(*Config)) *W
 	return &w
 }
 
+// Metadata indexes the metadata record of the index.
+func (w *Writer) Metadata(meta dps.Metadata) error {
+	return w.apply(noHeight, 0, w.lib.SaveMetadata(meta))
+}
+
 // First indexes the height of the first finalized block.
 func (w *Writer) First(height uint64) error {
-	return w.apply(w.lib.SaveFirst(height))
+	return w.apply(height, 0, w.lib.SaveFirst(height))
 }
 
 // Last indexes the height of the last finalized block.
 func (w *Writer) Last(height uint64) error {
-	return w.apply(w.lib.SaveLast(height))
+	return w.apply(height, 0, w.lib.SaveLast(height))
+}
+
+// Sealed indexes the height of the last sealed block.
+func (w *Writer) Sealed(height uint64) error {
+	return w.apply(height, 0, w.lib.SaveSealed(height))
 }
 
 // Height indexes the height for the given block ID.
 func (w *Writer) Height(blockID flow.Identifier, height uint64) error {
-	return w.apply(w.lib.IndexHeightForBlock(blockID, height))
+	return w.apply(height, 0, w.lib.IndexHeightForBlock(blockID, height))
 }
 
 // Commit indexes the given commitment of the execution state as it was after
 // the execution of the finalized block at the given height.
 func (w *Writer) Commit(height uint64, commit flow.StateCommitment) error {
-	return w.apply(w.lib.SaveCommit(height, commit))
+	return w.apply(height, 0, w.lib.SaveCommit(height, commit))
 }
 
 // Header indexes the given header of a finalized block at the given height.
 func (w *Writer) Header(height uint64, header *flow.Header) error {
-	return w.apply(w.lib.SaveHeader(height, header))
+	return w.apply(height, 0, w.lib.SaveHeader(height, header))
 }
 
 // Payloads indexes the given payloads, which should represent a trie update
@@ -118,12 +147,14 @@ func (w *Writer) Payloads(height uint64, paths []ledger.Path, payloads []*ledger
 
 	ops := make([]func(*badger.Txn) error, 0, len(payloads))
 
+	size := 0
 	for i, path := range paths {
 		payload := payloads[i]
 		ops = append(ops, w.lib.SavePayload(height, path, payload))
+		size += payload.Size()
 	}
 
-	return w.apply(ops...)
+	return w.apply(height, size, ops...)
 }
 
 // Collections indexes the collections at the given height.
@@ -141,18 +172,18 @@ func (w *Writer) Collections(height uint64, collections []*flow.LightCollection)
 
 	ops = append(ops, w.lib.IndexCollectionsForHeight(height, collIDs))
 
-	return w.apply(ops...)
+	return w.apply(height, 0, ops...)
 }
 
 // Guarantees indexes the guarantees at the given height.
-func (w *Writer) Guarantees(_ uint64, guarantees []*flow.CollectionGuarantee) error {
+func (w *Writer) Guarantees(height uint64, guarantees []*flow.CollectionGuarantee) error {
 
 	ops := make([]func(*badger.Txn) error, 0, len(guarantees))
 	for _, guarantee := range guarantees {
 		ops = append(ops, w.lib.SaveGuarantee(guarantee))
 	}
 
-	return w.apply(ops...)
+	return w.apply(height, 0, ops...)
 }
 
 // Transactions indexes the transactions at the given height.
@@ -160,17 +191,22 @@ func (w *Writer) Transactions(height uint64, transactions []*flow.TransactionBod
 
 	ops := make([]func(*badger.Txn) error, 0, 2*len(transactions)+1)
 
+	size := 0
 	txIDs := make([]flow.Identifier, 0, len(transactions))
 	for _, transaction := range transactions {
 		txID := transaction.ID()
 		txIDs = append(txIDs, txID)
 		ops = append(ops, w.lib.SaveTransaction(transaction))
 		ops = append(ops, w.lib.IndexHeightForTransaction(txID, height))
+		size += len(transaction.Script)
+		for _, argument := range transaction.Arguments {
+			size += len(argument)
+		}
 	}
 
 	ops = append(ops, w.lib.IndexTransactionsForHeight(height, txIDs))
 
-	return w.apply(ops...)
+	return w.apply(height, size, ops...)
 }
 
 // Results indexes the transaction results at the given height.
@@ -178,20 +214,24 @@ func (w *Writer) Results(results []*flow.TransactionResult) error {
 
 	ops := make([]func(*badger.Txn) error, 0, len(results))
 
+	size := 0
 	for _, result := range results {
 		ops = append(ops, w.lib.SaveResult(result))
+		size += len(result.ErrorMessage)
 	}
 
-	return w.apply(ops...)
+	return w.apply(noHeight, size, ops...)
 }
 
 // Events indexes the events, which should represent all events of the finalized
 // block at the given height.
 func (w *Writer) Events(height uint64, events []flow.Event) error {
 
+	size := 0
 	buckets := make(map[flow.EventType][]flow.Event)
 	for _, event := range events {
 		buckets[event.Type] = append(buckets[event.Type], event)
+		size += entryOverhead + len(event.Payload)
 	}
 
 	ops := make([]func(*badger.Txn) error, 0, len(buckets))
@@ -200,7 +240,7 @@ func (w *Writer) Events(height uint64, events []flow.Event) error {
 		ops = append(ops, w.lib.SaveEvents(height, typ, set))
 	}
 
-	return w.apply(ops...)
+	return w.apply(height, size, ops...)
 }
 
 // Seals indexes the seals, which should represent all seals in the finalized
@@ -218,10 +258,42 @@ func (w *Writer) Seals(height uint64, seals []*flow.Seal) error {
 
 	ops = append(ops, w.lib.IndexSealsForHeight(height, sealIDs))
 
-	return w.apply(ops...)
+	return w.apply(height, 0, ops...)
 }
 
-func (w *Writer) apply(ops ...func(*badger.Txn) error) error {
+// Failure records that the block at the given height could not be fully
+// indexed, along with the reason, so that reads for its data can fail with a
+// clear error.
+func (w *Writer) Failure(height uint64, reason string) error {
+	return w.apply(height, len(reason), w.lib.SaveFailure(height, reason))
+}
+
+// Skipped records that the payload of the register at the given path was not
+// indexed for the block at the given height, along with the reason, so that
+// reads of the register from that height on fail with a clear error instead of
+// returning an outdated value.
+func (w *Writer) Skipped(height uint64, path ledger.Path, reason string) error {
+	return w.apply(height, len(reason), w.lib.SaveSkipped(height, path, reason))
+}
+
+// Classes records which classes of data were indexed for the block at the given
+// height, so that reads for data that was skipped can fail with a clear error.
+func (w *Writer) Classes(height uint64, classes dps.Classes) error {
+	return w.apply(height, 0, w.lib.SaveClasses(height, classes))
+}
+
+// Recover removes the recorded failure for the block at the given height, once
+// its execution data has been fully indexed.
+func (w *Writer) Recover(height uint64) error {
+	return w.apply(height, 0, w.lib.DeleteFailure(height))
+}
+
+// apply applies the given operations to the current transaction. The height is
+// the height of the indexed data, or `noHeight` if the data is not related to a
+// height. The size is the estimated size of the variable-length data written
+// by the operations, which is used to flush transactions once they reach the
+// configured size.
+func (w *Writer) apply(height uint64, size int, ops ...func(*badger.Txn) error) error {
 
 	// Before applying an additional operation to the transaction we are
 	// currently building, we want to see if there was an error committing any
@@ -242,20 +314,89 @@ func (w *Writer) apply(ops ...func(*badger.Txn) error) error {
 		w.mutex.Lock()
 		err := op(w.tx)
 		if errors.Is(err, badger.ErrTxnTooBig) {
-			_ = w.sema.Acquire(context.Background(), 1)
-			w.tx.CommitWith(w.committed)
-			w.tx = w.db.NewTransaction(true)
+			w.commit()
 			err = op(w.tx)
 		}
+		w.size += entryOverhead
+		w.track(height)
 		w.mutex.Unlock()
 		if err != nil {
 			return fmt.Errorf("could not apply operation: %w", err)
 		}
 	}
 
+	// If a flush size is configured, we commit the transaction as soon as its
+	// estimated size crosses it, regardless of the flush interval. This bounds
+	// the amount of uncommitted data when operations come in bursts.
+	w.mutex.Lock()
+	w.size += uint64(size)
+	if w.cfg.FlushSize > 0 && w.size >= w.cfg.FlushSize {
+		w.commit()
+	}
+	w.mutex.Unlock()
+
 	return nil
 }
 
+// commit starts committing the current transaction in the background and
+// replaces it with a new one. It should only be called while holding the
+// transaction mutex.
+func (w *Writer) commit() {
+	_ = w.sema.Acquire(context.Background(), 1)
+	start := time.Now()
+	size, first, last := w.size, w.first, w.last
+	w.tx.CommitWith(func(err error) {
+		w.observe(time.Since(start), size, first, last)
+		w.committed(err)
+	})
+	w.renew()
+}
+
+// renew replaces the current transaction with a new one and resets its
+// statistics. It should only be called while holding the transaction mutex.
+func (w *Writer) renew() {
+	w.tx = w.db.NewTransaction(true)
+	w.size = 0
+	w.first = noHeight
+	w.last = 0
+}
+
+// track extends the height range of the current transaction with the given
+// height. It should only be called while holding the transaction mutex.
+func (w *Writer) track(height uint64) {
+	if height == noHeight {
+		return
+	}
+	if height < w.first {
+		w.first = height
+	}
+	if height > w.last {
+		w.last = height
+	}
+}
+
+// observe records the duration of a transaction commit and warns about slow
+// commits, which usually indicate that the database is under pressure, for
+// example because of compaction.
+func (w *Writer) observe(duration time.Duration, size uint64, first uint64, last uint64) {
+
+	if w.cfg.CommitObserver != nil {
+		w.cfg.CommitObserver.Observe(duration.Seconds())
+	}
+
+	if w.cfg.SlowCommit == 0 || duration < w.cfg.SlowCommit {
+		return
+	}
+
+	log := w.log.Warn().
+		Dur("duration", duration).
+		Uint64("size", size)
+	if first <= last {
+		log = log.Uint64("first", first).Uint64("last", last)
+	}
+	log.Msg("slow index transaction commit")
+}
+
 func (w *Writer) committed(err error) {
 
 	// When a transaction is fully committed, we get the result in this
@@ -270,6 +411,50 @@ func (w *Writer) committed(err error) {
 	w.sema.Release(1)
 }
 
+// Sync commits the pending transaction, waits for all in-flight transactions
+// to be committed and syncs the database to disk. It can be used to get a
+// durable and consistent index, for example before taking a snapshot, without
+// closing the writer.
+func (w *Writer) Sync() error {
+
+	// We hold the transaction mutex for the whole duration, so that no new
+	// operations are applied while we are syncing.
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// The pending transaction is committed synchronously, just like when
+	// closing the writer, and replaced by a new one.
+	start := time.Now()
+	err := w.tx.Commit()
+	w.observe(time.Since(start), w.size, w.first, w.last)
+	w.renew()
+	if err != nil {
+		return fmt.Errorf("could not commit pending transaction: %w", err)
+	}
+
+	// Acquiring all semaphore resources means that all in-flight transactions
+	// have been committed; we release them right away to keep going.
+	_ = w.sema.Acquire(context.Background(), int64(w.cfg.ConcurrentTransactions))
+	w.sema.Release(int64(w.cfg.ConcurrentTransactions))
+
+	// Any errors that happened while committing are now in the error channel.
+	var merr *multierror.Error
+	for len(w.err) > 0 {
+		merr = multierror.Append(merr, <-w.err)
+	}
+	err = merr.ErrorOrNil()
+	if err != nil {
+		return fmt.Errorf("could not commit transactions: %w", err)
+	}
+
+	err = w.db.Sync()
+	if err != nil {
+		return fmt.Errorf("could not sync database: %w", err)
+	}
+
+	return nil
+}
+
 // Close closes the writer and commits the pending transaction, if there is one.
 func (w *Writer) Close() error {
 
@@ -285,7 +470,9 @@ func (w *Writer) Close() error {
 	// transaction is properly committed. We assume that we are no longer
 	// applying new operations when we call `Close`, so we can explicitly do so
 	// here, without using the callback.
+	start := time.Now()
 	err := w.tx.Commit()
+	w.observe(time.Since(start), w.size, w.first, w.last)
 	if err != nil {
 		return fmt.Errorf("could not commit final transaction: %w", err)
 	}
@@ -314,9 +501,7 @@ func (w *Writer) flush() {
 
 		case <-ticker.C:
 			w.mutex.Lock()
-			_ = w.sema.Acquire(context.Background(), 1)
-			w.tx.CommitWith(w.committed)
-			w.tx = w.db.NewTransaction(true)
+			w.commit()
 			w.mutex.Unlock()
 
 		case <-w.done:
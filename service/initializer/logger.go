@@ -0,0 +1,57 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package initializer
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Supported log output formats.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Logger creates the logger for a binary, writing to the given writer with
+// the given level and output format. The JSON format produces structured logs
+// meant for machines, while the console format produces human-readable logs
+// meant for local debugging. If the level or the format is invalid, a JSON
+// logger at debug level is returned along with the error, so that the error
+// itself can still be logged.
+func Logger(w io.Writer, level string, format string) (zerolog.Logger, error) {
+
+	zerolog.TimestampFunc = func() time.Time { return time.Now().UTC() }
+	log := zerolog.New(w).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return log, fmt.Errorf("could not parse log level: %w", err)
+	}
+
+	switch format {
+	case FormatJSON:
+	case FormatConsole:
+		out := zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+		log = log.Output(out)
+	default:
+		return log, fmt.Errorf("invalid log format (%s), must be one of %s or %s", format, FormatJSON, FormatConsole)
+	}
+
+	return log.Level(lvl), nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package initializer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/initializer"
+)
+
+func TestLogger(t *testing.T) {
+	t.Run("json format", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		log, err := initializer.Logger(&buf, "info", initializer.FormatJSON)
+		require.NoError(t, err)
+
+		log.Info().Str("key", "value").Msg("message")
+
+		var entry map[string]interface{}
+		err = json.Unmarshal(buf.Bytes(), &entry)
+		require.NoError(t, err)
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "value", entry["key"])
+		assert.Equal(t, "message", entry["message"])
+		assert.Contains(t, entry, "time")
+	})
+
+	t.Run("console format", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		log, err := initializer.Logger(&buf, "info", initializer.FormatConsole)
+		require.NoError(t, err)
+
+		log.Info().Str("key", "value").Msg("message")
+
+		out := buf.String()
+		assert.False(t, json.Valid(buf.Bytes()))
+		assert.Contains(t, out, "INF")
+		assert.Contains(t, out, "message")
+		assert.Contains(t, out, "key=")
+		assert.Contains(t, out, "value")
+	})
+
+	t.Run("applies level", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		log, err := initializer.Logger(&buf, "warn", initializer.FormatJSON)
+		require.NoError(t, err)
+
+		assert.Equal(t, zerolog.WarnLevel, log.GetLevel())
+		log.Info().Msg("message")
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("handles invalid level", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		log, err := initializer.Logger(&buf, "invalid", initializer.FormatJSON)
+		require.Error(t, err)
+
+		log.Error().Err(err).Msg("message")
+		assert.NotZero(t, buf.Len())
+	})
+
+	t.Run("handles invalid format", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		_, err := initializer.Logger(&buf, "info", "invalid")
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package initializer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/onflow/flow-go/cmd/bootstrap/utils"
+	"github.com/onflow/flow-go/crypto"
+)
+
+// NetworkKey loads the private network key of the consensus follower from the
+// given file. If the file does not exist yet, it generates a new key and saves
+// it to the file, so that the follower keeps the same peer-to-peer identity
+// across restarts.
+func NetworkKey(path string) (crypto.PrivateKey, error) {
+
+	// If we already have a key file, decode the hex-encoded key from it.
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not read network key file: %w", err)
+	}
+	if err == nil {
+		encoded, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("could not decode network key hex: %w", err)
+		}
+		key, err := crypto.DecodePrivateKey(crypto.ECDSASecp256k1, encoded)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode network key: %w", err)
+		}
+		return key, nil
+	}
+
+	// Otherwise, generate a new key from a random seed and persist it.
+	seed := make([]byte, crypto.KeyGenSeedMinLenECDSASecp256k1)
+	_, err = rand.Read(seed)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate network key seed: %w", err)
+	}
+	key, err := utils.GenerateUnstakedNetworkingKey(seed)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate network key: %w", err)
+	}
+	data = []byte(hex.EncodeToString(key.Encode()) + "\n")
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not write network key file: %w", err)
+	}
+
+	return key, nil
+}
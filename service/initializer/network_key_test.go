@@ -0,0 +1,63 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package initializer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/initializer"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestNetworkKey(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "network.key")
+
+		generated, err := initializer.NetworkKey(path)
+		require.NoError(t, err)
+		assert.FileExists(t, path)
+
+		loaded, err := initializer.NetworkKey(path)
+		require.NoError(t, err)
+		assert.True(t, generated.Equals(loaded))
+	})
+
+	t.Run("handles invalid key file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "network.key")
+		err := os.WriteFile(path, mocks.GenericBytes, 0600)
+		require.NoError(t, err)
+
+		_, err = initializer.NetworkKey(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("handles unwritable key file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "missing", "network.key")
+
+		_, err := initializer.NetworkKey(path)
+		assert.Error(t, err)
+	})
+}
@@ -69,6 +69,26 @@ func TestProtocolState(t *testing.T) {
 		assert.Equal(t, root, have)
 	})
 
+	t.Run("handles already bootstrapped protocol state", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		err := initializer.ProtocolState(bytes.NewBuffer(data), db)
+		require.NoError(t, err)
+
+		var want uint64
+		require.NoError(t, db.View(operation.RetrieveRootHeight(&want)))
+
+		err = initializer.ProtocolState(bytes.NewBuffer(data), db)
+		assert.NoError(t, err)
+
+		var have uint64
+		assert.NoError(t, db.View(operation.RetrieveRootHeight(&have)))
+		assert.Equal(t, want, have)
+	})
+
 	t.Run("handles invalid snapshot encoding", func(t *testing.T) {
 		t.Parallel()
 
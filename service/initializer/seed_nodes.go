@@ -0,0 +1,61 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package initializer
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	sdk "github.com/onflow/flow-go-sdk/crypto"
+	unstaked "github.com/onflow/flow-go/follower"
+)
+
+// SeedNodes builds the bootstrap information for the seed nodes of the
+// consensus follower from their host addresses and their hex-encoded public
+// network keys, which are matched by position.
+func SeedNodes(addresses []string, keys []string) ([]unstaked.BootstrapNodeInfo, error) {
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("need at least one seed node")
+	}
+	if len(addresses) != len(keys) {
+		return nil, fmt.Errorf("mismatching number of seed node addresses and keys (addresses: %d, keys: %d)", len(addresses), len(keys))
+	}
+
+	nodes := make([]unstaked.BootstrapNodeInfo, 0, len(addresses))
+	for i, address := range addresses {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse seed node address (address: %s): %w", address, err)
+		}
+		number, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse seed node port (port: %s): %w", port, err)
+		}
+		key, err := sdk.DecodePublicKeyHex(sdk.ECDSA_P256, keys[i])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse seed node network public key (key: %s): %w", keys[i], err)
+		}
+		node := unstaked.BootstrapNodeInfo{
+			Host:             host,
+			Port:             uint(number),
+			NetworkPublicKey: key,
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package initializer_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/onflow/flow-go-sdk/crypto"
+
+	"github.com/optakt/flow-dps/service/initializer"
+)
+
+func TestSeedNodes(t *testing.T) {
+	keys := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		seed := make([]byte, sdk.MinSeedLength)
+		seed[0] = byte(i)
+		priv, err := sdk.GeneratePrivateKey(sdk.ECDSA_P256, seed)
+		require.NoError(t, err)
+		keys = append(keys, hex.EncodeToString(priv.PublicKey().Encode()))
+	}
+	addresses := []string{"access-001.onflow.org:9000", "access-002.onflow.org:3569"}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		nodes, err := initializer.SeedNodes(addresses, keys)
+		require.NoError(t, err)
+		require.Len(t, nodes, 2)
+
+		assert.Equal(t, "access-001.onflow.org", nodes[0].Host)
+		assert.Equal(t, uint(9000), nodes[0].Port)
+		assert.Equal(t, keys[0], hex.EncodeToString(nodes[0].NetworkPublicKey.Encode()))
+		assert.Equal(t, "access-002.onflow.org", nodes[1].Host)
+		assert.Equal(t, uint(3569), nodes[1].Port)
+		assert.Equal(t, keys[1], hex.EncodeToString(nodes[1].NetworkPublicKey.Encode()))
+	})
+
+	t.Run("handles missing seed nodes", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := initializer.SeedNodes(nil, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("handles mismatching number of keys", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := initializer.SeedNodes(addresses, keys[:1])
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid address", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := initializer.SeedNodes([]string{"access-001.onflow.org"}, keys[:1])
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid port", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := initializer.SeedNodes([]string{"access-001.onflow.org:99999"}, keys[:1])
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := initializer.SeedNodes(addresses[:1], []string{"cfce845f"})
+		assert.Error(t, err)
+	})
+}
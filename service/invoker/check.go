@@ -0,0 +1,67 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package invoker
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser2"
+)
+
+// check statically inspects the given script and returns an error wrapping
+// `ErrScriptTooComplex` if it exceeds any of the configured limits. It allows
+// rejecting obviously expensive scripts before handing them to the runtime.
+func (i *Invoker) check(script []byte) error {
+
+	// The size check is cheap, so we do it before parsing the script.
+	if i.cfg.MaxScriptSize > 0 && uint(len(script)) > i.cfg.MaxScriptSize {
+		return fmt.Errorf("%w: script size %d exceeds limit of %d bytes", ErrScriptTooComplex, len(script), i.cfg.MaxScriptSize)
+	}
+
+	// If there are no limits on the contents of the script, we don't need to
+	// parse it at all.
+	if i.cfg.MaxImports == 0 && i.cfg.MaxLoops == 0 {
+		return nil
+	}
+
+	program, err := parser2.ParseProgram(string(script))
+	if err != nil {
+		return fmt.Errorf("could not parse script: %w", err)
+	}
+
+	imports := uint(len(program.ImportDeclarations()))
+	if i.cfg.MaxImports > 0 && imports > i.cfg.MaxImports {
+		return fmt.Errorf("%w: %d imports exceed limit of %d", ErrScriptTooComplex, imports, i.cfg.MaxImports)
+	}
+
+	// Count all loop constructs, including nested ones, by walking the syntax
+	// tree of every declaration of the program.
+	loops := uint(0)
+	ast.Inspect(program, func(element ast.Element) bool {
+		switch element.(type) {
+		case nil:
+			return false
+		case *ast.WhileStatement, *ast.ForStatement:
+			loops++
+		}
+		return true
+	})
+	if i.cfg.MaxLoops > 0 && loops > i.cfg.MaxLoops {
+		return fmt.Errorf("%w: %d loops exceed limit of %d", ErrScriptTooComplex, loops, i.cfg.MaxLoops)
+	}
+
+	return nil
+}
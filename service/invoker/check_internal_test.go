@@ -0,0 +1,105 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package invoker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoker_Check(t *testing.T) {
+	const script = `
+import FungibleToken from 0xf233dcee88fe0abe
+import FlowToken from 0x1654653399040a61
+
+pub fun main(values: [UInt64]): UInt64 {
+	var sum: UInt64 = 0
+	for value in values {
+		var i: UInt64 = 0
+		while i < value {
+			sum = sum + 1
+			i = i + 1
+		}
+	}
+	return sum
+}
+`
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		checkErr   require.ErrorAssertionFunc
+		tooComplex bool
+	}{
+		{
+			name:     "unrestricted",
+			cfg:      Config{},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "within all limits",
+			cfg:      Config{MaxScriptSize: 1000, MaxImports: 2, MaxLoops: 2},
+			checkErr: require.NoError,
+		},
+		{
+			name:       "exceeds size limit",
+			cfg:        Config{MaxScriptSize: 100},
+			checkErr:   require.Error,
+			tooComplex: true,
+		},
+		{
+			name:       "exceeds imports limit",
+			cfg:        Config{MaxImports: 1},
+			checkErr:   require.Error,
+			tooComplex: true,
+		},
+		{
+			name:       "exceeds loops limit",
+			cfg:        Config{MaxLoops: 1},
+			checkErr:   require.Error,
+			tooComplex: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			invoke := baselineInvoker(t)
+			invoke.cfg = test.cfg
+
+			err := invoke.check([]byte(script))
+
+			test.checkErr(t, err)
+			assert.Equal(t, test.tooComplex, errors.Is(err, ErrScriptTooComplex))
+		})
+	}
+
+	t.Run("handles invalid script", func(t *testing.T) {
+		t.Parallel()
+
+		invoke := baselineInvoker(t)
+		invoke.cfg = Config{MaxLoops: 1}
+
+		err := invoke.check([]byte("pub fun main( {"))
+
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrScriptTooComplex)
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package invoker
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/encoding/json"
+)
+
+// Result is the result of executing a script at a specific height, as part of
+// a comparison across multiple heights.
+type Result struct {
+	Height  uint64
+	Value   cadence.Value
+	Changed bool
+}
+
+// Compare executes the given Cadence script at each of the given heights and
+// returns the results in the same order. Each result is flagged as changed if
+// its value differs from the value at the previous height in the list.
+func (i *Invoker) Compare(heights []uint64, script []byte, arguments []cadence.Value) ([]Result, error) {
+
+	results := make([]Result, 0, len(heights))
+	var previous []byte
+	for index, height := range heights {
+
+		value, err := i.Script(height, script, arguments)
+		if err != nil {
+			return nil, fmt.Errorf("could not execute script at height %d: %w", height, err)
+		}
+
+		// We compare the JSON encoding of the values, as Cadence values can
+		// not generally be compared for equality directly.
+		encoded, err := json.Encode(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode value at height %d: %w", height, err)
+		}
+
+		result := Result{
+			Height:  height,
+			Value:   value,
+			Changed: index > 0 && !bytes.Equal(encoded, previous),
+		}
+		results = append(results, result)
+
+		previous = encoded
+	}
+
+	return results, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package invoker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go/fvm"
+	"github.com/onflow/flow-go/fvm/programs"
+	"github.com/onflow/flow-go/fvm/state"
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestInvoker_Compare(t *testing.T) {
+	// The script returns a value that changes at heights 20 and 40.
+	values := map[uint64]cadence.Value{
+		10: cadence.NewUInt64(1),
+		15: cadence.NewUInt64(1),
+		20: cadence.NewUInt64(2),
+		30: cadence.NewUInt64(2),
+		40: cadence.NewUInt64(3),
+	}
+
+	comparingInvoker := func(t *testing.T) *Invoker {
+		t.Helper()
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			header := *mocks.GenericHeader
+			header.Height = height
+			return &header, nil
+		}
+
+		vm := mocks.BaselineVirtualMachine(t)
+		vm.RunFunc = func(ctx fvm.Context, proc fvm.Procedure, _ state.View, _ *programs.Programs) error {
+			require.IsType(t, proc, &fvm.ScriptProcedure{})
+			p := proc.(*fvm.ScriptProcedure)
+			p.Value = values[ctx.BlockHeader.Height]
+			return nil
+		}
+
+		invoke := baselineInvoker(t)
+		invoke.index = index
+		invoke.vm = vm
+
+		return invoke
+	}
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		invoke := comparingInvoker(t)
+
+		results, err := invoke.Compare([]uint64{10, 15, 20, 30, 40}, mocks.GenericBytes, []cadence.Value{})
+
+		require.NoError(t, err)
+		want := []Result{
+			{Height: 10, Value: cadence.NewUInt64(1), Changed: false},
+			{Height: 15, Value: cadence.NewUInt64(1), Changed: false},
+			{Height: 20, Value: cadence.NewUInt64(2), Changed: true},
+			{Height: 30, Value: cadence.NewUInt64(2), Changed: false},
+			{Height: 40, Value: cadence.NewUInt64(3), Changed: true},
+		}
+		assert.Equal(t, want, results)
+	})
+
+	t.Run("compares heights in given order", func(t *testing.T) {
+		t.Parallel()
+
+		invoke := comparingInvoker(t)
+
+		results, err := invoke.Compare([]uint64{30, 20, 10}, mocks.GenericBytes, []cadence.Value{})
+
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.False(t, results[0].Changed)
+		assert.False(t, results[1].Changed)
+		assert.True(t, results[2].Changed)
+	})
+
+	t.Run("handles no heights", func(t *testing.T) {
+		t.Parallel()
+
+		invoke := comparingInvoker(t)
+
+		results, err := invoke.Compare(nil, mocks.GenericBytes, []cadence.Value{})
+
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("handles script failure", func(t *testing.T) {
+		t.Parallel()
+
+		invoke := comparingInvoker(t)
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(uint64) (*flow.Header, error) {
+			return nil, mocks.GenericError
+		}
+		invoke.index = index
+
+		_, err := invoke.Compare([]uint64{10, 20}, mocks.GenericBytes, []cadence.Value{})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("reads each register from index once per height", func(t *testing.T) {
+		t.Parallel()
+
+		invoke := comparingInvoker(t)
+
+		reads := make(map[uint64]int)
+		index := invoke.index.(*mocks.Reader)
+		index.ValuesFunc = func(height uint64, paths []ledger.Path) ([]ledger.Value, error) {
+			reads[height] += len(paths)
+			return mocks.GenericLedgerValues(len(paths)), nil
+		}
+
+		cached := make(map[interface{}]interface{})
+		cache := mocks.BaselineCache(t)
+		cache.GetFunc = func(key interface{}) (interface{}, bool) {
+			value, ok := cached[key]
+			return value, ok
+		}
+		cache.SetFunc = func(key interface{}, value interface{}, _ int64) bool {
+			cached[key] = value
+			return true
+		}
+		invoke.cache = cache
+
+		// The script reads the same register twice, and the register is the
+		// same at both heights.
+		vm := invoke.vm.(*mocks.VirtualMachine)
+		run := vm.RunFunc
+		vm.RunFunc = func(ctx fvm.Context, proc fvm.Procedure, view state.View, programs *programs.Programs) error {
+			for i := 0; i < 2; i++ {
+				_, err := view.Get("owner", "controller", "key")
+				require.NoError(t, err)
+			}
+			return run(ctx, proc, view, programs)
+		}
+
+		_, err := invoke.Compare([]uint64{10, 20}, mocks.GenericBytes, []cadence.Value{})
+		require.NoError(t, err)
+
+		// Register values are cached per height, so an unchanged register is
+		// still read once at each height.
+		assert.Equal(t, map[uint64]int{10: 1, 20: 1}, reads)
+
+		// Comparing the same heights again only reads from the cache.
+		_, err = invoke.Compare([]uint64{10, 20}, mocks.GenericBytes, []cadence.Value{})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[uint64]int{10: 1, 20: 1}, reads)
+	})
+}
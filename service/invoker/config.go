@@ -14,9 +14,24 @@
 
 package invoker
 
+import (
+	"time"
+)
+
 // Config is the configuration for an invoker.
 type Config struct {
-	CacheSize uint64
+	CacheSize     uint64
+	MaxScriptSize uint
+	MaxImports    uint
+	MaxLoops      uint
+	ReadRetries   uint
+	ReadBackoff   time.Duration
+
+	// runtimes holds the virtual machines used from given heights onwards.
+	// They can not be configured from outside of the package yet, because the
+	// flow-go release this module depends on links a single version of the
+	// Cadence runtime, so there is no second runtime to select.
+	runtimes map[uint64]VirtualMachine
 }
 
 // WithCacheSize specifies the size of the cache the invoker uses.
@@ -25,3 +40,50 @@ func WithCacheSize(size uint64) func(*Config) {
 		cfg.CacheSize = size
 	}
 }
+
+// withRuntime specifies a virtual machine to be used for scripts and account
+// lookups from the given height onwards, until the start height of the next
+// configured runtime. Heights below the lowest configured start height are
+// handled by the default virtual machine.
+func withRuntime(height uint64, vm VirtualMachine) func(*Config) {
+	return func(cfg *Config) {
+		cfg.runtimes[height] = vm
+	}
+}
+
+// WithMaxScriptSize specifies the maximum size in bytes of scripts that the
+// invoker accepts. Zero means that the size is unlimited.
+func WithMaxScriptSize(size uint) func(*Config) {
+	return func(cfg *Config) {
+		cfg.MaxScriptSize = size
+	}
+}
+
+// WithMaxImports specifies the maximum number of import declarations of scripts
+// that the invoker accepts. Zero means that the number is unlimited.
+func WithMaxImports(imports uint) func(*Config) {
+	return func(cfg *Config) {
+		cfg.MaxImports = imports
+	}
+}
+
+// WithMaxLoops specifies the maximum number of loop constructs, including nested
+// ones, of scripts that the invoker accepts. Zero means that the number is
+// unlimited.
+func WithMaxLoops(loops uint) func(*Config) {
+	return func(cfg *Config) {
+		cfg.MaxLoops = loops
+	}
+}
+
+// WithReadRetries specifies the maximum number of times a register read is
+// retried when it times out or is aborted by a remote index, and the base
+// duration of the exponential backoff between retries. Unavailable and
+// overloaded remote indexes are already retried by the API connection, and
+// errors of a local index are never retried. Zero retries disables retrying.
+func WithReadRetries(max uint, backoff time.Duration) func(*Config) {
+	return func(cfg *Config) {
+		cfg.ReadRetries = max
+		cfg.ReadBackoff = backoff
+	}
+}
@@ -16,6 +16,8 @@ package invoker
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/dgraph-io/ristretto"
 	"github.com/rs/zerolog"
@@ -33,9 +35,17 @@ import (
 // Invoker retrieves account information from and executes Cadence scripts against
 // the Flow virtual machine.
 type Invoker struct {
-	index dps.Reader
-	vm    VirtualMachine
-	cache Cache
+	cfg      Config
+	index    dps.Reader
+	vm       VirtualMachine
+	runtimes []runtime
+	cache    Cache
+}
+
+// runtime is a virtual machine that is used starting at a given height.
+type runtime struct {
+	height uint64
+	vm     VirtualMachine
 }
 
 // New returns a new Invoker with the given configuration.
@@ -43,7 +53,10 @@ func New(index dps.Reader, options ...func(*Config)) (*Invoker, error) {
 
 	// Initialize the invoker configuration with conservative default values.
 	cfg := Config{
-		CacheSize: uint64(100_000_000), // ~100 MB default size
+		CacheSize:   uint64(100_000_000), // ~100 MB default size
+		ReadRetries: 0,
+		ReadBackoff: 100 * time.Millisecond,
+		runtimes:    make(map[uint64]VirtualMachine),
 	}
 
 	// Apply the option parameters provided by consumer.
@@ -67,10 +80,22 @@ func New(index dps.Reader, options ...func(*Config)) (*Invoker, error) {
 		return nil, fmt.Errorf("could not initialize cache: %w", err)
 	}
 
+	// Sort the height-specific runtimes by their start height, so that we can
+	// look up the runtime for a given height with a binary search.
+	runtimes := make([]runtime, 0, len(cfg.runtimes))
+	for height, machine := range cfg.runtimes {
+		runtimes = append(runtimes, runtime{height: height, vm: machine})
+	}
+	sort.Slice(runtimes, func(i int, j int) bool {
+		return runtimes[i].height < runtimes[j].height
+	})
+
 	i := Invoker{
-		index: index,
-		vm:    vm,
-		cache: cache,
+		cfg:      cfg,
+		index:    index,
+		vm:       vm,
+		runtimes: runtimes,
+		cache:    cache,
 	}
 
 	return &i, nil
@@ -118,13 +143,13 @@ func (i *Invoker) Account(height uint64, address flow.Address) (*flow.Account, e
 	// here. It's a smart cache, which means that items that are accessed often
 	// are more likely to be kept, regardless of height. This allows us to put
 	// an upper bound on total cache size while using it for all heights.
-	read := readRegister(i.index, i.cache, header.Height)
+	read := readRegister(i.index, i.cache, header.Height, i.cfg.ReadRetries, i.cfg.ReadBackoff)
 
 	// Initialize the view of the execution state on top of the ledger by
 	// using the read function at a specific commit.
 	view := delta.NewView(read)
 
-	account, err := i.vm.GetAccount(ctx, address, view, programs.NewEmptyPrograms())
+	account, err := i.runtime(header.Height).GetAccount(ctx, address, view, programs.NewEmptyPrograms())
 	if err != nil {
 		return nil, fmt.Errorf("could not get account at height %d: %w", header.Height, err)
 	}
@@ -135,6 +160,13 @@ func (i *Invoker) Account(height uint64, address flow.Address) (*flow.Account, e
 // Script executes the given Cadence script and returns its result.
 func (i *Invoker) Script(height uint64, script []byte, arguments []cadence.Value) (cadence.Value, error) {
 
+	// Reject scripts that exceed the configured complexity limits before we
+	// spend any resources on executing them.
+	err := i.check(script)
+	if err != nil {
+		return nil, fmt.Errorf("could not check script: %w", err)
+	}
+
 	// Encode the arguments from Cadence values to byte slices.
 	var args [][]byte
 	for _, argument := range arguments {
@@ -159,7 +191,7 @@ func (i *Invoker) Script(height uint64, script []byte, arguments []cadence.Value
 	// here. It's a smart cache, which means that items that are accessed often
 	// are more likely to be kept, regardless of height. This allows us to put
 	// an upper bound on total cache size while using it for all heights.
-	read := readRegister(i.index, i.cache, height)
+	read := readRegister(i.index, i.cache, height, i.cfg.ReadRetries, i.cfg.ReadBackoff)
 
 	// Initialize the view of the execution state on top of the ledger by
 	// using the read function at a specific commit.
@@ -174,7 +206,7 @@ func (i *Invoker) Script(height uint64, script []byte, arguments []cadence.Value
 
 	// The script procedure is then run using the Flow virtual machine and all
 	// the constructed contextual parameters.
-	err = i.vm.Run(ctx, proc, view, programs)
+	err = i.runtime(height).Run(ctx, proc, view, programs)
 	if err != nil {
 		return nil, fmt.Errorf("could not run script: %w", err)
 	}
@@ -184,3 +216,16 @@ func (i *Invoker) Script(height uint64, script []byte, arguments []cadence.Value
 
 	return proc.Value, nil
 }
+
+// runtime returns the virtual machine to use at the given height. It is the
+// runtime with the highest start height at or below the given height, or the
+// default virtual machine if there is no such runtime.
+func (i *Invoker) runtime(height uint64) VirtualMachine {
+	index := sort.Search(len(i.runtimes), func(index int) bool {
+		return i.runtimes[index].height > height
+	})
+	if index == 0 {
+		return i.vm
+	}
+	return i.runtimes[index-1].vm
+}
@@ -16,6 +16,7 @@ package invoker
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,6 +46,23 @@ func TestNew(t *testing.T) {
 		assert.NotNil(t, invoke.vm)
 	})
 
+	t.Run("sorts runtimes by height", func(t *testing.T) {
+		t.Parallel()
+
+		index := mocks.BaselineReader(t)
+		first := mocks.BaselineVirtualMachine(t)
+		second := mocks.BaselineVirtualMachine(t)
+
+		invoke, err := New(index, withRuntime(200, second), withRuntime(100, first))
+
+		require.NoError(t, err)
+		require.Len(t, invoke.runtimes, 2)
+		assert.Equal(t, uint64(100), invoke.runtimes[0].height)
+		assert.Same(t, first, invoke.runtimes[0].vm)
+		assert.Equal(t, uint64(200), invoke.runtimes[1].height)
+		assert.Same(t, second, invoke.runtimes[1].vm)
+	})
+
 	t.Run("handles invalid cache configuration", func(t *testing.T) {
 		t.Parallel()
 
@@ -97,6 +115,57 @@ func TestInvoker_Script(t *testing.T) {
 		assert.Equal(t, testValue, val)
 	})
 
+	t.Run("provides block header of height to script", func(t *testing.T) {
+		t.Parallel()
+
+		header := *mocks.GenericHeader
+		header.Height = mocks.GenericHeight + 1
+		header.Timestamp = time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+		index := mocks.BaselineReader(t)
+		index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+			assert.Equal(t, header.Height, height)
+
+			return &header, nil
+		}
+
+		vm := mocks.BaselineVirtualMachine(t)
+		vm.RunFunc = func(ctx fvm.Context, proc fvm.Procedure, _ state.View, _ *programs.Programs) error {
+			require.NotNil(t, ctx.BlockHeader)
+			assert.Equal(t, header.ID(), ctx.BlockHeader.ID())
+			assert.Equal(t, header.Height, ctx.BlockHeader.Height)
+			assert.Equal(t, header.Timestamp, ctx.BlockHeader.Timestamp)
+
+			return nil
+		}
+
+		invoke := baselineInvoker(t)
+		invoke.index = index
+		invoke.vm = vm
+
+		_, err := invoke.Script(header.Height, mocks.GenericBytes, []cadence.Value{})
+
+		require.NoError(t, err)
+	})
+
+	t.Run("handles script exceeding limits", func(t *testing.T) {
+		t.Parallel()
+
+		vm := mocks.BaselineVirtualMachine(t)
+		vm.RunFunc = func(fvm.Context, fvm.Procedure, state.View, *programs.Programs) error {
+			t.Fail()
+			return nil
+		}
+
+		invoke := baselineInvoker(t)
+		invoke.vm = vm
+		invoke.cfg.MaxScriptSize = uint(len(mocks.GenericBytes) - 1)
+
+		_, err := invoke.Script(mocks.GenericHeight, mocks.GenericBytes, []cadence.Value{})
+
+		assert.ErrorIs(t, err, ErrScriptTooComplex)
+	})
+
 	t.Run("handles indexer failure on Header", func(t *testing.T) {
 		t.Parallel()
 
@@ -150,6 +219,59 @@ func TestInvoker_Script(t *testing.T) {
 	})
 }
 
+func TestInvoker_ScriptRuntimes(t *testing.T) {
+	runtimeVM := func(t *testing.T, value uint64) *mocks.VirtualMachine {
+		vm := mocks.BaselineVirtualMachine(t)
+		vm.RunFunc = func(_ fvm.Context, proc fvm.Procedure, _ state.View, _ *programs.Programs) error {
+			require.IsType(t, proc, &fvm.ScriptProcedure{})
+			p := proc.(*fvm.ScriptProcedure)
+			p.Value = cadence.NewUInt64(value)
+
+			return nil
+		}
+		return vm
+	}
+
+	tests := []struct {
+		name   string
+		height uint64
+		want   cadence.Value
+	}{
+		{name: "default runtime below first start height", height: 99, want: cadence.NewUInt64(0)},
+		{name: "first runtime at its start height", height: 100, want: cadence.NewUInt64(1)},
+		{name: "first runtime before second start height", height: 199, want: cadence.NewUInt64(1)},
+		{name: "second runtime at its start height", height: 200, want: cadence.NewUInt64(2)},
+		{name: "second runtime above its start height", height: 1000, want: cadence.NewUInt64(2)},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			index := mocks.BaselineReader(t)
+			index.HeaderFunc = func(height uint64) (*flow.Header, error) {
+				header := *mocks.GenericHeader
+				header.Height = height
+				return &header, nil
+			}
+
+			invoke := baselineInvoker(t)
+			invoke.index = index
+			invoke.vm = runtimeVM(t, 0)
+			invoke.runtimes = []runtime{
+				{height: 100, vm: runtimeVM(t, 1)},
+				{height: 200, vm: runtimeVM(t, 2)},
+			}
+
+			val, err := invoke.Script(test.height, mocks.GenericBytes, []cadence.Value{})
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, val)
+		})
+	}
+}
+
 func TestInvoker_Account(t *testing.T) {
 	t.Run("nominal case", func(t *testing.T) {
 		t.Parallel()
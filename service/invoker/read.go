@@ -15,19 +15,25 @@
 package invoker
 
 import (
+	"errors"
 	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
-	"github.com/onflow/flow-go/engine/execution/state"
 	"github.com/onflow/flow-go/engine/execution/state/delta"
 	"github.com/onflow/flow-go/ledger"
-	"github.com/onflow/flow-go/ledger/common/pathfinder"
-	"github.com/onflow/flow-go/ledger/complete"
 	"github.com/onflow/flow-go/model/flow"
 
+	"github.com/optakt/flow-dps/models/convert"
 	"github.com/optakt/flow-dps/models/dps"
 )
 
-func readRegister(index dps.Reader, cache Cache, height uint64) delta.GetRegisterFunc {
+// maxReadDelay is the maximum delay between retries of a register read.
+const maxReadDelay = 5 * time.Second
+
+func readRegister(index dps.Reader, cache Cache, height uint64, retries uint, backoff time.Duration) delta.GetRegisterFunc {
 	return func(owner string, controller string, key string) (flow.RegisterValue, error) {
 
 		cacheKey := fmt.Sprintf("%d/%x/%x/%s", height, owner, controller, key)
@@ -37,14 +43,25 @@ func readRegister(index dps.Reader, cache Cache, height uint64) delta.GetRegiste
 		}
 
 		regID := flow.NewRegisterID(owner, controller, key)
-		path, err := pathfinder.KeyToPath(state.RegisterIDToKey(regID), complete.DefaultPathFinderVersion)
+		path, err := convert.RegisterIDToPath(regID)
 		if err != nil {
-			return nil, fmt.Errorf("could not convert key to path: %w", err)
+			return nil, fmt.Errorf("could not convert register to path: %w", err)
 		}
 
-		values, err := index.Values(height, []ledger.Path{path})
-		if err != nil {
-			return nil, fmt.Errorf("could not read register: %w", err)
+		// Reads against a remote index can fail transiently, in which case we
+		// retry with a capped exponential backoff instead of failing the
+		// script. The height is bound to the read function, so a retried read
+		// always returns the value at the same height.
+		var values []ledger.Value
+		for attempt := uint(0); ; attempt++ {
+			values, err = index.Values(height, []ledger.Path{path})
+			if err == nil {
+				break
+			}
+			if attempt >= retries || !retryable(err) {
+				return nil, fmt.Errorf("could not read register (attempts: %d): %w", attempt+1, err)
+			}
+			time.Sleep(readDelay(backoff, attempt))
 		}
 
 		value := flow.RegisterValue(values[0])
@@ -53,3 +70,34 @@ func readRegister(index dps.Reader, cache Cache, height uint64) delta.GetRegiste
 		return value, nil
 	}
 }
+
+// readDelay returns the delay before retrying a register read after the given
+// number of failed attempts, which doubles with each attempt, up to a maximum.
+func readDelay(backoff time.Duration, attempt uint) time.Duration {
+	delay := backoff << attempt
+	if delay > maxReadDelay || delay>>attempt != backoff {
+		return maxReadDelay
+	}
+	return delay
+}
+
+// retryable returns whether the given error of a register read is transient
+// and not already retried by the connection to a remote index. The retry
+// interceptor of API connections retries requests that fail because the API is
+// unavailable or overloaded, so retrying those here as well would multiply the
+// number of attempts. Errors without a gRPC status are never considered
+// transient.
+func retryable(err error) bool {
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.DeadlineExceeded, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
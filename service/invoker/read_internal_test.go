@@ -15,10 +15,14 @@
 package invoker
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/optakt/flow-dps/testing/mocks"
@@ -45,7 +49,7 @@ func TestReadRegister(t *testing.T) {
 			return nil, nil
 		}
 
-		readFunc := readRegister(index, cache, mocks.GenericHeight)
+		readFunc := readRegister(index, cache, mocks.GenericHeight, 0, 0)
 		value, err := readFunc(owner, controller, key)
 
 		require.NoError(t, err)
@@ -69,7 +73,7 @@ func TestReadRegister(t *testing.T) {
 			return []ledger.Value{mocks.GenericBytes}, nil
 		}
 
-		readFunc := readRegister(index, cache, mocks.GenericHeight)
+		readFunc := readRegister(index, cache, mocks.GenericHeight, 0, 0)
 		value, err := readFunc(owner, controller, key)
 
 		require.NoError(t, err)
@@ -91,9 +95,131 @@ func TestReadRegister(t *testing.T) {
 			return nil, mocks.GenericError
 		}
 
-		readFunc := readRegister(index, cache, mocks.GenericHeight)
+		readFunc := readRegister(index, cache, mocks.GenericHeight, 0, 0)
 		_, err := readFunc(owner, controller, key)
 
 		assert.Error(t, err)
 	})
+
+	t.Run("retries transient failure on Values", func(t *testing.T) {
+		t.Parallel()
+
+		cache := mocks.BaselineCache(t)
+		cache.GetFunc = func(key interface{}) (interface{}, bool) {
+			return nil, false
+		}
+
+		// The index fails twice with a transient error before it succeeds.
+		var calls int
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(height uint64, _ []ledger.Path) ([]ledger.Value, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+			calls++
+			if calls <= 2 {
+				return nil, fmt.Errorf("could not get registers: %w", status.Error(codes.DeadlineExceeded, "deadline exceeded"))
+			}
+			return []ledger.Value{mocks.GenericBytes}, nil
+		}
+
+		readFunc := readRegister(index, cache, mocks.GenericHeight, 3, time.Millisecond)
+		value, err := readFunc(owner, controller, key)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericBytes, value[:])
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("handles exhausted retries on Values", func(t *testing.T) {
+		t.Parallel()
+
+		cache := mocks.BaselineCache(t)
+		cache.GetFunc = func(key interface{}) (interface{}, bool) {
+			return nil, false
+		}
+
+		var calls int
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+			calls++
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		}
+
+		readFunc := readRegister(index, cache, mocks.GenericHeight, 2, time.Millisecond)
+		_, err := readFunc(owner, controller, key)
+
+		assert.Error(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("does not retry fatal failure on Values", func(t *testing.T) {
+		t.Parallel()
+
+		cache := mocks.BaselineCache(t)
+		cache.GetFunc = func(key interface{}) (interface{}, bool) {
+			return nil, false
+		}
+
+		var calls int
+		index := mocks.BaselineReader(t)
+		index.ValuesFunc = func(uint64, []ledger.Path) ([]ledger.Value, error) {
+			calls++
+			return nil, status.Error(codes.NotFound, "not found")
+		}
+
+		readFunc := readRegister(index, cache, mocks.GenericHeight, 3, time.Millisecond)
+		_, err := readFunc(owner, controller, key)
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "deadline exceeded", err: status.Error(codes.DeadlineExceeded, "deadline exceeded"), want: true},
+		{name: "aborted", err: status.Error(codes.Aborted, "aborted"), want: true},
+		{name: "wrapped deadline exceeded", err: fmt.Errorf("could not get registers: %w", status.Error(codes.DeadlineExceeded, "deadline exceeded")), want: true},
+		{name: "unavailable retried by connection", err: status.Error(codes.Unavailable, "unavailable"), want: false},
+		{name: "resource exhausted retried by connection", err: status.Error(codes.ResourceExhausted, "resource exhausted"), want: false},
+		{name: "not found", err: status.Error(codes.NotFound, "not found"), want: false},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "invalid argument"), want: false},
+		{name: "local error", err: mocks.GenericError, want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, retryable(test.err))
+		})
+	}
+}
+
+func TestReadDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff time.Duration
+		attempt uint
+		want    time.Duration
+	}{
+		{name: "first attempt", backoff: 100 * time.Millisecond, attempt: 0, want: 100 * time.Millisecond},
+		{name: "doubles with each attempt", backoff: 100 * time.Millisecond, attempt: 3, want: 800 * time.Millisecond},
+		{name: "capped at maximum", backoff: 100 * time.Millisecond, attempt: 10, want: maxReadDelay},
+		{name: "capped on overflow", backoff: 100 * time.Millisecond, attempt: 64, want: maxReadDelay},
+		{name: "without backoff", backoff: 0, attempt: 5, want: 0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, readDelay(test.backoff, test.attempt))
+		})
+	}
 }
@@ -15,6 +15,7 @@
 package mapper
 
 import (
+	"runtime"
 	"time"
 )
 
@@ -23,6 +24,13 @@ var DefaultConfig = Config{
 	BootstrapState: false,
 	SkipRegisters:  false,
 	WaitInterval:   100 * time.Millisecond,
+	TrieWorkers:    runtime.NumCPU(),
+
+	InitialHeight:   0,
+	SkipIndexed:     nil,
+	ContinueOnError: false,
+	LogSample:       0,
+	MaxValueSize:    128 * 1024 * 1024, // 128 MiB
 }
 
 // Config contains optional parameters for the Mapper.
@@ -30,6 +38,13 @@ type Config struct {
 	BootstrapState bool
 	SkipRegisters  bool
 	WaitInterval   time.Duration
+	TrieWorkers    int
+
+	InitialHeight   uint64
+	SkipIndexed     Inspector
+	ContinueOnError bool
+	LogSample       uint32
+	MaxValueSize    uint64
 }
 
 // Option is an option that can be given to the mapper to configure optional
@@ -59,3 +74,65 @@ func WithWaitInterval(interval time.Duration) Option {
 		cfg.WaitInterval = interval
 	}
 }
+
+// WithTrieWorkers sets the number of workers used to apply trie updates to the
+// execution state trie. Workers are only used for updates that are large
+// enough to benefit from them, and the resulting trie is always the same.
+func WithTrieWorkers(workers int) Option {
+	return func(cfg *Config) {
+		cfg.TrieWorkers = workers
+	}
+}
+
+// WithInitialHeight makes the mapper resume indexing from the given indexed
+// height, rather than from the last indexed height. It should be paired with a
+// loader that restores the trie as it was at that height, such as an index
+// loader that excludes all heights above it. A height of zero disables it.
+func WithInitialHeight(height uint64) Option {
+	return func(cfg *Config) {
+		cfg.InitialHeight = height
+	}
+}
+
+// WithSkipIndexed makes the mapper skip the heights below the last indexed
+// height that it replays after resuming from an initial height, as long as the
+// given inspector reports them as completely indexed and their indexed state
+// commitment matches the one of the chain. Heights with a recorded failure or
+// without recorded classes are indexed again. A nil inspector disables it.
+func WithSkipIndexed(inspect Inspector) Option {
+	return func(cfg *Config) {
+		cfg.SkipIndexed = inspect
+	}
+}
+
+// WithContinueOnError makes the mapper continue with the next block when the
+// execution data of a block is missing or invalid. The failure is recorded in
+// the index for the height of the block, so that reads of its execution data
+// fail with a clear error instead of returning partial data. Errors writing to
+// the index are always fatal.
+func WithContinueOnError(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.ContinueOnError = enabled
+	}
+}
+
+// WithLogSample makes the mapper log only one out of every `n` debug and info
+// messages that it emits for each block, which reduces the log volume when
+// indexing many blocks. Warnings and errors are always logged. Values below
+// two disable sampling.
+func WithLogSample(n uint32) Option {
+	return func(cfg *Config) {
+		cfg.LogSample = n
+	}
+}
+
+// WithMaxValueSize sets the maximum size in bytes of register values that the
+// mapper indexes. Registers with bigger values are rejected: by default, the
+// mapper fails, but if it is configured to continue on errors, it skips the
+// register and records that it was skipped instead. A size of zero disables
+// the limit.
+func WithMaxValueSize(size uint64) Option {
+	return func(cfg *Config) {
+		cfg.MaxValueSize = size
+	}
+}
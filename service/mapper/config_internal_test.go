@@ -19,6 +19,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/testing/mocks"
 )
 
 func TestWithBootstrapState(t *testing.T) {
@@ -53,3 +55,35 @@ func TestWithIndexHeader(t *testing.T) {
 
 	assert.Equal(t, interval, c.WaitInterval)
 }
+
+func TestWithInitialHeight(t *testing.T) {
+	c := Config{
+		InitialHeight: 0,
+	}
+	height := mocks.GenericHeight
+
+	WithInitialHeight(height)(&c)
+
+	assert.Equal(t, height, c.InitialHeight)
+}
+
+func TestWithSkipIndexed(t *testing.T) {
+	c := Config{
+		SkipIndexed: nil,
+	}
+	inspect := baselineInspector(true)
+
+	WithSkipIndexed(inspect)(&c)
+
+	assert.Equal(t, inspect, c.SkipIndexed)
+}
+
+func TestWithContinueOnError(t *testing.T) {
+	c := Config{
+		ContinueOnError: false,
+	}
+
+	WithContinueOnError(true)(&c)
+
+	assert.True(t, c.ContinueOnError)
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package mapper
+
+import (
+	"fmt"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Execution is the execution data of a finalized block, apart from its state
+// commitment.
+type Execution struct {
+	Collections  []*flow.LightCollection
+	Transactions []*flow.TransactionBody
+	Results      []*flow.TransactionResult
+	Events       []flow.Event
+}
+
+// ReadExecution retrieves the execution data of the finalized block at the
+// given height from the given chain. Its errors relate to the execution data
+// of that block only, so they can be recorded as a failure for the block.
+func ReadExecution(chain dps.Chain, height uint64) (*Execution, error) {
+
+	collections, err := chain.Collections(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get collections: %w", err)
+	}
+	transactions, err := chain.Transactions(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transactions: %w", err)
+	}
+	results, err := chain.Results(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get transaction results: %w", err)
+	}
+	events, err := chain.Events(height)
+	if err != nil {
+		return nil, fmt.Errorf("could not get events: %w", err)
+	}
+
+	execution := Execution{
+		Collections:  collections,
+		Transactions: transactions,
+		Results:      results,
+		Events:       events,
+	}
+
+	return &execution, nil
+}
+
+// IndexExecution indexes the given execution data of the finalized block at
+// the given height using the given writer. Its errors are errors of the index
+// database, which affect all blocks, so they should never be recorded as a
+// failure for the block.
+func IndexExecution(write dps.Writer, height uint64, execution *Execution) error {
+
+	err := write.Collections(height, execution.Collections)
+	if err != nil {
+		return fmt.Errorf("could not index collections: %w", err)
+	}
+	err = write.Transactions(height, execution.Transactions)
+	if err != nil {
+		return fmt.Errorf("could not index transactions: %w", err)
+	}
+	err = write.Results(execution.Results)
+	if err != nil {
+		return fmt.Errorf("could not index transaction results: %w", err)
+	}
+	err = write.Events(height, execution.Events)
+	if err != nil {
+		return fmt.Errorf("could not index events: %w", err)
+	}
+
+	return nil
+}
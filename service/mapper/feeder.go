@@ -15,7 +15,11 @@
 package mapper
 
 import (
+	"fmt"
+
 	"github.com/onflow/flow-go/ledger"
+
+	"github.com/optakt/flow-dps/ledger/trie"
 )
 
 // Feeder represents something that can be consumed to get trie updates
@@ -23,3 +27,21 @@ import (
 type Feeder interface {
 	Update() (*ledger.TrieUpdate, error)
 }
+
+// Gap is the error returned by a feeder when it had to skip trie updates it
+// could not read. It holds the tries of the checkpoint from which the feeder
+// continues, so that the forest can be rebuilt from them.
+type Gap struct {
+	Tries []*trie.Trie
+	Err   error
+}
+
+// Error implements the error interface.
+func (g *Gap) Error() string {
+	return fmt.Sprintf("skipped trie updates (tries: %d): %s", len(g.Tries), g.Err)
+}
+
+// Unwrap returns the error that caused the trie updates to be skipped.
+func (g *Gap) Unwrap() error {
+	return g.Err
+}
@@ -29,4 +29,5 @@ type Forest interface {
 	Paths(commit flow.StateCommitment) ([]ledger.Path, bool)
 	Parent(commit flow.StateCommitment) (flow.StateCommitment, bool)
 	Reset(finalized flow.StateCommitment)
+	Prune(finalized flow.StateCommitment)
 }
@@ -15,6 +15,7 @@
 package mapper
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -28,6 +29,7 @@ type FSM struct {
 	state       *State
 	transitions map[Status]TransitionFunc
 	wg          *sync.WaitGroup
+	halt        sync.Once
 }
 
 // NewFSM returns a new FSM using the given state and options.
@@ -48,9 +50,29 @@ func NewFSM(state *State, options ...func(*FSM)) *FSM {
 
 // Run starts the state machine.
 func (f *FSM) Run() error {
+	return f.RunWithContext(context.Background())
+}
+
+// RunWithContext starts the state machine, which stops when the given context
+// is canceled. Cancellation is checked between transitions, and interrupts any
+// transition that is waiting for data to become available, applying a trie
+// update or about to write a batch of registers. Since each height
+// is only marked as indexed by its last transition, the data of all completely
+// indexed heights is persisted when it returns.
+func (f *FSM) RunWithContext(ctx context.Context) error {
 	f.wg.Add(1)
 	defer f.wg.Done()
 
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.stop()
+		case <-finished:
+		}
+	}()
+
 	for {
 		select {
 		case <-f.state.done:
@@ -76,6 +98,10 @@ func (f *FSM) Run() error {
 
 // Stop gracefully stops the state machine.
 func (f *FSM) Stop() {
-	close(f.state.done)
+	f.stop()
 	f.wg.Wait()
 }
+
+func (f *FSM) stop() {
+	f.halt.Do(func() { close(f.state.done) })
+}
@@ -15,6 +15,7 @@
 package mapper
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -130,3 +131,82 @@ func TestFSM_Run(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestFSM_RunWithContext(t *testing.T) {
+	t.Run("cancel between heights", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// The index transition cancels the context at the fourth height, and
+		// waits for the cancellation to reach the state machine, so that the
+		// height is never forwarded.
+		var indexed int
+		var persisted []uint64
+		st := &State{
+			status: StatusIndex,
+			done:   make(chan struct{}),
+		}
+		f := NewFSM(st,
+			WithTransition(StatusIndex, func(state *State) error {
+				indexed++
+				if state.height == 3 {
+					cancel()
+					<-state.done
+				}
+				state.status = StatusForward
+				return nil
+			}),
+			WithTransition(StatusForward, func(state *State) error {
+				persisted = append(persisted, state.height)
+				state.height++
+				state.status = StatusIndex
+				return nil
+			}),
+		)
+
+		err := f.RunWithContext(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, indexed)
+		assert.Equal(t, []uint64{0, 1, 2}, persisted)
+		assert.Equal(t, StatusForward, st.status)
+
+		// Stopping an already canceled state machine should not panic.
+		assert.NotPanics(t, f.Stop)
+	})
+
+	t.Run("cancel while waiting", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		st := &State{
+			status: StatusIndex,
+			done:   make(chan struct{}),
+		}
+		f := NewFSM(st,
+			WithTransition(StatusIndex, func(state *State) error {
+				state.wait(time.Hour)
+				return nil
+			}),
+		)
+
+		done := make(chan error)
+		go func() {
+			done <- f.RunWithContext(ctx)
+		}()
+
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("state machine did not stop after cancellation")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package mapper
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rs/zerolog"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Recoverer represents something that can write on a DPS index and remove the
+// recorded failures of blocks once they have been fully indexed.
+type Recoverer interface {
+	dps.Writer
+	Recover(height uint64) error
+}
+
+// RetryFailures re-attempts indexing the execution data of the blocks at each
+// of the given failed heights. The failure of each height that is indexed
+// successfully is removed; for those whose execution data still can not be
+// retrieved, the failure is updated with the new reason. Errors writing to the
+// index abort the retry. It returns the heights that still failed, in
+// ascending order.
+func RetryFailures(log zerolog.Logger, chain dps.Chain, write Recoverer, failures map[uint64]string) ([]uint64, error) {
+
+	heights := make([]uint64, 0, len(failures))
+	for height := range failures {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i int, j int) bool {
+		return heights[i] < heights[j]
+	})
+
+	var failed []uint64
+	for _, height := range heights {
+
+		execution, err := ReadExecution(chain, height)
+		if err != nil {
+			log.Warn().Uint64("height", height).Str("previous", failures[height]).Err(err).Msg("retry of failed height failed")
+			err = write.Failure(height, err.Error())
+			if err != nil {
+				return nil, fmt.Errorf("could not update failure (height: %d): %w", height, err)
+			}
+			failed = append(failed, height)
+			continue
+		}
+
+		err = IndexExecution(write, height, execution)
+		if err != nil {
+			return nil, fmt.Errorf("could not index execution data (height: %d): %w", height, err)
+		}
+
+		err = write.Recover(height)
+		if err != nil {
+			return nil, fmt.Errorf("could not remove failure (height: %d): %w", height, err)
+		}
+
+		log.Info().Uint64("height", height).Msg("retry of failed height succeeded")
+	}
+
+	return failed, nil
+}
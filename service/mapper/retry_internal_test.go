@@ -0,0 +1,160 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+type recoverer struct {
+	*mocks.Writer
+	RecoverFunc func(height uint64) error
+}
+
+func (r *recoverer) Recover(height uint64) error {
+	return r.RecoverFunc(height)
+}
+
+func TestRetryFailures(t *testing.T) {
+	failures := map[uint64]string{
+		mocks.GenericHeight:     "could not get events",
+		mocks.GenericHeight + 1: "could not get collections",
+	}
+
+	t.Run("retry succeeds", func(t *testing.T) {
+		t.Parallel()
+
+		var recovered []uint64
+		write := &recoverer{
+			Writer: mocks.BaselineWriter(t),
+			RecoverFunc: func(height uint64) error {
+				recovered = append(recovered, height)
+				return nil
+			},
+		}
+		write.FailureFunc = func(uint64, string) error {
+			t.Fatal("unexpected failure update")
+			return nil
+		}
+
+		failed, err := RetryFailures(mocks.NoopLogger, mocks.BaselineChain(t), write, failures)
+
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+		assert.Equal(t, []uint64{mocks.GenericHeight, mocks.GenericHeight + 1}, recovered)
+	})
+
+	t.Run("retry still fails", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.EventsFunc = func(height uint64) ([]flow.Event, error) {
+			if height == mocks.GenericHeight+1 {
+				return nil, mocks.GenericError
+			}
+			return mocks.GenericEvents(4), nil
+		}
+
+		var recovered []uint64
+		var updated []uint64
+		write := &recoverer{
+			Writer: mocks.BaselineWriter(t),
+			RecoverFunc: func(height uint64) error {
+				recovered = append(recovered, height)
+				return nil
+			},
+		}
+		write.FailureFunc = func(height uint64, reason string) error {
+			assert.Contains(t, reason, mocks.GenericError.Error())
+			updated = append(updated, height)
+			return nil
+		}
+
+		failed, err := RetryFailures(mocks.NoopLogger, chain, write, failures)
+
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{mocks.GenericHeight + 1}, failed)
+		assert.Equal(t, []uint64{mocks.GenericHeight}, recovered)
+		assert.Equal(t, []uint64{mocks.GenericHeight + 1}, updated)
+	})
+
+	t.Run("handles writer failure on Recover", func(t *testing.T) {
+		t.Parallel()
+
+		write := &recoverer{
+			Writer: mocks.BaselineWriter(t),
+			RecoverFunc: func(uint64) error {
+				return mocks.GenericError
+			},
+		}
+
+		_, err := RetryFailures(mocks.NoopLogger, mocks.BaselineChain(t), write, failures)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles writer failure on Failure", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.CollectionsFunc = func(uint64) ([]*flow.LightCollection, error) {
+			return nil, mocks.GenericError
+		}
+
+		write := &recoverer{
+			Writer: mocks.BaselineWriter(t),
+			RecoverFunc: func(uint64) error {
+				return nil
+			},
+		}
+		write.FailureFunc = func(uint64, string) error {
+			return mocks.GenericError
+		}
+
+		_, err := RetryFailures(mocks.NoopLogger, chain, write, failures)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles writer failure on indexing execution data", func(t *testing.T) {
+		t.Parallel()
+
+		write := &recoverer{
+			Writer: mocks.BaselineWriter(t),
+			RecoverFunc: func(uint64) error {
+				t.Fatal("unexpected recovery")
+				return nil
+			},
+		}
+		write.EventsFunc = func(uint64, []flow.Event) error {
+			return mocks.GenericError
+		}
+		write.FailureFunc = func(uint64, string) error {
+			t.Fatal("unexpected failure update")
+			return nil
+		}
+
+		_, err := RetryFailures(mocks.NoopLogger, mocks.BaselineChain(t), write, failures)
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+}
@@ -16,6 +16,7 @@ package mapper
 
 import (
 	"math"
+	"time"
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
@@ -30,6 +31,13 @@ type State struct {
 	next        flow.StateCommitment
 	registerIdx int
 	registers   map[ledger.Path]*ledger.Payload
+	collected   map[ledger.Path]struct{}
+	blocks      map[flow.Identifier]uint64
+	sealed      uint64
+	indexed     uint64
+	skip        bool
+	rebuilt     bool
+	lost        uint64
 	done        chan struct{}
 }
 
@@ -43,8 +51,29 @@ func EmptyState(forest Forest) *State {
 		last:      flow.DummyStateCommitment,
 		next:      flow.DummyStateCommitment,
 		registers: make(map[ledger.Path]*ledger.Payload),
+		collected: make(map[ledger.Path]struct{}),
+		blocks:    make(map[flow.Identifier]uint64),
 		done:      make(chan struct{}),
 	}
 
 	return &s
 }
+
+// stopped returns whether the state machine was stopped.
+func (s *State) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait waits for the given interval to elapse, unless the state machine is
+// stopped first.
+func (s *State) wait(interval time.Duration) {
+	select {
+	case <-time.After(interval):
+	case <-s.done:
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"errors"
 	"fmt"
 	"sync"
-	"time"
 
 	"github.com/rs/zerolog"
 
@@ -26,6 +25,7 @@ import (
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/ledger/trie"
+	"github.com/optakt/flow-dps/models/convert"
 	"github.com/optakt/flow-dps/models/dps"
 )
 
@@ -37,14 +37,15 @@ type TransitionFunc func(*State) error
 
 // Transitions is what applies transitions to the state of an FSM.
 type Transitions struct {
-	cfg   Config
-	log   zerolog.Logger
-	load  Loader
-	chain dps.Chain
-	feed  Feeder
-	read  dps.Reader
-	write dps.Writer
-	once  *sync.Once
+	cfg     Config
+	log     zerolog.Logger
+	sampled zerolog.Logger
+	load    Loader
+	chain   dps.Chain
+	feed    Feeder
+	read    dps.Reader
+	write   dps.Writer
+	once    *sync.Once
 }
 
 // NewTransitions returns a Transitions component using the given dependencies and using the given options
@@ -55,15 +56,19 @@ func NewTransitions(log zerolog.Logger, load Loader, chain dps.Chain, feed Feede
 		option(&cfg)
 	}
 
+	// Messages that are emitted for every block go through a separate logger,
+	// which can be sampled to reduce the log volume.
+	log = log.With().Str("component", "mapper_transitions").Logger()
 	t := Transitions{
-		log:   log.With().Str("component", "mapper_transitions").Logger(),
-		cfg:   cfg,
-		load:  load,
-		chain: chain,
-		feed:  feed,
-		read:  read,
-		write: write,
-		once:  &sync.Once{},
+		log:     log,
+		sampled: sampleLogger(log, cfg.LogSample),
+		cfg:     cfg,
+		load:    load,
+		chain:   chain,
+		feed:    feed,
+		read:    read,
+		write:   write,
+		once:    &sync.Once{},
 	}
 
 	return &t
@@ -76,6 +81,13 @@ func (t *Transitions) InitializeMapper(s *State) error {
 		return fmt.Errorf("invalid status for initializing mapper (%s)", s.status)
 	}
 
+	// If we were given an initial height, we resume from it, even if
+	// bootstrapping was configured.
+	if t.cfg.InitialHeight != 0 {
+		s.status = StatusResume
+		return nil
+	}
+
 	if t.cfg.BootstrapState {
 		s.status = StatusBootstrap
 		return nil
@@ -159,11 +171,20 @@ func (t *Transitions) ResumeIndexing(s *State) error {
 	}
 
 	// We need to know what the last indexed height was at the point we stopped
-	// indexing.
+	// indexing. If we were given an initial height, we instead resume from it,
+	// as long as it was indexed.
 	last, err := t.read.Last()
 	if err != nil {
 		return fmt.Errorf("could not get last height: %w", err)
 	}
+	s.indexed = last
+	if t.cfg.InitialHeight != 0 {
+		if t.cfg.InitialHeight < first || t.cfg.InitialHeight > last {
+			return fmt.Errorf("initial height is not indexed (height: %d, first: %d, last: %d)", t.cfg.InitialHeight, first, last)
+		}
+		last = t.cfg.InitialHeight
+		t.log.Info().Uint64("height", last).Msg("resuming from initial height")
+	}
 
 	// When resuming, the loader injected into the mapper rebuilds the trie from
 	// the paths and payloads stored in the index database.
@@ -207,7 +228,7 @@ func (t *Transitions) IndexChain(s *State) error {
 		return fmt.Errorf("invalid status for indexing chain (%s)", s.status)
 	}
 
-	log := t.log.With().Uint64("height", s.height).Logger()
+	log := t.sampled.With().Uint64("height", s.height).Logger()
 
 	// We try to retrieve the next header until it becomes available, which
 	// means all data coming from the protocol state is available after this
@@ -215,13 +236,30 @@ func (t *Transitions) IndexChain(s *State) error {
 	header, err := t.chain.Header(s.height)
 	if errors.Is(err, dps.ErrUnavailable) {
 		log.Debug().Msg("waiting for next header")
-		time.Sleep(t.cfg.WaitInterval)
+		s.wait(t.cfg.WaitInterval)
 		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("could not get header: %w", err)
 	}
 
+	// When resuming from a height below the last indexed height, the blocks up
+	// to the last indexed height are replayed. If configured to do so, we skip
+	// rewriting the data of such a height when the index already holds the same
+	// state commitment and it was completely indexed, and only keep updating
+	// the state tree.
+	if t.cfg.SkipIndexed != nil && s.indexed > 0 && s.height <= s.indexed {
+		skip, err := t.alreadyIndexed(s)
+		if err != nil {
+			return fmt.Errorf("could not check indexed height: %w", err)
+		}
+		if skip {
+			log.Debug().Msg("already indexed, skipping")
+			s.status = StatusUpdate
+			return nil
+		}
+	}
+
 	// At this point, we can retrieve the data from the consensus state. This is
 	// a slight optimization for the live indexer, as it allows us to process
 	// some data before the full execution data becomes available.
@@ -255,56 +293,52 @@ func (t *Transitions) IndexChain(s *State) error {
 		return fmt.Errorf("could not index seals: %w", err)
 	}
 
+	// We keep track of the last sealed height as we go, so that it can be
+	// served without walking back through the seals of the indexed blocks.
+	s.blocks[blockID] = s.height
+	err = t.trackSealed(s, seals)
+	if err != nil {
+		return fmt.Errorf("could not track sealed height: %w", err)
+	}
+
 	// Next, we try to retrieve the next commit until it becomes available,
 	// at which point all the data coming from the execution data should be
 	// available.
 	commit, err := t.chain.Commit(s.height)
 	if errors.Is(err, dps.ErrUnavailable) {
 		log.Debug().Msg("waiting for next state commitment")
-		time.Sleep(t.cfg.WaitInterval)
+		s.wait(t.cfg.WaitInterval)
 		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("could not get commit: %w", err)
 	}
-	collections, err := t.chain.Collections(s.height)
-	if err != nil {
-		return fmt.Errorf("could not get collections: %w", err)
-	}
-	transactions, err := t.chain.Transactions(s.height)
-	if err != nil {
-		return fmt.Errorf("could not get transactions: %w", err)
-	}
-	results, err := t.chain.Results(s.height)
-	if err != nil {
-		return fmt.Errorf("could not get transaction results: %w", err)
-	}
-	events, err := t.chain.Events(s.height)
-	if err != nil {
-		return fmt.Errorf("could not get events: %w", err)
-	}
-
-	// Next, all we need to do is index the remaining data and we have fully
-	// processed indexing for this block height.
+	// Next, we index the commit, which is needed to keep track of the execution
+	// state, and the remaining execution data. If the execution data can not be
+	// retrieved and we are configured to continue on errors, we record the
+	// failure for the height in the index instead, so that one bad execution
+	// record does not halt indexing. Errors writing to the index are always
+	// fatal, as they are not specific to the block.
 	err = t.write.Commit(s.height, commit)
 	if err != nil {
 		return fmt.Errorf("could not index commit: %w", err)
 	}
-	err = t.write.Collections(s.height, collections)
-	if err != nil {
-		return fmt.Errorf("could not index collections: %w", err)
-	}
-	err = t.write.Transactions(s.height, transactions)
-	if err != nil {
-		return fmt.Errorf("could not index transactions: %w", err)
+	execution, err := ReadExecution(t.chain, s.height)
+	if err != nil && !t.cfg.ContinueOnError {
+		return err
 	}
-	err = t.write.Results(results)
 	if err != nil {
-		return fmt.Errorf("could not index transaction results: %w", err)
+		log.Error().Err(err).Msg("could not retrieve execution data, recording failure")
+		err = t.write.Failure(s.height, err.Error())
+		if err != nil {
+			return fmt.Errorf("could not index failure: %w", err)
+		}
 	}
-	err = t.write.Events(s.height, events)
-	if err != nil {
-		return fmt.Errorf("could not index events: %w", err)
+	if execution != nil {
+		err = IndexExecution(t.write, s.height, execution)
+		if err != nil {
+			return err
+		}
 	}
 
 	// At this point, we need to forward the `last` state commitment to
@@ -335,13 +369,32 @@ func (t *Transitions) UpdateTree(s *State) error {
 		return fmt.Errorf("invalid status for updating tree (%s)", s.status)
 	}
 
-	log := t.log.With().Uint64("height", s.height).Hex("last", s.last[:]).Hex("next", s.next[:]).Logger()
+	log := t.sampled.With().Uint64("height", s.height).Hex("last", s.last[:]).Hex("next", s.next[:]).Logger()
+
+	// If the trie updates leading to the tree of the next finalized block were
+	// skipped, we can not collect its registers, so we record the failure for
+	// the height and go straight to forwarding it. Heights that were already
+	// indexed by a previous run keep their registers.
+	if s.height < s.lost {
+		if !s.skip {
+			log.Warn().Msg("registers lost to skipped trie updates, recording failure")
+			err := t.write.Failure(s.height, "registers lost to skipped trie updates")
+			if err != nil {
+				return fmt.Errorf("could not index failure: %w", err)
+			}
+		}
+		s.status = StatusForward
+		return nil
+	}
 
 	// If the forest contains a tree for the commit of the next finalized block,
 	// we have reached our goal, and we can go to the next step in order to
 	// collect the register payloads we want to index for that block.
 	ok := s.forest.Has(s.next)
 	if ok {
+		if s.rebuilt {
+			t.reconnect(s)
+		}
 		log.Info().Hex("commit", s.next[:]).Msg("matched commit of finalized block")
 		s.status = StatusCollect
 		return nil
@@ -353,13 +406,26 @@ func (t *Transitions) UpdateTree(s *State) error {
 	// branch of the execution forest.
 	update, err := t.feed.Update()
 	if errors.Is(err, dps.ErrUnavailable) {
-		time.Sleep(t.cfg.WaitInterval)
+		s.wait(t.cfg.WaitInterval)
 		log.Debug().Msg("waiting for next trie update")
 		return nil
 	}
+	var gap *Gap
+	if errors.As(err, &gap) {
+		log.Warn().Err(gap.Err).Int("tries", len(gap.Tries)).Msg("trie updates skipped, rebuilding forest from checkpoint")
+		return t.rebuildForest(s, gap)
+	}
 	if err != nil {
 		return fmt.Errorf("could not feed update: %w", err)
 	}
+
+	// Reading the update can take a while, so the state machine might have
+	// been stopped in the meantime. As the height is only forwarded once all
+	// of its data is indexed, we can drop the update in that case.
+	if s.stopped() {
+		return nil
+	}
+
 	parent := flow.StateCommitment(update.RootHash)
 	tree, ok := s.forest.Tree(parent)
 	if !ok {
@@ -369,9 +435,26 @@ func (t *Transitions) UpdateTree(s *State) error {
 
 	// We then apply the update to the relevant tree, as retrieved from the
 	// forest, and save the updated tree in the forest. If the tree is not new,
-	// we should error, as that should not happen.
+	// we should error, as that should not happen. Big updates take a while to
+	// apply, so we don't wait for them to finish if the state machine is
+	// stopped in the meantime.
 	paths, payloads := pathsPayloads(update)
-	tree, err = tree.Mutate(paths, payloads)
+	type mutation struct {
+		tree *trie.Trie
+		err  error
+	}
+	mutated := make(chan mutation, 1)
+	go func() {
+		tree, err := tree.MutateWithWorkers(paths, payloads, t.cfg.TrieWorkers)
+		mutated <- mutation{tree: tree, err: err}
+	}()
+	var result mutation
+	select {
+	case <-s.done:
+		return nil
+	case result = <-mutated:
+	}
+	tree, err = result.tree, result.err
 	if err != nil {
 		log.Error().Err(err).Msg("could not insert trie update")
 		return err
@@ -388,7 +471,7 @@ func (t *Transitions) UpdateTree(s *State) error {
 // CollectRegisters reads the payloads for the next block to be indexed from the state's forest, unless payload
 // indexing is disabled.
 func (t *Transitions) CollectRegisters(s *State) error {
-	log := t.log.With().Uint64("height", s.height).Hex("commit", s.next[:]).Logger()
+	log := t.sampled.With().Uint64("height", s.height).Hex("commit", s.next[:]).Logger()
 	if s.status != StatusCollect {
 		return fmt.Errorf("invalid status for collecting registers (%s)", s.status)
 	}
@@ -396,7 +479,7 @@ func (t *Transitions) CollectRegisters(s *State) error {
 	// If indexing payloads is disabled, we can bypass collection and indexing
 	// of payloads and just go straight to forwarding the height to the next
 	// finalized block.
-	if t.cfg.SkipRegisters {
+	if t.cfg.SkipRegisters || s.skip {
 		s.status = StatusForward
 		return nil
 	}
@@ -428,17 +511,25 @@ func (t *Transitions) CollectRegisters(s *State) error {
 		tree, _ := s.forest.Tree(commit)
 		paths, _ := s.forest.Paths(commit)
 
-		// Read enough paths to fill the batch.
-		end := registerBatchSize - len(s.registers)
-		if end >= len(paths) {
-			// If there are not enough paths to fill the batch, just read all
-			// paths instead.
-			end = len(paths)
+		// Read enough paths to fill the batch, skipping the paths that were
+		// already collected for this block. Those were either indexed in a
+		// previous batch, or they were collected from a more recent tree.
+		var batch []ledger.Path
+		for _, path := range paths {
+			if len(s.registers)+len(batch) >= registerBatchSize {
+				break
+			}
+			_, ok := s.collected[path]
+			if ok {
+				continue
+			}
+			s.collected[path] = struct{}{}
+			batch = append(batch, path)
 		}
 
-		payloads := tree.UnsafeRead(paths[:end])
+		payloads := tree.UnsafeRead(batch)
 		for i := range payloads {
-			s.registers[paths[i]] = payloads[i]
+			s.registers[batch[i]] = payloads[i]
 		}
 
 		if len(s.registers) >= registerBatchSize {
@@ -473,7 +564,7 @@ func (t *Transitions) MapRegisters(s *State) error {
 		return fmt.Errorf("invalid status for indexing registers (%s)", s.status)
 	}
 
-	log := t.log.With().Uint64("height", s.height).Hex("commit", s.next[:]).Logger()
+	log := t.sampled.With().Uint64("height", s.height).Hex("commit", s.next[:]).Logger()
 
 	// If there are no registers left to be indexed, we can go to the next step,
 	// which is about forwarding the height to the next finalized block.
@@ -490,16 +581,34 @@ func (t *Transitions) MapRegisters(s *State) error {
 	paths := make([]ledger.Path, 0, registerBatchSize)
 	payloads := make([]*ledger.Payload, 0, registerBatchSize)
 	for path, payload := range s.registers {
-		paths = append(paths, path)
-		payloads = append(payloads, payload)
 		delete(s.registers, path)
 		s.registerIdx++
 
+		// Registers with oversized values are rejected, so that a malformed
+		// execution record can not blow up the memory usage of the index.
+		ok, err := t.checkValueSize(log, s.height, path, payload)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		paths = append(paths, path)
+		payloads = append(payloads, payload)
+
 		if len(paths) >= registerBatchSize {
 			break
 		}
 	}
 
+	// Writing a batch takes a while, so we skip it if the state machine was
+	// stopped in the meantime. The height is not forwarded in that case, so
+	// all of its registers are indexed again by the next run.
+	if s.stopped() {
+		return nil
+	}
+
 	// Then we store the (maximum) 1000 paths and payloads.
 	err := t.write.Payloads(s.height, paths, payloads)
 	if err != nil {
@@ -522,10 +631,33 @@ func (t *Transitions) ForwardHeight(s *State) error {
 		return fmt.Errorf("invalid status for forwarding height (%s)", s.status)
 	}
 
+	// If the height was already indexed by a previous run, we neither rewrite
+	// its classes nor move the last indexed height backwards.
+	if s.skip {
+		s.skip = false
+		s.height++
+		if !s.rebuilt {
+			s.forest.Prune(s.next)
+		}
+		s.registerIdx = 0
+		s.status = StatusIndex
+		return nil
+	}
+
+	// Before moving on, we record which classes of data were indexed for the
+	// height, so that readers can tell skipped data apart from missing data.
+	classes := t.classes()
+	if s.height < s.lost {
+		classes &^= dps.ClassRegisters
+	}
+	err := t.write.Classes(s.height, classes)
+	if err != nil {
+		return fmt.Errorf("could not index classes: %w", err)
+	}
+
 	// After finishing the indexing of the payloads for a finalized block, or
 	// skipping it, we should document the last indexed height. On the first
 	// pass, we will also index the first indexed height here.
-	var err error
 	t.once.Do(func() { err = t.write.First(s.height) })
 	if err != nil {
 		return fmt.Errorf("could not index first height: %w", err)
@@ -534,17 +666,236 @@ func (t *Transitions) ForwardHeight(s *State) error {
 	if err != nil {
 		return fmt.Errorf("could not index last height: %w", err)
 	}
+	if s.sealed > 0 {
+		err = t.write.Sealed(s.sealed)
+		if err != nil {
+			return fmt.Errorf("could not index sealed height: %w", err)
+		}
+	}
 
 	// Now that we have indexed the heights, we can forward to the next height,
-	// and reset the forest to free up memory.
+	// and prune the forest of all tries that can no longer become finalized to
+	// free up memory. After the forest was rebuilt from a checkpoint, its tries
+	// don't descend from each other, so we keep them until the next finalized
+	// block is reached through trie updates again.
 	s.height++
-	s.forest.Reset(s.next)
+	if !s.rebuilt {
+		s.forest.Prune(s.next)
+	}
 	s.registerIdx = 0
+	s.collected = make(map[ledger.Path]struct{})
 
-	t.log.Info().Uint64("height", s.height).Msg("forwarded finalized block to next height")
+	t.sampled.Info().Uint64("height", s.height).Msg("forwarded finalized block to next height")
 
 	// Once the height is forwarded, we can set the status so that we index
 	// the blockchain data next.
 	s.status = StatusIndex
 	return nil
 }
+
+// trackSealed updates the last sealed height of the state with the given seals
+// of the block at the state's current height. The heights of recently indexed
+// blocks are kept in the state, because the writer might not have committed
+// them to the index yet; older blocks are looked up in the index. Seals of
+// blocks that were never indexed, which happens for the first few blocks after
+// the root height, are ignored.
+func (t *Transitions) trackSealed(s *State, seals []*flow.Seal) error {
+
+	for _, seal := range seals {
+		height, ok := s.blocks[seal.BlockID]
+		if !ok {
+			var err error
+			height, err = t.read.HeightForBlock(seal.BlockID)
+			if errors.Is(err, dps.ErrBlockNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("could not get height for sealed block (block: %x): %w", seal.BlockID, err)
+			}
+		}
+		if height > s.sealed {
+			s.sealed = height
+		}
+	}
+
+	// Blocks are sealed in order, so we no longer need the heights of blocks
+	// at or below the last sealed height.
+	for blockID, height := range s.blocks {
+		if height <= s.sealed {
+			delete(s.blocks, blockID)
+		}
+	}
+
+	return nil
+}
+
+// alreadyIndexed checks whether the state's current height was already indexed
+// completely and with the same state commitment by a previous run, according
+// to the configured inspector. If it was, it forwards the
+// state commitments of the state and marks the height as skipped.
+func (t *Transitions) alreadyIndexed(s *State) (bool, error) {
+
+	commit, err := t.chain.Commit(s.height)
+	if errors.Is(err, dps.ErrUnavailable) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not get commit: %w", err)
+	}
+	indexed, err := t.read.Commit(s.height)
+	if err != nil {
+		return false, fmt.Errorf("could not get indexed commit: %w", err)
+	}
+	if indexed != commit {
+		return false, nil
+	}
+
+	// Even with a matching commit, the height might not have been completely
+	// indexed, for example if its execution data was missing and the failure
+	// was recorded instead, in which case we index it again.
+	complete, err := t.cfg.SkipIndexed.Complete(s.height, t.classes())
+	if err != nil {
+		return false, fmt.Errorf("could not check indexed classes: %w", err)
+	}
+	if !complete {
+		return false, nil
+	}
+
+	s.last = s.next
+	s.next = commit
+	s.skip = true
+
+	return true, nil
+}
+
+// rebuildForest replaces the trees of the state's forest with the tries of the
+// checkpoint from which the feeder continues after skipping trie updates. It
+// then determines the heights whose registers were lost, which are those before
+// the last finalized block whose tree is part of the checkpoint.
+func (t *Transitions) rebuildForest(s *State, gap *Gap) error {
+
+	// Just like when bootstrapping, we add the tries on top of an empty tree,
+	// which is the stopping point when collecting registers. We do not know
+	// which paths were changed for each of them, so we leave them empty.
+	empty := trie.NewEmptyTrie()
+	base := flow.StateCommitment(empty.RootHash())
+	s.forest.Add(empty, nil, flow.DummyStateCommitment)
+	s.forest.Reset(base)
+	for _, tree := range gap.Tries {
+		commit := flow.StateCommitment(tree.RootHash())
+		if commit == base {
+			continue
+		}
+		s.forest.Add(tree, nil, base)
+	}
+	s.rebuilt = true
+
+	// The checkpoint holds the most recent tries at the time it was written,
+	// so the trees of the finalized blocks that follow the last one it holds
+	// will be built from the trie updates after it. We thus look for the last
+	// finalized block with a tree in the checkpoint, until the chain can not
+	// provide any more commits.
+	s.lost = s.height
+	for height := s.height; ; height++ {
+		commit, err := t.chain.Commit(height)
+		if err != nil {
+			break
+		}
+		if s.forest.Has(commit) {
+			s.lost = height
+		}
+	}
+
+	t.log.Warn().Uint64("height", s.height).Uint64("resume", s.lost).Msg("rebuilt forest from checkpoint, registers of heights before resume height are lost")
+
+	return nil
+}
+
+// reconnect checks whether the tree of the next finalized block descends from
+// the tree of the last finalized block after the forest was rebuilt from a
+// checkpoint. If it does not, we don't know which registers changed between
+// the two, so the tree is added again with all of its paths, as the child of
+// the last finalized block's tree, so that all of its registers are collected.
+func (t *Transitions) reconnect(s *State) {
+
+	base := flow.StateCommitment(trie.NewEmptyTrie().RootHash())
+	commit := s.next
+	for commit != base {
+		if commit == s.last {
+			s.rebuilt = false
+			return
+		}
+		parent, ok := s.forest.Parent(commit)
+		if !ok {
+			break
+		}
+		commit = parent
+	}
+
+	tree, _ := s.forest.Tree(s.next)
+	s.forest.Add(tree, tree.Paths(), s.last)
+}
+
+// classes returns the classes of data that the mapper indexes for each height.
+func (t *Transitions) classes() dps.Classes {
+	classes := dps.ClassesAll
+	if t.cfg.SkipRegisters {
+		classes &^= dps.ClassRegisters
+	}
+	return classes
+}
+
+// checkValueSize checks whether the value of the given register payload is
+// within the configured maximum size, in which case it returns true. If it is
+// not, it returns an error, unless the mapper is configured to continue on
+// errors, in which case it records that the register was skipped at the height
+// and returns false.
+func (t *Transitions) checkValueSize(log zerolog.Logger, height uint64, path ledger.Path, payload *ledger.Payload) (bool, error) {
+
+	size := uint64(len(payload.Value))
+	if t.cfg.MaxValueSize == 0 || size <= t.cfg.MaxValueSize {
+		return true, nil
+	}
+
+	// We include as much information about the register as possible, so that
+	// operators can investigate the offending execution record.
+	log = log.With().Hex("path", path[:]).Uint64("size", size).Uint64("max_size", t.cfg.MaxValueSize).Logger()
+	regID, err := convert.KeyToRegisterID(payload.Key)
+	if err == nil {
+		log = log.With().Hex("owner", []byte(regID.Owner)).Hex("controller", []byte(regID.Controller)).Str("key", regID.Key).Logger()
+	}
+
+	if !t.cfg.ContinueOnError {
+		log.Error().Msg("register value exceeds maximum size")
+		return false, fmt.Errorf("register value exceeds maximum size (path: %x, size: %d, max: %d)", path, size, t.cfg.MaxValueSize)
+	}
+
+	// We record that the register was skipped, so that reads of the register
+	// don't return its previous value. We do not record a failure for the
+	// height, as the rest of its data is complete, and retrying the height
+	// would clear the failure while the register is still missing.
+	log.Error().Msg("register value exceeds maximum size, skipping register")
+	reason := fmt.Sprintf("register value exceeds maximum size (path: %x, size: %d, max: %d)", path, size, t.cfg.MaxValueSize)
+	err = t.write.Skipped(height, path, reason)
+	if err != nil {
+		return false, fmt.Errorf("could not index skipped register: %w", err)
+	}
+
+	return false, nil
+}
+
+// sampleLogger returns a logger that only logs one out of every `n` debug and
+// info messages, while always logging warnings and errors. If `n` is below two,
+// the logger is returned as is.
+func sampleLogger(log zerolog.Logger, n uint32) zerolog.Logger {
+	if n < 2 {
+		return log
+	}
+
+	sampler := zerolog.LevelSampler{
+		DebugSampler: &zerolog.BasicSampler{N: n},
+		InfoSampler:  &zerolog.BasicSampler{N: n},
+	}
+
+	return log.Sample(sampler)
+}
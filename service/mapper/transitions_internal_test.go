@@ -15,15 +15,18 @@
 package mapper
 
 import (
+	"bytes"
 	"sync"
 	"testing"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
 
+	trees "github.com/optakt/flow-dps/ledger/forest"
 	"github.com/optakt/flow-dps/ledger/trie"
 	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/testing/mocks"
@@ -237,6 +240,189 @@ func TestTransitions_IndexChain(t *testing.T) {
 		assert.Equal(t, StatusUpdate, st.status)
 	})
 
+	t.Run("skips already indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		read := mocks.BaselineReader(t)
+		read.CommitFunc = func(height uint64) (flow.StateCommitment, error) {
+			assert.Equal(t, mocks.GenericHeight, height)
+
+			return mocks.GenericCommit(2), nil
+		}
+
+		chain := mocks.BaselineChain(t)
+		chain.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(2), nil
+		}
+
+		write := mocks.BaselineWriter(t)
+		write.HeightFunc = func(flow.Identifier, uint64) error {
+			t.Fail()
+			return nil
+		}
+		write.HeaderFunc = func(uint64, *flow.Header) error {
+			t.Fail()
+			return nil
+		}
+		write.CommitFunc = func(uint64, flow.StateCommitment) error {
+			t.Fail()
+			return nil
+		}
+
+		inspect := &inspector{
+			CompleteFunc: func(height uint64, classes dps.Classes) (bool, error) {
+				assert.Equal(t, mocks.GenericHeight, height)
+				assert.Equal(t, dps.ClassesAll, classes)
+
+				return true, nil
+			},
+		}
+
+		tr, st := baselineFSM(t, StatusIndex, withReader(read), withWriter(write), withChain(chain))
+		tr.cfg.SkipIndexed = inspect
+		st.indexed = mocks.GenericHeight
+
+		err := tr.IndexChain(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusUpdate, st.status)
+		assert.True(t, st.skip)
+		assert.Equal(t, mocks.GenericCommit(0), st.last)
+		assert.Equal(t, mocks.GenericCommit(2), st.next)
+	})
+
+	t.Run("reindexes height with different indexed commit", func(t *testing.T) {
+		t.Parallel()
+
+		read := mocks.BaselineReader(t)
+		read.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(3), nil
+		}
+
+		chain := mocks.BaselineChain(t)
+		chain.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(2), nil
+		}
+
+		var written bool
+		write := mocks.BaselineWriter(t)
+		write.CommitFunc = func(height uint64, commit flow.StateCommitment) error {
+			assert.Equal(t, mocks.GenericCommit(2), commit)
+			written = true
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusIndex, withReader(read), withWriter(write), withChain(chain))
+		tr.cfg.SkipIndexed = baselineInspector(true)
+		st.indexed = mocks.GenericHeight
+
+		err := tr.IndexChain(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusUpdate, st.status)
+		assert.False(t, st.skip)
+		assert.True(t, written)
+	})
+
+	t.Run("reindexes incompletely indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		read := mocks.BaselineReader(t)
+		read.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(2), nil
+		}
+
+		chain := mocks.BaselineChain(t)
+		chain.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(2), nil
+		}
+
+		var written bool
+		write := mocks.BaselineWriter(t)
+		write.CommitFunc = func(uint64, flow.StateCommitment) error {
+			written = true
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusIndex, withReader(read), withWriter(write), withChain(chain))
+		tr.cfg.SkipIndexed = baselineInspector(false)
+		st.indexed = mocks.GenericHeight
+
+		err := tr.IndexChain(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusUpdate, st.status)
+		assert.False(t, st.skip)
+		assert.True(t, written)
+	})
+
+	t.Run("reindexes already indexed height without inspector", func(t *testing.T) {
+		t.Parallel()
+
+		read := mocks.BaselineReader(t)
+		read.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			t.Fail()
+			return mocks.GenericCommit(2), nil
+		}
+
+		chain := mocks.BaselineChain(t)
+		chain.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return mocks.GenericCommit(2), nil
+		}
+
+		var written bool
+		write := mocks.BaselineWriter(t)
+		write.CommitFunc = func(uint64, flow.StateCommitment) error {
+			written = true
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusIndex, withReader(read), withWriter(write), withChain(chain))
+		st.indexed = mocks.GenericHeight
+
+		err := tr.IndexChain(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusUpdate, st.status)
+		assert.False(t, st.skip)
+		assert.True(t, written)
+	})
+
+	t.Run("handles reader failure on already indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		read := mocks.BaselineReader(t)
+		read.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return flow.DummyStateCommitment, mocks.GenericError
+		}
+
+		tr, st := baselineFSM(t, StatusIndex, withReader(read))
+		tr.cfg.SkipIndexed = baselineInspector(true)
+		st.indexed = mocks.GenericHeight
+
+		err := tr.IndexChain(st)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles inspector failure on already indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		inspect := &inspector{
+			CompleteFunc: func(uint64, dps.Classes) (bool, error) {
+				return false, mocks.GenericError
+			},
+		}
+
+		tr, st := baselineFSM(t, StatusIndex)
+		tr.cfg.SkipIndexed = inspect
+		st.indexed = mocks.GenericHeight
+
+		err := tr.IndexChain(st)
+
+		assert.Error(t, err)
+	})
+
 	t.Run("handles invalid status", func(t *testing.T) {
 		t.Parallel()
 
@@ -439,6 +625,63 @@ func TestTransitions_IndexChain(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("records failure to retrieve events when continuing on error", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.EventsFunc = func(uint64) ([]flow.Event, error) {
+			return nil, mocks.GenericError
+		}
+
+		var failed bool
+		write := mocks.BaselineWriter(t)
+		write.FailureFunc = func(height uint64, reason string) error {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.Contains(t, reason, mocks.GenericError.Error())
+			failed = true
+			return nil
+		}
+		write.EventsFunc = func(uint64, []flow.Event) error {
+			t.Fatal("unexpected indexing of events")
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusIndex)
+		tr.chain = chain
+		tr.write = write
+		tr.cfg.ContinueOnError = true
+
+		err := tr.IndexChain(st)
+
+		require.NoError(t, err)
+		assert.True(t, failed)
+		assert.Equal(t, StatusUpdate, st.status)
+		assert.Equal(t, mocks.GenericCommit(0), st.next)
+	})
+
+	t.Run("handles writer failure to index failure", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.EventsFunc = func(uint64) ([]flow.Event, error) {
+			return nil, mocks.GenericError
+		}
+
+		write := mocks.BaselineWriter(t)
+		write.FailureFunc = func(uint64, string) error {
+			return mocks.GenericError
+		}
+
+		tr, st := baselineFSM(t, StatusIndex)
+		tr.chain = chain
+		tr.write = write
+		tr.cfg.ContinueOnError = true
+
+		err := tr.IndexChain(st)
+
+		assert.Error(t, err)
+	})
+
 	t.Run("handles writer failure to index events", func(t *testing.T) {
 		t.Parallel()
 
@@ -455,6 +698,27 @@ func TestTransitions_IndexChain(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("does not record writer failure to index events when continuing on error", func(t *testing.T) {
+		t.Parallel()
+
+		write := mocks.BaselineWriter(t)
+		write.EventsFunc = func(uint64, []flow.Event) error {
+			return mocks.GenericError
+		}
+		write.FailureFunc = func(uint64, string) error {
+			t.Fatal("unexpected indexing of failure")
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusIndex)
+		tr.write = write
+		tr.cfg.ContinueOnError = true
+
+		err := tr.IndexChain(st)
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+
 	t.Run("handles chain failure to retrieve seals", func(t *testing.T) {
 		t.Parallel()
 
@@ -486,6 +750,71 @@ func TestTransitions_IndexChain(t *testing.T) {
 
 		assert.Error(t, err)
 	})
+
+	t.Run("tracks last sealed height", func(t *testing.T) {
+		t.Parallel()
+
+		// The first seal is for a recently indexed block, the second one for a
+		// block indexed by a previous run and the third one for a block that
+		// was never indexed.
+		seals := mocks.GenericSeals(4)
+		chain := mocks.BaselineChain(t)
+		chain.SealsFunc = func(uint64) ([]*flow.Seal, error) {
+			return seals[:3], nil
+		}
+
+		read := mocks.BaselineReader(t)
+		read.HeightForBlockFunc = func(blockID flow.Identifier) (uint64, error) {
+			switch blockID {
+			case seals[1].BlockID:
+				return mocks.GenericHeight - 10, nil
+			case seals[2].BlockID:
+				return 0, dps.ErrBlockNotFound
+			default:
+				t.Fail()
+				return 0, nil
+			}
+		}
+
+		tr, st := baselineFSM(t, StatusIndex, withReader(read), withChain(chain))
+		st.blocks[seals[0].BlockID] = mocks.GenericHeight - 5
+		st.blocks[seals[3].BlockID] = mocks.GenericHeight - 1
+
+		err := tr.IndexChain(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight-5, st.sealed)
+		assert.NotContains(t, st.blocks, seals[0].BlockID)
+		assert.Contains(t, st.blocks, seals[3].BlockID)
+		assert.Contains(t, st.blocks, mocks.GenericHeader.ID())
+	})
+
+	t.Run("keeps last sealed height when sealing older blocks", func(t *testing.T) {
+		t.Parallel()
+
+		tr, st := baselineFSM(t, StatusIndex)
+		st.sealed = mocks.GenericHeight + 1
+
+		err := tr.IndexChain(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight+1, st.sealed)
+	})
+
+	t.Run("handles reader failure on sealed block height", func(t *testing.T) {
+		t.Parallel()
+
+		read := mocks.BaselineReader(t)
+		read.HeightForBlockFunc = func(flow.Identifier) (uint64, error) {
+			return 0, mocks.GenericError
+		}
+
+		tr, st := baselineFSM(t, StatusIndex, withReader(read))
+
+		err := tr.IndexChain(st)
+
+		assert.Error(t, err)
+	})
 }
 
 func TestTransitions_UpdateTree(t *testing.T) {
@@ -516,6 +845,27 @@ func TestTransitions_UpdateTree(t *testing.T) {
 		assert.Equal(t, StatusUpdate, st.status)
 	})
 
+	t.Run("drops update when stopped", func(t *testing.T) {
+		t.Parallel()
+
+		tr, st := baselineFSM(t, StatusUpdate)
+
+		forest := forest.BaselineMock(t, false)
+		forest.AddFunc = func(*trie.Trie, []ledger.Path, flow.StateCommitment) {
+			t.Error("should not add tree once stopped")
+		}
+		forest.TreeFunc = func(flow.StateCommitment) (*trie.Trie, bool) {
+			return tree, true
+		}
+		st.forest = forest
+		close(st.done)
+
+		err := tr.UpdateTree(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusUpdate, st.status)
+	})
+
 	t.Run("nominal case with no available update temporarily", func(t *testing.T) {
 		t.Parallel()
 
@@ -609,6 +959,112 @@ func TestTransitions_UpdateTree(t *testing.T) {
 	})
 }
 
+func TestTransitions_SkippedTrieUpdates(t *testing.T) {
+
+	// The tries of the checkpoint the feeder continues from hold the tree for
+	// the second height, while the tree of the first height is lost. The tree
+	// for the third height is built from a trie update after the checkpoint.
+	paths := mocks.GenericLedgerPaths(4)
+	var payloads []ledger.Payload
+	for _, payload := range mocks.GenericLedgerPayloads(4) {
+		payloads = append(payloads, *payload)
+	}
+	checkpoint, err := trie.NewEmptyTrie().Mutate(paths[:3], payloads[:3])
+	require.NoError(t, err)
+	update := &ledger.TrieUpdate{
+		RootHash: checkpoint.RootHash(),
+		Paths:    paths[3:],
+		Payloads: mocks.GenericLedgerPayloads(4)[3:],
+	}
+	next, err := checkpoint.Mutate(paths[3:], payloads[3:])
+	require.NoError(t, err)
+
+	commits := []flow.StateCommitment{
+		mocks.GenericCommit(0),
+		flow.StateCommitment(checkpoint.RootHash()),
+		flow.StateCommitment(next.RootHash()),
+	}
+
+	chain := mocks.BaselineChain(t)
+	chain.CommitFunc = func(height uint64) (flow.StateCommitment, error) {
+		index := height - mocks.GenericHeight
+		if index >= uint64(len(commits)) {
+			return flow.DummyStateCommitment, dps.ErrUnavailable
+		}
+		return commits[index], nil
+	}
+
+	var calls int
+	feed := mocks.BaselineFeeder(t)
+	feed.UpdateFunc = func() (*ledger.TrieUpdate, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, &Gap{Tries: []*trie.Trie{checkpoint}, Err: mocks.GenericError}
+		case 2:
+			return update, nil
+		default:
+			return nil, dps.ErrUnavailable
+		}
+	}
+
+	failures := make(map[uint64]string)
+	classes := make(map[uint64]dps.Classes)
+	registers := make(map[uint64][]ledger.Path)
+	write := mocks.BaselineWriter(t)
+	write.FailureFunc = func(height uint64, reason string) error {
+		failures[height] = reason
+		return nil
+	}
+	write.ClassesFunc = func(height uint64, indexed dps.Classes) error {
+		classes[height] = indexed
+		return nil
+	}
+	write.PayloadsFunc = func(height uint64, paths []ledger.Path, _ []*ledger.Payload) error {
+		registers[height] = append(registers[height], paths...)
+		return nil
+	}
+
+	tr, st := baselineFSM(t, StatusUpdate)
+	tr.chain = chain
+	tr.feed = feed
+	tr.write = write
+	st.forest = trees.New()
+
+	transitions := map[Status]func(*State) error{
+		StatusIndex:   tr.IndexChain,
+		StatusUpdate:  tr.UpdateTree,
+		StatusCollect: tr.CollectRegisters,
+		StatusMap:     tr.MapRegisters,
+		StatusForward: tr.ForwardHeight,
+	}
+	for i := 0; st.height < mocks.GenericHeight+uint64(len(commits)); i++ {
+		require.Less(t, i, 100, "indexing did not continue past skipped trie updates")
+		err := transitions[st.status](st)
+		require.NoError(t, err)
+	}
+
+	// The registers of the first height are lost, so its failure is recorded
+	// and it is not marked as having registers.
+	assert.Contains(t, failures, mocks.GenericHeight)
+	assert.Len(t, failures, 1)
+	assert.Equal(t, dps.ClassesAll&^dps.ClassRegisters, classes[mocks.GenericHeight])
+	assert.Empty(t, registers[mocks.GenericHeight])
+
+	// All of the registers of the second height are indexed, as we don't
+	// know which of them changed, and only the updated ones for the third.
+	assert.Equal(t, dps.ClassesAll, classes[mocks.GenericHeight+1])
+	assert.ElementsMatch(t, paths[:3], registers[mocks.GenericHeight+1])
+	assert.Equal(t, dps.ClassesAll, classes[mocks.GenericHeight+2])
+	assert.ElementsMatch(t, paths[3:], registers[mocks.GenericHeight+2])
+
+	// Once the tree of the third height was built from a trie update, the
+	// forest is pruned again.
+	assert.False(t, st.rebuilt)
+	assert.False(t, st.forest.Has(commits[1]))
+	assert.True(t, st.forest.Has(commits[2]))
+}
+
 func TestTransitions_CollectRegisters(t *testing.T) {
 
 	tree := trie.NewEmptyTrie()
@@ -648,6 +1104,103 @@ func TestTransitions_CollectRegisters(t *testing.T) {
 		}
 	})
 
+	t.Run("skips already collected registers", func(t *testing.T) {
+		t.Parallel()
+
+		forest := forest.BaselineMock(t, true)
+		forest.TreeFunc = func(commit flow.StateCommitment) (*trie.Trie, bool) {
+			return tree, true
+		}
+		forest.ParentFunc = func(commit flow.StateCommitment) (flow.StateCommitment, bool) {
+			return mocks.GenericCommit(1), true
+		}
+
+		tr, st := baselineFSM(t, StatusCollect)
+		st.forest = forest
+		for _, path := range mocks.GenericLedgerPaths(6) {
+			st.collected[path] = struct{}{}
+		}
+
+		err := tr.CollectRegisters(st)
+		require.NoError(t, err)
+
+		assert.Equal(t, StatusForward, st.status)
+		assert.Empty(t, st.registers)
+	})
+
+	t.Run("finishes collection over several batches", func(t *testing.T) {
+		t.Parallel()
+
+		paths := mocks.GenericLedgerPaths(registerBatchSize + 1)
+		forest := forest.BaselineMock(t, true)
+		forest.PathsFunc = func(flow.StateCommitment) ([]ledger.Path, bool) {
+			return paths, true
+		}
+
+		tr, st := baselineFSM(t, StatusCollect)
+		st.forest = forest
+
+		err := tr.CollectRegisters(st)
+		require.NoError(t, err)
+		assert.Equal(t, StatusMap, st.status)
+		assert.Len(t, st.registers, registerBatchSize)
+
+		// Mapping the registers empties the batch, after which only the
+		// remaining register should be collected.
+		st.registers = make(map[ledger.Path]*ledger.Payload)
+		st.status = StatusCollect
+		err = tr.CollectRegisters(st)
+		require.NoError(t, err)
+		assert.Equal(t, StatusMap, st.status)
+		assert.Len(t, st.registers, 1)
+		assert.Contains(t, st.registers, paths[registerBatchSize])
+
+		st.registers = make(map[ledger.Path]*ledger.Payload)
+		st.status = StatusCollect
+		err = tr.CollectRegisters(st)
+		require.NoError(t, err)
+		assert.Equal(t, StatusForward, st.status)
+		assert.Empty(t, st.registers)
+	})
+
+	t.Run("keeps more recent payloads over older ones", func(t *testing.T) {
+		t.Parallel()
+
+		path := mocks.GenericLedgerPath(0)
+		newer, err := trie.NewEmptyTrie().Mutate([]ledger.Path{path}, []ledger.Payload{*mocks.GenericLedgerPayload(0)})
+		require.NoError(t, err)
+		older, err := trie.NewEmptyTrie().Mutate([]ledger.Path{path}, []ledger.Payload{*mocks.GenericLedgerPayload(1)})
+		require.NoError(t, err)
+
+		// The tree of the next block is the newer one, whose parent is the
+		// older one, whose parent in turn is the tree of the last block.
+		forest := forest.BaselineMock(t, true)
+		forest.TreeFunc = func(commit flow.StateCommitment) (*trie.Trie, bool) {
+			if commit == mocks.GenericCommit(0) {
+				return newer, true
+			}
+			return older, true
+		}
+		forest.PathsFunc = func(flow.StateCommitment) ([]ledger.Path, bool) {
+			return []ledger.Path{path}, true
+		}
+		forest.ParentFunc = func(commit flow.StateCommitment) (flow.StateCommitment, bool) {
+			if commit == mocks.GenericCommit(0) {
+				return mocks.GenericCommit(2), true
+			}
+			return mocks.GenericCommit(1), true
+		}
+
+		tr, st := baselineFSM(t, StatusCollect)
+		st.forest = forest
+
+		err = tr.CollectRegisters(st)
+		require.NoError(t, err)
+
+		require.Contains(t, st.registers, path)
+		assert.Equal(t, mocks.GenericLedgerPayload(0), st.registers[path])
+	})
+
 	t.Run("indexing payloads disabled", func(t *testing.T) {
 		t.Parallel()
 
@@ -667,6 +1220,26 @@ func TestTransitions_CollectRegisters(t *testing.T) {
 		assert.Equal(t, StatusForward, st.status)
 	})
 
+	t.Run("already indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		forest := forest.BaselineMock(t, true)
+		forest.TreeFunc = func(flow.StateCommitment) (*trie.Trie, bool) {
+			t.Fail()
+			return nil, false
+		}
+
+		tr, st := baselineFSM(t, StatusCollect)
+		st.forest = forest
+		st.skip = true
+
+		err := tr.CollectRegisters(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusForward, st.status)
+		assert.Empty(t, st.registers)
+	})
+
 	t.Run("handles invalid status", func(t *testing.T) {
 		t.Parallel()
 
@@ -728,6 +1301,119 @@ func TestTransitions_MapRegisters(t *testing.T) {
 		assert.Equal(t, StatusCollect, st.status)
 	})
 
+	t.Run("does not write registers when stopped", func(t *testing.T) {
+		t.Parallel()
+
+		write := mocks.BaselineWriter(t)
+		write.PayloadsFunc = func(uint64, []ledger.Path, []*ledger.Payload) error {
+			t.Error("should not write registers once stopped")
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusMap, withWriter(write))
+		st.registers = map[ledger.Path]*ledger.Payload{
+			mocks.GenericLedgerPath(0): mocks.GenericLedgerPayload(0),
+		}
+		close(st.done)
+
+		err := tr.MapRegisters(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusMap, st.status)
+	})
+
+	t.Run("handles oversized register value", func(t *testing.T) {
+		t.Parallel()
+
+		oversized := ledger.NewPayload(mocks.GenericLedgerKey, make([]byte, 1024))
+		testRegisters := map[ledger.Path]*ledger.Payload{
+			mocks.GenericLedgerPath(0): mocks.GenericLedgerPayload(0),
+			mocks.GenericLedgerPath(1): oversized,
+		}
+
+		write := mocks.BaselineWriter(t)
+		write.PayloadsFunc = func(uint64, []ledger.Path, []*ledger.Payload) error {
+			t.Fail()
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusMap)
+		tr.write = write
+		tr.cfg.MaxValueSize = 512
+		st.registers = testRegisters
+
+		err := tr.MapRegisters(st)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("skips oversized register value when continuing on error", func(t *testing.T) {
+		t.Parallel()
+
+		oversized := ledger.NewPayload(mocks.GenericLedgerKey, make([]byte, 1024))
+		testRegisters := map[ledger.Path]*ledger.Payload{
+			mocks.GenericLedgerPath(0): mocks.GenericLedgerPayload(0),
+			mocks.GenericLedgerPath(1): oversized,
+			mocks.GenericLedgerPath(2): mocks.GenericLedgerPayload(2),
+		}
+
+		var skipped []ledger.Path
+		write := mocks.BaselineWriter(t)
+		write.FailureFunc = func(uint64, string) error {
+			t.Fail()
+			return nil
+		}
+		write.SkippedFunc = func(height uint64, path ledger.Path, reason string) error {
+			assert.Equal(t, mocks.GenericHeight, height)
+			assert.NotEmpty(t, reason)
+			skipped = append(skipped, path)
+			return nil
+		}
+		write.PayloadsFunc = func(height uint64, paths []ledger.Path, payloads []*ledger.Payload) error {
+			assert.Len(t, paths, 2)
+			assert.NotContains(t, paths, mocks.GenericLedgerPath(1))
+			assert.NotContains(t, payloads, oversized)
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusMap)
+		tr.write = write
+		tr.cfg.MaxValueSize = 512
+		tr.cfg.ContinueOnError = true
+		st.registers = testRegisters
+
+		err := tr.MapRegisters(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, []ledger.Path{mocks.GenericLedgerPath(1)}, skipped)
+		assert.Empty(t, st.registers)
+		assert.Equal(t, StatusCollect, st.status)
+	})
+
+	t.Run("handles writer failure on skipped register", func(t *testing.T) {
+		t.Parallel()
+
+		oversized := ledger.NewPayload(mocks.GenericLedgerKey, make([]byte, 1024))
+		testRegisters := map[ledger.Path]*ledger.Payload{
+			mocks.GenericLedgerPath(0): oversized,
+		}
+
+		write := mocks.BaselineWriter(t)
+		write.SkippedFunc = func(uint64, ledger.Path, string) error {
+			return mocks.GenericError
+		}
+
+		tr, st := baselineFSM(t, StatusMap)
+		tr.write = write
+		tr.cfg.MaxValueSize = 512
+		tr.cfg.ContinueOnError = true
+		st.registers = testRegisters
+
+		err := tr.MapRegisters(st)
+
+		assert.Error(t, err)
+	})
+
 	t.Run("nominal case no more registers left to write", func(t *testing.T) {
 		t.Parallel()
 
@@ -774,61 +1460,161 @@ func TestTransitions_MapRegisters(t *testing.T) {
 		write := mocks.BaselineWriter(t)
 		write.PayloadsFunc = func(uint64, []ledger.Path, []*ledger.Payload) error { return mocks.GenericError }
 
-		tr, st := baselineFSM(t, StatusMap)
+		tr, st := baselineFSM(t, StatusMap)
+		tr.write = write
+		st.registers = testRegisters
+
+		err := tr.MapRegisters(st)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTransitions_ForwardHeight(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			firstCalled int
+			lastCalled  int
+		)
+		write := mocks.BaselineWriter(t)
+		write.FirstFunc = func(height uint64) error {
+			assert.Equal(t, mocks.GenericHeight, height)
+			firstCalled++
+			return nil
+		}
+		write.LastFunc = func(height uint64) error {
+			assert.Equal(t, mocks.GenericHeight+uint64(lastCalled), height)
+			lastCalled++
+			return nil
+		}
+
+		forest := forest.BaselineMock(t, true)
+		forest.PruneFunc = func(finalized flow.StateCommitment) {
+			assert.Equal(t, mocks.GenericCommit(0), finalized)
+		}
+
+		tr, st := baselineFSM(t, StatusForward)
+		st.forest = forest
+		tr.write = write
+
+		err := tr.ForwardHeight(st)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusIndex, st.status)
+		assert.Equal(t, mocks.GenericHeight+1, st.height)
+
+		// Reset status to allow next call.
+		st.status = StatusForward
+		err = tr.ForwardHeight(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusIndex, st.status)
+		assert.Equal(t, mocks.GenericHeight+2, st.height)
+
+		// First should have been called only once.
+		assert.Equal(t, 1, firstCalled)
+	})
+
+	t.Run("resets collected registers", func(t *testing.T) {
+		t.Parallel()
+
+		tr, st := baselineFSM(t, StatusForward)
+		for _, path := range mocks.GenericLedgerPaths(6) {
+			st.collected[path] = struct{}{}
+		}
+
+		err := tr.ForwardHeight(st)
+
+		require.NoError(t, err)
+		assert.Empty(t, st.collected)
+	})
+
+	t.Run("does not rewrite already indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		write := mocks.BaselineWriter(t)
+		write.ClassesFunc = func(uint64, dps.Classes) error {
+			t.Fail()
+			return nil
+		}
+		write.FirstFunc = func(uint64) error {
+			t.Fail()
+			return nil
+		}
+		write.LastFunc = func(uint64) error {
+			t.Fail()
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusForward)
+		tr.write = write
+		st.skip = true
+
+		err := tr.ForwardHeight(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusIndex, st.status)
+		assert.Equal(t, mocks.GenericHeight+1, st.height)
+		assert.False(t, st.skip)
+	})
+
+	t.Run("records indexed classes", func(t *testing.T) {
+		t.Parallel()
+
+		var got dps.Classes
+		write := mocks.BaselineWriter(t)
+		write.ClassesFunc = func(height uint64, classes dps.Classes) error {
+			assert.Equal(t, mocks.GenericHeight, height)
+			got = classes
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusForward)
 		tr.write = write
-		st.registers = testRegisters
 
-		err := tr.MapRegisters(st)
+		err := tr.ForwardHeight(st)
 
-		assert.Error(t, err)
+		require.NoError(t, err)
+		assert.Equal(t, dps.ClassesAll, got)
 	})
-}
 
-func TestTransitions_ForwardHeight(t *testing.T) {
-	t.Run("nominal case", func(t *testing.T) {
+	t.Run("records indexed classes with registers skipped", func(t *testing.T) {
 		t.Parallel()
 
-		var (
-			firstCalled int
-			lastCalled  int
-		)
+		var got dps.Classes
 		write := mocks.BaselineWriter(t)
-		write.FirstFunc = func(height uint64) error {
-			assert.Equal(t, mocks.GenericHeight, height)
-			firstCalled++
-			return nil
-		}
-		write.LastFunc = func(height uint64) error {
-			assert.Equal(t, mocks.GenericHeight+uint64(lastCalled), height)
-			lastCalled++
+		write.ClassesFunc = func(height uint64, classes dps.Classes) error {
+			got = classes
 			return nil
 		}
 
-		forest := forest.BaselineMock(t, true)
-		forest.ResetFunc = func(finalized flow.StateCommitment) {
-			assert.Equal(t, mocks.GenericCommit(0), finalized)
-		}
-
 		tr, st := baselineFSM(t, StatusForward)
-		st.forest = forest
 		tr.write = write
+		tr.cfg.SkipRegisters = true
 
 		err := tr.ForwardHeight(st)
 
-		assert.NoError(t, err)
-		assert.Equal(t, StatusIndex, st.status)
-		assert.Equal(t, mocks.GenericHeight+1, st.height)
+		require.NoError(t, err)
+		assert.False(t, got.Has(dps.ClassRegisters))
+		assert.True(t, got.Has(dps.ClassHeaders|dps.ClassEvents|dps.ClassTransactions|dps.ClassResults))
+	})
 
-		// Reset status to allow next call.
-		st.status = StatusForward
-		err = tr.ForwardHeight(st)
+	t.Run("handles writer error on classes", func(t *testing.T) {
+		t.Parallel()
 
-		require.NoError(t, err)
-		assert.Equal(t, StatusIndex, st.status)
-		assert.Equal(t, mocks.GenericHeight+2, st.height)
+		write := mocks.BaselineWriter(t)
+		write.ClassesFunc = func(uint64, dps.Classes) error {
+			return mocks.GenericError
+		}
 
-		// First should have been called only once.
-		assert.Equal(t, 1, firstCalled)
+		tr, st := baselineFSM(t, StatusForward)
+		tr.write = write
+
+		err := tr.ForwardHeight(st)
+
+		assert.Error(t, err)
 	})
 
 	t.Run("handles invalid status", func(t *testing.T) {
@@ -872,6 +1658,46 @@ func TestTransitions_ForwardHeight(t *testing.T) {
 
 		assert.Error(t, err)
 	})
+
+	t.Run("indexes last sealed height", func(t *testing.T) {
+		t.Parallel()
+
+		var sealed []uint64
+		write := mocks.BaselineWriter(t)
+		write.SealedFunc = func(height uint64) error {
+			sealed = append(sealed, height)
+			return nil
+		}
+
+		tr, st := baselineFSM(t, StatusForward, withWriter(write))
+
+		// Without any sealed block, there is no sealed height to index.
+		err := tr.ForwardHeight(st)
+		require.NoError(t, err)
+
+		st.status = StatusForward
+		st.sealed = mocks.GenericHeight - 5
+		err = tr.ForwardHeight(st)
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{mocks.GenericHeight - 5}, sealed)
+	})
+
+	t.Run("handles writer error on sealed", func(t *testing.T) {
+		t.Parallel()
+
+		write := mocks.BaselineWriter(t)
+		write.SealedFunc = func(uint64) error {
+			return mocks.GenericError
+		}
+
+		tr, st := baselineFSM(t, StatusForward, withWriter(write))
+		st.sealed = mocks.GenericHeight - 5
+
+		err := tr.ForwardHeight(st)
+
+		assert.Error(t, err)
+	})
 }
 
 func TestTransitions_InitializeMapper(t *testing.T) {
@@ -901,6 +1727,20 @@ func TestTransitions_InitializeMapper(t *testing.T) {
 		assert.Equal(t, StatusResume, st.status)
 	})
 
+	t.Run("switches state to StatusResume if initial height configured", func(t *testing.T) {
+		t.Parallel()
+
+		tr, st := baselineFSM(t, StatusInitialize)
+
+		tr.cfg.BootstrapState = true
+		tr.cfg.InitialHeight = mocks.GenericHeight
+
+		err := tr.InitializeMapper(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusResume, st.status)
+	})
+
 	t.Run("handles invalid status", func(t *testing.T) {
 		t.Parallel()
 
@@ -962,6 +1802,7 @@ func TestTransitions_ResumeIndexing(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, StatusIndex, st.status)
 		assert.Equal(t, header.Height+1, st.height)
+		assert.Equal(t, header.Height, st.indexed)
 		assert.Equal(t, flow.DummyStateCommitment, st.last)
 		assert.Equal(t, commit, st.next)
 	})
@@ -1176,6 +2017,152 @@ func TestTransitions_ResumeIndexing(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("resumes from height of initial commitment", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.RootFunc = func() (uint64, error) {
+			return header.Height, nil
+		}
+
+		loader := loader.BaselineMock(t)
+		loader.TrieFunc = func() (*trie.Trie, error) {
+			return tree, nil
+		}
+
+		reader := mocks.BaselineReader(t)
+		reader.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		reader.CommitFunc = func(height uint64) (flow.StateCommitment, error) {
+			if height == header.Height+3 {
+				return commit, nil
+			}
+			return differentCommit, nil
+		}
+
+		tr, st := baselineFSM(
+			t,
+			StatusResume,
+			withReader(reader),
+			withLoader(loader),
+			withChain(chain),
+		)
+		tr.cfg.InitialHeight = header.Height + 3
+
+		err := tr.ResumeIndexing(st)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusIndex, st.status)
+		assert.Equal(t, header.Height+4, st.height)
+		assert.Equal(t, commit, st.next)
+	})
+
+	t.Run("handles initial height above last indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.RootFunc = func() (uint64, error) {
+			return header.Height, nil
+		}
+
+		loader := loader.BaselineMock(t)
+		loader.TrieFunc = func() (*trie.Trie, error) {
+			return tree, nil
+		}
+
+		reader := mocks.BaselineReader(t)
+		reader.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		reader.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return commit, nil
+		}
+
+		tr, st := baselineFSM(
+			t,
+			StatusResume,
+			withReader(reader),
+			withLoader(loader),
+			withChain(chain),
+		)
+		tr.cfg.InitialHeight = header.Height + 11
+
+		err := tr.ResumeIndexing(st)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles initial height below first indexed height", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.RootFunc = func() (uint64, error) {
+			return header.Height, nil
+		}
+
+		loader := loader.BaselineMock(t)
+		loader.TrieFunc = func() (*trie.Trie, error) {
+			return tree, nil
+		}
+
+		reader := mocks.BaselineReader(t)
+		reader.LastFunc = func() (uint64, error) {
+			return header.Height + 10, nil
+		}
+		reader.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return commit, nil
+		}
+
+		tr, st := baselineFSM(
+			t,
+			StatusResume,
+			withReader(reader),
+			withLoader(loader),
+			withChain(chain),
+		)
+		tr.cfg.InitialHeight = header.Height - 1
+
+		err := tr.ResumeIndexing(st)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles mismatch between tree root hash and commit at initial height", func(t *testing.T) {
+		t.Parallel()
+
+		chain := mocks.BaselineChain(t)
+		chain.RootFunc = func() (uint64, error) {
+			return header.Height, nil
+		}
+
+		loader := loader.BaselineMock(t)
+		loader.TrieFunc = func() (*trie.Trie, error) {
+			return tree, nil
+		}
+
+		reader := mocks.BaselineReader(t)
+		reader.LastFunc = func() (uint64, error) {
+			return header.Height, nil
+		}
+		reader.CommitFunc = func(uint64) (flow.StateCommitment, error) {
+			return differentCommit, nil
+		}
+
+		tr, st := baselineFSM(
+			t,
+			StatusResume,
+			withReader(reader),
+			withLoader(loader),
+			withChain(chain),
+		)
+		tr.cfg.InitialHeight = header.Height
+
+		err := tr.ResumeIndexing(st)
+
+		assert.Error(t, err)
+	})
+
 	t.Run("handles invalid status", func(t *testing.T) {
 		t.Parallel()
 
@@ -1211,6 +2198,57 @@ func TestTransitions_ResumeIndexing(t *testing.T) {
 	})
 }
 
+func TestSampleLogger(t *testing.T) {
+	count := func(buf *bytes.Buffer) int {
+		return bytes.Count(buf.Bytes(), []byte("\n"))
+	}
+
+	t.Run("samples debug and info messages", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		log := sampleLogger(zerolog.New(&buf), 10)
+
+		for i := 0; i < 100; i++ {
+			log.Debug().Msg("debug")
+		}
+		assert.Equal(t, 10, count(&buf))
+
+		buf.Reset()
+		for i := 0; i < 100; i++ {
+			log.Info().Msg("info")
+		}
+		assert.Equal(t, 10, count(&buf))
+	})
+
+	t.Run("always logs warnings and errors", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		log := sampleLogger(zerolog.New(&buf), 10)
+
+		for i := 0; i < 100; i++ {
+			log.Warn().Msg("warning")
+			log.Error().Msg("error")
+		}
+		assert.Equal(t, 200, count(&buf))
+	})
+
+	t.Run("does not sample below two", func(t *testing.T) {
+		t.Parallel()
+
+		for _, n := range []uint32{0, 1} {
+			var buf bytes.Buffer
+			log := sampleLogger(zerolog.New(&buf), n)
+
+			for i := 0; i < 100; i++ {
+				log.Debug().Msg("debug")
+			}
+			assert.Equal(t, 100, count(&buf))
+		}
+	})
+}
+
 func baselineFSM(t *testing.T, status Status, opts ...func(tr *Transitions)) (*Transitions, *State) {
 	t.Helper()
 
@@ -1230,13 +2268,14 @@ func baselineFSM(t *testing.T, status Status, opts ...func(tr *Transitions)) (*T
 			SkipRegisters:  false,
 			WaitInterval:   0,
 		},
-		log:   mocks.NoopLogger,
-		load:  load,
-		chain: chain,
-		feed:  feeder,
-		read:  read,
-		write: write,
-		once:  once,
+		log:     mocks.NoopLogger,
+		sampled: mocks.NoopLogger,
+		load:    load,
+		chain:   chain,
+		feed:    feeder,
+		read:    read,
+		write:   write,
+		once:    once,
 	}
 
 	for _, opt := range opts {
@@ -1250,6 +2289,8 @@ func baselineFSM(t *testing.T, status Status, opts ...func(tr *Transitions)) (*T
 		last:      mocks.GenericCommit(1),
 		next:      mocks.GenericCommit(0),
 		registers: make(map[ledger.Path]*ledger.Payload),
+		collected: make(map[ledger.Path]struct{}),
+		blocks:    make(map[flow.Identifier]uint64),
 		done:      doneCh,
 	}
 
@@ -1285,3 +2326,19 @@ func withWriter(write dps.Writer) func(*Transitions) {
 		tr.write = write
 	}
 }
+
+type inspector struct {
+	CompleteFunc func(height uint64, classes dps.Classes) (bool, error)
+}
+
+func baselineInspector(complete bool) *inspector {
+	return &inspector{
+		CompleteFunc: func(uint64, dps.Classes) (bool, error) {
+			return complete, nil
+		},
+	}
+}
+
+func (i *inspector) Complete(height uint64, classes dps.Classes) (bool, error) {
+	return i.CompleteFunc(height, classes)
+}
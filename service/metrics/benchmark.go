@@ -0,0 +1,127 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Benchmark executes a function for each height of a height range, with a
+// bounded number of concurrent executions, and logs the latency percentiles of
+// the executions once it is done or stopped.
+type Benchmark struct {
+	log         zerolog.Logger
+	concurrency uint
+	repeat      uint
+	stop        chan struct{}
+	once        sync.Once
+}
+
+// NewBenchmark creates a new benchmark that runs at most the given number of
+// executions concurrently, and executes the function the given number of times
+// for each height.
+func NewBenchmark(log zerolog.Logger, concurrency uint, repeat uint) *Benchmark {
+
+	b := Benchmark{
+		log:         log,
+		concurrency: concurrency,
+		repeat:      repeat,
+		stop:        make(chan struct{}),
+	}
+
+	return &b
+}
+
+// Run executes the given function for each height from first to last, until
+// all executions are done or the benchmark is stopped. Failed executions are
+// logged and counted, but their latency is not collected.
+func (b *Benchmark) Run(first uint64, last uint64, execute func(height uint64) error) (*Latencies, error) {
+
+	if b.concurrency == 0 {
+		return nil, fmt.Errorf("concurrency needs to be at least one")
+	}
+	if first > last {
+		return nil, fmt.Errorf("invalid height range (first: %d, last: %d)", first, last)
+	}
+
+	// We feed the heights through a channel to a bounded number of workers,
+	// which execute the function and record the latency of each execution.
+	heights := make(chan uint64)
+	var failed uint64
+	var wg sync.WaitGroup
+	var durations Latencies
+	for i := uint(0); i < b.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for height := range heights {
+				start := time.Now()
+				err := execute(height)
+				if err != nil {
+					atomic.AddUint64(&failed, 1)
+					b.log.Warn().Uint64("height", height).Err(err).Msg("could not execute benchmark")
+					continue
+				}
+				durations.Add(time.Since(start))
+			}
+		}()
+	}
+
+	start := time.Now()
+	b.log.Info().Uint64("first", first).Uint64("last", last).Uint("concurrency", b.concurrency).Msg("benchmark starting")
+
+Feed:
+	for height := first; height <= last; height++ {
+		for i := uint(0); i < b.repeat; i++ {
+			select {
+			case <-b.stop:
+				break Feed
+			case heights <- height:
+			}
+		}
+	}
+	close(heights)
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	count := durations.Count()
+
+	b.log.Info().
+		Int("executed", count).
+		Uint64("failed", failed).
+		Dur("elapsed", elapsed).
+		Float64("throughput", float64(count)/elapsed.Seconds()).
+		Dur("p50", durations.Percentile(0.50)).
+		Dur("p90", durations.Percentile(0.90)).
+		Dur("p99", durations.Percentile(0.99)).
+		Dur("max", durations.Percentile(1.0)).
+		Msg("benchmark done")
+
+	return &durations, nil
+}
+
+// Stop stops feeding heights to the executions of the benchmark, so that it
+// finishes once the ongoing executions are done.
+func (b *Benchmark) Stop() {
+	b.once.Do(func() {
+		b.log.Info().Msg("benchmark stopping")
+		close(b.stop)
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package metrics_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/metrics"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestBenchmark(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var mutex sync.Mutex
+		executed := make(map[uint64]int)
+
+		bench := metrics.NewBenchmark(mocks.NoopLogger, 3, 2)
+		latencies, err := bench.Run(10, 14, func(height uint64) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			executed[height]++
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[uint64]int{10: 2, 11: 2, 12: 2, 13: 2, 14: 2}, executed)
+		assert.Equal(t, 10, latencies.Count())
+	})
+
+	t.Run("does not collect latencies of failed executions", func(t *testing.T) {
+		t.Parallel()
+
+		bench := metrics.NewBenchmark(mocks.NoopLogger, 2, 1)
+		latencies, err := bench.Run(10, 14, func(height uint64) error {
+			if height%2 == 0 {
+				return mocks.GenericError
+			}
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, latencies.Count())
+	})
+
+	t.Run("stops feeding heights when stopped", func(t *testing.T) {
+		t.Parallel()
+
+		bench := metrics.NewBenchmark(mocks.NoopLogger, 1, 1)
+		latencies, err := bench.Run(10, 1000, func(height uint64) error {
+			if height == 12 {
+				bench.Stop()
+			}
+			return nil
+		})
+
+		require.NoError(t, err)
+		// Heights that were already fed to the worker are still executed, so
+		// the benchmark only stops a few heights later.
+		assert.Less(t, latencies.Count(), 100)
+
+		// Stopping again should not panic.
+		bench.Stop()
+	})
+
+	t.Run("handles invalid concurrency", func(t *testing.T) {
+		t.Parallel()
+
+		bench := metrics.NewBenchmark(mocks.NoopLogger, 0, 1)
+		_, err := bench.Run(10, 14, func(uint64) error {
+			return nil
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles invalid height range", func(t *testing.T) {
+		t.Parallel()
+
+		bench := metrics.NewBenchmark(mocks.NoopLogger, 1, 1)
+		_, err := bench.Run(14, 10, func(uint64) error {
+			return nil
+		})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Latencies collects durations concurrently, for example those of script
+// executions during a benchmark, so that percentiles can be computed once the
+// benchmark is done.
+type Latencies struct {
+	mutex     sync.Mutex
+	durations []time.Duration
+}
+
+// Add adds the given duration to the collected durations.
+func (l *Latencies) Add(duration time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.durations = append(l.durations, duration)
+}
+
+// Count returns the number of collected durations.
+func (l *Latencies) Count() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.durations)
+}
+
+// Percentile returns the duration below which the given fraction of the
+// collected durations fall, using the nearest-rank method.
+func (l *Latencies) Percentile(fraction float64) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.durations) == 0 {
+		return 0
+	}
+
+	sort.Slice(l.durations, func(i int, j int) bool {
+		return l.durations[i] < l.durations[j]
+	})
+
+	rank := int(math.Ceil(fraction*float64(len(l.durations)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(l.durations) {
+		rank = len(l.durations) - 1
+	}
+
+	return l.durations[rank]
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package metrics_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optakt/flow-dps/service/metrics"
+)
+
+func TestLatencies(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var latencies metrics.Latencies
+
+		// Add the durations from 1ms to 100ms concurrently and out of order.
+		var wg sync.WaitGroup
+		for i := 100; i > 0; i-- {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				latencies.Add(time.Duration(i) * time.Millisecond)
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 100, latencies.Count())
+		assert.Equal(t, 1*time.Millisecond, latencies.Percentile(0))
+		assert.Equal(t, 50*time.Millisecond, latencies.Percentile(0.50))
+		assert.Equal(t, 90*time.Millisecond, latencies.Percentile(0.90))
+		assert.Equal(t, 99*time.Millisecond, latencies.Percentile(0.99))
+		assert.Equal(t, 100*time.Millisecond, latencies.Percentile(1.0))
+	})
+
+	t.Run("rounds rank up", func(t *testing.T) {
+		t.Parallel()
+
+		var latencies metrics.Latencies
+		for i := 1; i <= 5; i++ {
+			latencies.Add(time.Duration(i) * time.Millisecond)
+		}
+
+		// With five durations, the rank of the 25th percentile is 1.25, which
+		// has to be rounded up to the second duration.
+		assert.Equal(t, 2*time.Millisecond, latencies.Percentile(0.25))
+	})
+
+	t.Run("single duration", func(t *testing.T) {
+		t.Parallel()
+
+		var latencies metrics.Latencies
+		latencies.Add(time.Second)
+
+		assert.Equal(t, 1, latencies.Count())
+		assert.Equal(t, time.Second, latencies.Percentile(0.50))
+		assert.Equal(t, time.Second, latencies.Percentile(0.99))
+	})
+
+	t.Run("handles no durations", func(t *testing.T) {
+		t.Parallel()
+
+		var latencies metrics.Latencies
+
+		assert.Zero(t, latencies.Count())
+		assert.Zero(t, latencies.Percentile(0.50))
+	})
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/rs/zerolog"
+)
+
+// Pusher pushes the metrics of the default registry to a Prometheus pushgateway.
+// It is meant for short-lived batch jobs, for which scraping metrics is not
+// practical.
+type Pusher struct {
+	log      zerolog.Logger
+	pusher   *push.Pusher
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// NewPusher creates a new pusher that pushes metrics to the pushgateway at the
+// given URL under the given job name, once per interval while it is running.
+func NewPusher(log zerolog.Logger, url string, job string, interval time.Duration) *Pusher {
+
+	p := Pusher{
+		log:      log.With().Str("component", "pusher").Logger(),
+		pusher:   push.New(url, job).Gatherer(prometheus.DefaultGatherer),
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+
+	return &p
+}
+
+// Start pushes the metrics at regular intervals until the pusher is stopped.
+// Failures to push are logged, so that an unavailable pushgateway does not
+// interrupt the job.
+func (p *Pusher) Start() error {
+	if p.interval == 0 {
+		<-p.stop
+		return nil
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		err := p.Push()
+		if err != nil {
+			p.log.Warn().Err(err).Msg("could not push metrics")
+		}
+	}
+}
+
+// Stop stops the regular pushing of metrics.
+func (p *Pusher) Stop() {
+	close(p.stop)
+}
+
+// Push pushes the current metrics to the pushgateway, replacing all metrics
+// previously pushed for the job. It should be called once more when the job
+// completes, so that the final values of the metrics are recorded.
+func (p *Pusher) Push() error {
+	err := p.pusher.Push()
+	if err != nil {
+		return fmt.Errorf("could not push to pushgateway: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/service/metrics"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestPusher_Push(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			method string
+			path   string
+			body   []byte
+		)
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			path = r.URL.Path
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer gateway.Close()
+
+		pusher := metrics.NewPusher(mocks.NoopLogger, gateway.URL, "indexer", 0)
+
+		err := pusher.Push()
+
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPut, method)
+		assert.Equal(t, "/metrics/job/indexer", path)
+		assert.NotEmpty(t, body)
+	})
+
+	t.Run("handles pushgateway failure", func(t *testing.T) {
+		t.Parallel()
+
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer gateway.Close()
+
+		pusher := metrics.NewPusher(mocks.NoopLogger, gateway.URL, "indexer", 0)
+
+		err := pusher.Push()
+
+		assert.Error(t, err)
+	})
+}
+
+func TestPusher_Start(t *testing.T) {
+	var (
+		mutex  sync.Mutex
+		pushes int
+	)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		pushes++
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	pusher := metrics.NewPusher(mocks.NoopLogger, gateway.URL, "indexer", 10*time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		done <- pusher.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return pushes >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	pusher.Stop()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("pusher did not stop")
+	}
+}
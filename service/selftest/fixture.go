@@ -0,0 +1,176 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package selftest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/ledger/trie"
+	"github.com/optakt/flow-dps/models/convert"
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// fixture is a tiny synthetic chain, which changes a single ledger register at
+// each height. It provides the chain data, the trie updates and the root trie
+// that the mapper needs to index it.
+type fixture struct {
+	root    uint64
+	regID   flow.RegisterID
+	path    ledger.Path
+	tree    *trie.Trie
+	headers []*flow.Header
+	commits []flow.StateCommitment
+	updates []*ledger.TrieUpdate
+	next    int
+}
+
+// newFixture creates a fixture with the given number of blocks after the root
+// block at the given height.
+func newFixture(root uint64, blocks int) (*fixture, error) {
+
+	regID := flow.NewRegisterID(string(flow.HexToAddress("01").Bytes()), "", "selftest")
+	path, err := convert.RegisterIDToPath(regID)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert register ID: %w", err)
+	}
+
+	f := fixture{
+		root:  root,
+		regID: regID,
+		path:  path,
+	}
+
+	// For each height, we build the header and apply a trie update that sets
+	// the register to the height, starting with the root trie.
+	tree := trie.NewEmptyTrie()
+	parentID := flow.ZeroID
+	timestamp := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for height := root; height <= root+uint64(blocks); height++ {
+
+		payload := ledger.NewPayload(convert.RegisterIDToKey(regID), f.Value(height))
+		parent := tree.RootHash()
+		tree, err = tree.Mutate([]ledger.Path{path}, []ledger.Payload{*payload})
+		if err != nil {
+			return nil, fmt.Errorf("could not mutate trie (height: %d): %w", height, err)
+		}
+
+		// The root trie is loaded directly, while all other changes are fed as
+		// trie updates.
+		if height == root {
+			f.tree = tree
+		} else {
+			update := ledger.TrieUpdate{
+				RootHash: parent,
+				Paths:    []ledger.Path{path},
+				Payloads: []*ledger.Payload{payload},
+			}
+			f.updates = append(f.updates, &update)
+		}
+
+		header := flow.Header{
+			ChainID:   flow.Emulator,
+			ParentID:  parentID,
+			Height:    height,
+			Timestamp: timestamp.Add(time.Duration(height-root) * time.Second),
+		}
+		parentID = header.ID()
+
+		f.headers = append(f.headers, &header)
+		f.commits = append(f.commits, flow.StateCommitment(tree.RootHash()))
+	}
+
+	return &f, nil
+}
+
+// Last returns the height of the last block of the fixture.
+func (f *fixture) Last() uint64 {
+	return f.root + uint64(len(f.headers)) - 1
+}
+
+// Value returns the value of the fixture's register at the given height.
+func (f *fixture) Value(height uint64) ledger.Value {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, height)
+	return value
+}
+
+// Trie returns the root trie of the fixture.
+func (f *fixture) Trie() (*trie.Trie, error) {
+	return f.tree, nil
+}
+
+// Update returns the next trie update of the fixture.
+func (f *fixture) Update() (*ledger.TrieUpdate, error) {
+	if f.next >= len(f.updates) {
+		return nil, dps.ErrUnavailable
+	}
+	update := f.updates[f.next]
+	f.next++
+	return update, nil
+}
+
+// Root returns the root height of the fixture.
+func (f *fixture) Root() (uint64, error) {
+	return f.root, nil
+}
+
+// Header returns the header at the given height. It returns `dps.ErrFinished`
+// after the last block, so that the mapper stops.
+func (f *fixture) Header(height uint64) (*flow.Header, error) {
+	if height > f.Last() {
+		return nil, dps.ErrFinished
+	}
+	return f.headers[height-f.root], nil
+}
+
+// Commit returns the state commitment at the given height.
+func (f *fixture) Commit(height uint64) (flow.StateCommitment, error) {
+	return f.commits[height-f.root], nil
+}
+
+// Events returns no events, as the fixture has no transactions.
+func (f *fixture) Events(uint64) ([]flow.Event, error) {
+	return nil, nil
+}
+
+// Collections returns no collections, as the fixture has no transactions.
+func (f *fixture) Collections(uint64) ([]*flow.LightCollection, error) {
+	return nil, nil
+}
+
+// Guarantees returns no guarantees, as the fixture has no transactions.
+func (f *fixture) Guarantees(uint64) ([]*flow.CollectionGuarantee, error) {
+	return nil, nil
+}
+
+// Transactions returns no transactions.
+func (f *fixture) Transactions(uint64) ([]*flow.TransactionBody, error) {
+	return nil, nil
+}
+
+// Results returns no transaction results, as the fixture has no transactions.
+func (f *fixture) Results(uint64) ([]*flow.TransactionResult, error) {
+	return nil, nil
+}
+
+// Seals returns no seals.
+func (f *fixture) Seals(uint64) ([]*flow.Seal, error) {
+	return nil, nil
+}
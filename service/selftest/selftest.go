@@ -0,0 +1,194 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package selftest
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go/ledger"
+
+	api "github.com/optakt/flow-dps/api/dps"
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/ledger/forest"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/service/index"
+	"github.com/optakt/flow-dps/service/invoker"
+	"github.com/optakt/flow-dps/service/mapper"
+	"github.com/optakt/flow-dps/service/storage"
+)
+
+const (
+	root   = 100
+	blocks = 4
+)
+
+// script returns the successor of the height it is given as argument.
+const script = `pub fun main(height: UInt64): UInt64 { return height + 1 }`
+
+// Run runs a self-test of the full loop from indexing to serving the index. It
+// indexes a tiny synthetic chain with the mapper into a new index in the given
+// directory, serves the index with the DPS API on a local port, and checks
+// through an API client that the indexed data can be read back and that a
+// script can be executed against it.
+func Run(log zerolog.Logger, dir string) error {
+
+	fixture, err := newFixture(root, blocks)
+	if err != nil {
+		return fmt.Errorf("could not create fixture: %w", err)
+	}
+
+	db, err := badger.Open(dps.DefaultOptions(dir))
+	if err != nil {
+		return fmt.Errorf("could not open index database: %w", err)
+	}
+	defer db.Close()
+
+	// First, we index the fixture with the mapper, which uses the fixture as
+	// its chain, its feeder and its loader.
+	codec := zbor.NewCodec()
+	lib := storage.New(codec)
+	read := index.NewReader(db, lib)
+	write := index.NewWriter(log, db, lib)
+	transitions := mapper.NewTransitions(log, fixture, fixture, fixture, read, write,
+		mapper.WithBootstrapState(true),
+	)
+	state := mapper.EmptyState(forest.New())
+	fsm := mapper.NewFSM(state,
+		mapper.WithTransition(mapper.StatusInitialize, transitions.InitializeMapper),
+		mapper.WithTransition(mapper.StatusBootstrap, transitions.BootstrapState),
+		mapper.WithTransition(mapper.StatusResume, transitions.ResumeIndexing),
+		mapper.WithTransition(mapper.StatusIndex, transitions.IndexChain),
+		mapper.WithTransition(mapper.StatusUpdate, transitions.UpdateTree),
+		mapper.WithTransition(mapper.StatusCollect, transitions.CollectRegisters),
+		mapper.WithTransition(mapper.StatusMap, transitions.MapRegisters),
+		mapper.WithTransition(mapper.StatusForward, transitions.ForwardHeight),
+	)
+	err = fsm.Run()
+	if err != nil {
+		_ = write.Close()
+		return fmt.Errorf("could not index fixture: %w", err)
+	}
+	err = write.Close()
+	if err != nil {
+		return fmt.Errorf("could not close index writer: %w", err)
+	}
+
+	log.Info().Uint64("root", root).Uint64("last", fixture.Last()).Msg("fixture indexed")
+
+	// Next, we serve the index with the DPS API on a random local port.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("could not create listener: %w", err)
+	}
+	gsvr := grpc.NewServer()
+	api.RegisterAPIServer(gsvr, api.NewServer(read, codec))
+	go func() {
+		_ = gsvr.Serve(listener)
+	}()
+	defer gsvr.Stop()
+
+	conn, err := api.Dial(listener.Addr().String())
+	if err != nil {
+		return fmt.Errorf("could not dial API: %w", err)
+	}
+	defer conn.Close()
+	client := api.IndexFromAPI(api.NewAPIClient(conn), codec)
+
+	log.Info().Str("address", listener.Addr().String()).Msg("index served")
+
+	// Finally, we check the indexed data through the API client.
+	err = check(client, fixture)
+	if err != nil {
+		return fmt.Errorf("could not verify indexed data: %w", err)
+	}
+
+	log.Info().Msg("indexed data verified")
+
+	// Last but not least, the invoker should be able to execute a script
+	// against the index served by the API.
+	invoke, err := invoker.New(client)
+	if err != nil {
+		return fmt.Errorf("could not initialize invoker: %w", err)
+	}
+	last := fixture.Last()
+	args := []cadence.Value{cadence.NewUInt64(last)}
+	value, err := invoke.Script(last, []byte(script), args)
+	if err != nil {
+		return fmt.Errorf("could not execute script: %w", err)
+	}
+	if value != cadence.NewUInt64(last+1) {
+		return fmt.Errorf("unexpected script result (expected: %d, actual: %s)", last+1, value)
+	}
+
+	log.Info().Str("result", value.String()).Msg("script executed")
+
+	return nil
+}
+
+// check verifies that the data of the given fixture can be read back from the
+// given index.
+func check(index dps.Reader, fixture *fixture) error {
+
+	first, err := index.First()
+	if err != nil {
+		return fmt.Errorf("could not get first height: %w", err)
+	}
+	if first != root {
+		return fmt.Errorf("unexpected first height (expected: %d, actual: %d)", root, first)
+	}
+	last, err := index.Last()
+	if err != nil {
+		return fmt.Errorf("could not get last height: %w", err)
+	}
+	if last != fixture.Last() {
+		return fmt.Errorf("unexpected last height (expected: %d, actual: %d)", fixture.Last(), last)
+	}
+
+	for height := first; height <= last; height++ {
+		header, err := index.Header(height)
+		if err != nil {
+			return fmt.Errorf("could not get header (height: %d): %w", height, err)
+		}
+		expected, _ := fixture.Header(height)
+		if header.ID() != expected.ID() {
+			return fmt.Errorf("unexpected header (height: %d, expected: %x, actual: %x)", height, expected.ID(), header.ID())
+		}
+
+		commit, err := index.Commit(height)
+		if err != nil {
+			return fmt.Errorf("could not get commit (height: %d): %w", height, err)
+		}
+		if commit != fixture.commits[height-root] {
+			return fmt.Errorf("unexpected commit (height: %d, expected: %x, actual: %x)", height, fixture.commits[height-root], commit)
+		}
+
+		values, err := index.Values(height, []ledger.Path{fixture.path})
+		if err != nil {
+			return fmt.Errorf("could not get register value (height: %d): %w", height, err)
+		}
+		if len(values) != 1 || !bytes.Equal(values[0], fixture.Value(height)) {
+			return fmt.Errorf("unexpected register value (height: %d, expected: %x, actual: %x)", height, fixture.Value(height), values)
+		}
+	}
+
+	return nil
+}
@@ -15,10 +15,18 @@
 package storage
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/hashicorp/go-multierror"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/ledger/common/pathfinder"
+
+	"github.com/optakt/flow-dps/models/dps"
 )
 
 // Fallback goes through the provided operations until one of them succeeds.
@@ -54,9 +62,31 @@ func Combine(ops ...func(*badger.Txn) error) func(*badger.Txn) error {
 	}
 }
 
+// notFound returns the error for a value that is missing from the index, based
+// on the prefix of its key. Keys without a more specific sentinel error keep
+// the generic `badger.ErrKeyNotFound` error.
+func notFound(key []byte) error {
+	if len(key) == 0 {
+		return badger.ErrKeyNotFound
+	}
+	switch key[0] {
+	case PrefixCommit, PrefixHeader, PrefixCollectionsForHeight, PrefixTransactionsForHeight, PrefixSealsForHeight:
+		return dps.ErrHeightNotIndexed
+	case PrefixPayload:
+		return dps.ErrRegisterNotFound
+	case PrefixHeightForBlock:
+		return dps.ErrBlockNotFound
+	default:
+		return badger.ErrKeyNotFound
+	}
+}
+
 func (l *Library) retrieve(key []byte, v interface{}) func(tx *badger.Txn) error {
 	return func(tx *badger.Txn) error {
 		item, err := tx.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return fmt.Errorf("could not get value (key: %x): %w", key, notFound(key))
+		}
 		if err != nil {
 			return fmt.Errorf("could not get value (key: %x): %w", key, err)
 		}
@@ -87,3 +117,53 @@ func (l *Library) save(key []byte, value interface{}) func(*badger.Txn) error {
 		return nil
 	}
 }
+
+// anySkipped returns whether the payload of any register was skipped in the
+// index. The answer is looked up once and then cached by the library, which
+// keeps it up to date when it records skipped payloads itself.
+func (l *Library) anySkipped(tx *badger.Txn) bool {
+
+	switch atomic.LoadInt32(&l.skipped) {
+	case skippedNone:
+		return false
+	case skippedSome:
+		return true
+	}
+
+	it := tx.NewIterator(badger.IteratorOptions{
+		PrefetchValues: false,
+		Prefix:         []byte{PrefixSkipped},
+	})
+	defer it.Close()
+
+	it.Rewind()
+	found := it.Valid()
+
+	state := skippedNone
+	if found {
+		state = skippedSome
+	}
+	atomic.CompareAndSwapInt32(&l.skipped, skippedUnknown, state)
+
+	return found
+}
+
+// lastSkipped returns the highest height at or below the given height at which
+// the payload of the register at the given path was skipped, if there is one.
+func lastSkipped(tx *badger.Txn, height uint64, path ledger.Path) (uint64, bool) {
+
+	key := EncodeKey(PrefixSkipped, path, height)
+	it := tx.NewIterator(badger.IteratorOptions{
+		PrefetchValues: false,
+		Reverse:        true,
+		Prefix:         key[:1+pathfinder.PathByteSize],
+	})
+	defer it.Close()
+
+	it.Seek(key)
+	if !it.Valid() {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint64(it.Item().Key()[1+pathfinder.PathByteSize:]), true
+}
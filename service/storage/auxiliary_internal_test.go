@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/testing/helpers"
 	"github.com/optakt/flow-dps/testing/mocks"
 )
@@ -100,6 +101,75 @@ func TestLibrary_Retrieve(t *testing.T) {
 	})
 }
 
+func TestLibrary_RetrieveNotFound(t *testing.T) {
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	l := &Library{
+		codec: mocks.BaselineCodec(t),
+	}
+
+	tests := []struct {
+		name string
+
+		key []byte
+
+		wantErr error
+	}{
+		{
+			name:    "header",
+			key:     EncodeKey(PrefixHeader, mocks.GenericHeight),
+			wantErr: dps.ErrHeightNotIndexed,
+		},
+		{
+			name:    "commit",
+			key:     EncodeKey(PrefixCommit, mocks.GenericHeight),
+			wantErr: dps.ErrHeightNotIndexed,
+		},
+		{
+			name:    "collections for height",
+			key:     EncodeKey(PrefixCollectionsForHeight, mocks.GenericHeight),
+			wantErr: dps.ErrHeightNotIndexed,
+		},
+		{
+			name:    "transactions for height",
+			key:     EncodeKey(PrefixTransactionsForHeight, mocks.GenericHeight),
+			wantErr: dps.ErrHeightNotIndexed,
+		},
+		{
+			name:    "seals for height",
+			key:     EncodeKey(PrefixSealsForHeight, mocks.GenericHeight),
+			wantErr: dps.ErrHeightNotIndexed,
+		},
+		{
+			name:    "payload",
+			key:     EncodeKey(PrefixPayload, mocks.GenericLedgerPath(0), mocks.GenericHeight),
+			wantErr: dps.ErrRegisterNotFound,
+		},
+		{
+			name:    "height for block",
+			key:     EncodeKey(PrefixHeightForBlock, mocks.GenericHeader.ID()),
+			wantErr: dps.ErrBlockNotFound,
+		},
+		{
+			name:    "transaction",
+			key:     EncodeKey(PrefixTransaction, mocks.GenericTransaction(0).ID()),
+			wantErr: badger.ErrKeyNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var got uint64
+			err := db.View(l.retrieve(test.key, &got))
+
+			assert.ErrorIs(t, err, test.wantErr)
+			assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+		})
+	}
+}
+
 func TestLibrary_Save(t *testing.T) {
 	db := helpers.InMemoryDB(t)
 	defer db.Close()
@@ -0,0 +1,267 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/onflow/flow-go/ledger/common/pathfinder"
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// ClassSize contains the size of the indexed data of a class. The stored size
+// is the size of the keys and of the compressed values, as they are stored in
+// the index, while the raw size uses the size of the values before they were
+// compressed.
+type ClassSize struct {
+	Keys   uint64
+	Stored uint64
+	Raw    uint64
+}
+
+// Sizes contains the size of the indexed data of each class for a number of
+// consecutive blocks.
+type Sizes struct {
+	Blocks  uint64
+	Classes map[dps.Classes]*ClassSize
+}
+
+// Estimate is the projected storage of a class of indexed data. The ratio is
+// the compression ratio of its values, which is the stored size divided by the
+// raw size.
+type Estimate struct {
+	PerBlock float64
+	Ratio    float64
+	Total    uint64
+}
+
+// Project returns the estimated storage of each class of indexed data for the
+// given number of blocks, based on the average size per block of the sample.
+func (s Sizes) Project(blocks uint64) map[dps.Classes]Estimate {
+
+	estimates := make(map[dps.Classes]Estimate, len(s.Classes))
+	for class, size := range s.Classes {
+
+		var estimate Estimate
+		if s.Blocks != 0 {
+			estimate.PerBlock = float64(size.Stored) / float64(s.Blocks)
+		}
+		if size.Raw != 0 {
+			estimate.Ratio = float64(size.Stored) / float64(size.Raw)
+		}
+		estimate.Total = uint64(estimate.PerBlock*float64(blocks) + 0.5)
+
+		estimates[class] = estimate
+	}
+
+	return estimates
+}
+
+// SampleSizes is an operation that measures the size of the data indexed for
+// each height from first to last, grouped by class. Data that is indexed by
+// identifier is attributed to the height that references it. Register payloads
+// are not grouped by height in the index, so all payload keys are scanned to
+// find those in the range.
+func (l *Library) SampleSizes(first uint64, last uint64, sizes *Sizes) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+
+		sizes.Blocks = last - first + 1
+		sizes.Classes = make(map[dps.Classes]*ClassSize)
+		for _, class := range []dps.Classes{dps.ClassHeaders, dps.ClassRegisters, dps.ClassEvents, dps.ClassTransactions, dps.ClassResults} {
+			sizes.Classes[class] = &ClassSize{}
+		}
+
+		for height := first; height <= last; height++ {
+			err := l.sampleHeight(tx, height, sizes)
+			if err != nil {
+				return fmt.Errorf("could not sample height %d: %w", height, err)
+			}
+		}
+
+		prefix := EncodeKey(PrefixPayload)
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			key := item.Key()
+			if len(key) != 1+pathfinder.PathByteSize+8 {
+				continue
+			}
+			height := binary.BigEndian.Uint64(key[1+pathfinder.PathByteSize:])
+			if height < first || height > last {
+				continue
+			}
+			err := measure(item, sizes.Classes[dps.ClassRegisters])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// sampleHeight adds the size of the data indexed for the given height to the
+// given sizes, except for register payloads.
+func (l *Library) sampleHeight(tx *badger.Txn, height uint64, sizes *Sizes) error {
+
+	headers := sizes.Classes[dps.ClassHeaders]
+	transactions := sizes.Classes[dps.ClassTransactions]
+	results := sizes.Classes[dps.ClassResults]
+
+	// Keys that are indexed by height directly.
+	byHeight := map[uint8]*ClassSize{
+		PrefixHeader:                headers,
+		PrefixCommit:                headers,
+		PrefixSealsForHeight:        headers,
+		PrefixClasses:               headers,
+		PrefixFailure:               headers,
+		PrefixTransactionsForHeight: transactions,
+		PrefixCollectionsForHeight:  transactions,
+	}
+	for prefix, size := range byHeight {
+		err := l.sampleKey(tx, EncodeKey(prefix, height), size)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The block ID is needed to find the key of its height.
+	var header flow.Header
+	err := l.RetrieveHeader(height, &header)(tx)
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return fmt.Errorf("could not retrieve header: %w", err)
+	}
+	if err == nil {
+		err = l.sampleKey(tx, EncodeKey(PrefixHeightForBlock, header.ID()), headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keys that are indexed by identifiers listed for the height.
+	var sealIDs []flow.Identifier
+	err = l.LookupSealsForHeight(height, &sealIDs)(tx)
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return fmt.Errorf("could not look up seals: %w", err)
+	}
+	for _, sealID := range sealIDs {
+		err = l.sampleKey(tx, EncodeKey(PrefixSeal, sealID), headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	var collIDs []flow.Identifier
+	err = l.LookupCollectionsForHeight(height, &collIDs)(tx)
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return fmt.Errorf("could not look up collections: %w", err)
+	}
+	for _, collID := range collIDs {
+		for _, prefix := range []uint8{PrefixCollection, PrefixGuarantee, PrefixTransactionsForCollection} {
+			err = l.sampleKey(tx, EncodeKey(prefix, collID), transactions)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var txIDs []flow.Identifier
+	err = l.LookupTransactionsForHeight(height, &txIDs)(tx)
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return fmt.Errorf("could not look up transactions: %w", err)
+	}
+	for _, txID := range txIDs {
+		for _, prefix := range []uint8{PrefixTransaction, PrefixHeightForTransaction} {
+			err = l.sampleKey(tx, EncodeKey(prefix, txID), transactions)
+			if err != nil {
+				return err
+			}
+		}
+		err = l.sampleKey(tx, EncodeKey(PrefixResults, txID), results)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Events are stored under one key per event type.
+	prefix := EncodeKey(PrefixEvents, height)
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+
+	it := tx.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		err = measure(it.Item(), sizes.Classes[dps.ClassEvents])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sampleKey adds the size of the given key and its value to the given size, if
+// the key exists.
+func (l *Library) sampleKey(tx *badger.Txn, key []byte, size *ClassSize) error {
+	item, err := tx.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not get value (key: %x): %w", key, err)
+	}
+	return measure(item, size)
+}
+
+// measure adds the size of the given item to the given size. The raw size of
+// the value is taken from the frame header of its compression, and falls back
+// to its stored size if the header does not include it.
+func measure(item *badger.Item, size *ClassSize) error {
+
+	keySize := uint64(len(item.Key()))
+	valueSize := uint64(item.ValueSize())
+	rawSize := valueSize
+	err := item.Value(func(val []byte) error {
+		var header zstd.Header
+		err := header.Decode(val)
+		if err == nil && header.HasFCS {
+			rawSize = header.FrameContentSize
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not read value (key: %x): %w", item.Key(), err)
+	}
+
+	size.Keys++
+	size.Stored += keySize + valueSize
+	size.Raw += keySize + rawSize
+
+	return nil
+}
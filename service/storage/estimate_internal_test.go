@@ -0,0 +1,146 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestSizes_Project(t *testing.T) {
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		sizes := Sizes{
+			Blocks: 10,
+			Classes: map[dps.Classes]*ClassSize{
+				dps.ClassHeaders:   {Keys: 20, Stored: 1000, Raw: 2000},
+				dps.ClassRegisters: {Keys: 50, Stored: 5000, Raw: 5000},
+				dps.ClassEvents:    {Keys: 3, Stored: 7, Raw: 28},
+			},
+		}
+
+		got := sizes.Project(1000)
+
+		want := map[dps.Classes]Estimate{
+			dps.ClassHeaders:   {PerBlock: 100, Ratio: 0.5, Total: 100_000},
+			dps.ClassRegisters: {PerBlock: 500, Ratio: 1, Total: 500_000},
+			dps.ClassEvents:    {PerBlock: 0.7, Ratio: 0.25, Total: 700},
+		}
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("handles empty sample", func(t *testing.T) {
+		t.Parallel()
+
+		sizes := Sizes{
+			Blocks: 0,
+			Classes: map[dps.Classes]*ClassSize{
+				dps.ClassHeaders: {},
+			},
+		}
+
+		got := sizes.Project(1000)
+
+		assert.Equal(t, map[dps.Classes]Estimate{dps.ClassHeaders: {}}, got)
+	})
+}
+
+func TestLibrary_SampleSizes(t *testing.T) {
+	lib := New(zbor.NewCodec())
+	first := mocks.GenericHeight
+
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	paths := mocks.GenericLedgerPaths(2)
+	payloads := mocks.GenericLedgerPayloads(3)
+	transactions := mocks.GenericTransactions(2)
+	results := mocks.GenericResults(2)
+	txIDs := []flow.Identifier{transactions[0].ID(), transactions[1].ID()}
+	events := mocks.GenericEvents(2, mocks.GenericEventTypes(2)...)
+	err := db.Update(func(tx *badger.Txn) error {
+		ops := []func(*badger.Txn) error{
+			// The third height is outside of the sampled range.
+			lib.SavePayload(first, paths[0], payloads[0]),
+			lib.SavePayload(first+1, paths[1], payloads[1]),
+			lib.SavePayload(first+2, paths[0], payloads[2]),
+
+			lib.IndexTransactionsForHeight(first, txIDs),
+			lib.SaveTransaction(transactions[0]),
+			lib.SaveTransaction(transactions[1]),
+			lib.IndexHeightForTransaction(txIDs[0], first),
+			lib.IndexHeightForTransaction(txIDs[1], first),
+
+			lib.SaveEvents(first+1, events[0].Type, events[:1]),
+			lib.SaveEvents(first+1, events[1].Type, events[1:]),
+		}
+		for i := uint64(0); i < 3; i++ {
+			header := *mocks.GenericHeader
+			header.Height = first + i
+			ops = append(ops,
+				lib.SaveHeader(header.Height, &header),
+				lib.IndexHeightForBlock(header.ID(), header.Height),
+			)
+		}
+		for _, result := range results {
+			result.TransactionID = txIDs[0]
+			if result != results[0] {
+				result.TransactionID = txIDs[1]
+			}
+			ops = append(ops, lib.SaveResult(result))
+		}
+
+		for _, op := range ops {
+			err := op(tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var sizes Sizes
+	err = db.View(lib.SampleSizes(first, first+1, &sizes))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(2), sizes.Blocks)
+	require.Len(t, sizes.Classes, 5)
+
+	wantKeys := map[dps.Classes]uint64{
+		dps.ClassHeaders:      4,
+		dps.ClassRegisters:    2,
+		dps.ClassEvents:       2,
+		dps.ClassTransactions: 5,
+		dps.ClassResults:      2,
+	}
+	for class, keys := range wantKeys {
+		size := sizes.Classes[class]
+		assert.Equal(t, keys, size.Keys, "class %d", class)
+		assert.Positive(t, size.Stored, "class %d", class)
+		assert.Positive(t, size.Raw, "class %d", class)
+	}
+}
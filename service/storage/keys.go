@@ -0,0 +1,191 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// KeyInfo contains the decoded segments of an index key, as well as the size of
+// the value stored under it. Only the segments that are part of the key for the
+// given prefix are set.
+type KeyInfo struct {
+	Prefix uint8
+	Height uint64
+	ID     flow.Identifier
+	Path   ledger.Path
+	Hash   uint64
+	Size   int64
+}
+
+var prefixNames = map[uint8]string{
+	PrefixFirst:                     "first",
+	PrefixLast:                      "last",
+	PrefixHeightForBlock:            "height_for_block",
+	PrefixHeightForTransaction:      "height_for_transaction",
+	PrefixCommit:                    "commit",
+	PrefixHeader:                    "header",
+	PrefixEvents:                    "events",
+	PrefixPayload:                   "payload",
+	PrefixTransaction:               "transaction",
+	PrefixCollection:                "collection",
+	PrefixGuarantee:                 "guarantee",
+	PrefixTransactionsForHeight:     "transactions_for_height",
+	PrefixTransactionsForCollection: "transactions_for_collection",
+	PrefixCollectionsForHeight:      "collections_for_height",
+	PrefixResults:                   "results",
+	PrefixSeal:                      "seal",
+	PrefixSealsForHeight:            "seals_for_height",
+	PrefixFailure:                   "failure",
+	PrefixClasses:                   "classes",
+	PrefixMetadata:                  "metadata",
+	PrefixSkipped:                   "skipped",
+	PrefixSealed:                    "sealed",
+}
+
+// PrefixName returns the human-readable name of the given key prefix.
+func PrefixName(prefix uint8) string {
+	name, ok := prefixNames[prefix]
+	if !ok {
+		return fmt.Sprintf("unknown_%d", prefix)
+	}
+	return name
+}
+
+// PrefixByName returns the key prefix with the given human-readable name.
+func PrefixByName(name string) (uint8, bool) {
+	for prefix, candidate := range prefixNames {
+		if candidate == name {
+			return prefix, true
+		}
+	}
+	return 0, false
+}
+
+// String returns a human-readable representation of the key information.
+func (k KeyInfo) String() string {
+	s := PrefixName(k.Prefix)
+	switch k.Prefix {
+	case PrefixHeightForBlock, PrefixHeightForTransaction, PrefixTransaction, PrefixCollection,
+		PrefixGuarantee, PrefixTransactionsForCollection, PrefixResults, PrefixSeal:
+		s += fmt.Sprintf(" id=%x", k.ID)
+	case PrefixCommit, PrefixHeader, PrefixTransactionsForHeight, PrefixCollectionsForHeight, PrefixSealsForHeight,
+		PrefixFailure, PrefixClasses:
+		s += fmt.Sprintf(" height=%d", k.Height)
+	case PrefixEvents:
+		s += fmt.Sprintf(" height=%d hash=%x", k.Height, k.Hash)
+	case PrefixPayload, PrefixSkipped:
+		s += fmt.Sprintf(" path=%x height=%d", k.Path, k.Height)
+	}
+	return s + fmt.Sprintf(" size=%d", k.Size)
+}
+
+// DecodeKey decodes the segments of the given index key. It fails if the prefix
+// is unknown or if the key length does not match the prefix.
+func DecodeKey(key []byte) (KeyInfo, error) {
+
+	if len(key) == 0 {
+		return KeyInfo{}, fmt.Errorf("empty key")
+	}
+
+	info := KeyInfo{
+		Prefix: key[0],
+	}
+	segments := key[1:]
+
+	var want int
+	switch info.Prefix {
+
+	case PrefixFirst, PrefixLast, PrefixMetadata, PrefixSealed:
+		want = 0
+
+	case PrefixHeightForBlock, PrefixHeightForTransaction, PrefixTransaction, PrefixCollection,
+		PrefixGuarantee, PrefixTransactionsForCollection, PrefixResults, PrefixSeal:
+		want = 32
+		if len(segments) == want {
+			copy(info.ID[:], segments)
+		}
+
+	case PrefixCommit, PrefixHeader, PrefixTransactionsForHeight, PrefixCollectionsForHeight, PrefixSealsForHeight,
+		PrefixFailure, PrefixClasses:
+		want = 8
+		if len(segments) == want {
+			info.Height = binary.BigEndian.Uint64(segments)
+		}
+
+	case PrefixEvents:
+		want = 16
+		if len(segments) == want {
+			info.Height = binary.BigEndian.Uint64(segments[:8])
+			info.Hash = binary.BigEndian.Uint64(segments[8:])
+		}
+
+	case PrefixPayload, PrefixSkipped:
+		want = 40
+		if len(segments) == want {
+			copy(info.Path[:], segments[:32])
+			info.Height = binary.BigEndian.Uint64(segments[32:])
+		}
+
+	default:
+		return KeyInfo{}, fmt.Errorf("unknown key prefix (%d)", info.Prefix)
+	}
+
+	if len(segments) != want {
+		return KeyInfo{}, fmt.Errorf("invalid key length for prefix %s (got: %d, want: %d)", PrefixName(info.Prefix), len(segments), want)
+	}
+
+	return info, nil
+}
+
+// DumpKeys is an operation that decodes up to the given limit of keys starting
+// with the given prefix, along with the size of their values. A limit of zero
+// means that all matching keys are decoded. It is meant to help with debugging
+// the contents of an index.
+func DumpKeys(prefix []byte, limit int, infos *[]KeyInfo) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+
+			if limit > 0 && len(*infos) >= limit {
+				break
+			}
+
+			item := it.Item()
+			info, err := DecodeKey(item.Key())
+			if err != nil {
+				return fmt.Errorf("could not decode key (key: %x): %w", item.Key(), err)
+			}
+			info.Size = item.ValueSize()
+
+			*infos = append(*infos, info)
+		}
+
+		return nil
+	}
+}
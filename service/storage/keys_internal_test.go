@@ -0,0 +1,246 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/OneOfOne/xxhash"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestDecodeKey(t *testing.T) {
+	id := mocks.GenericHeader.ID()
+	path := mocks.GenericLedgerPath(0)
+	hash := xxhash.ChecksumString64(string(mocks.GenericEventType(0)))
+
+	tests := []struct {
+		name string
+
+		key []byte
+
+		wantInfo KeyInfo
+		checkErr require.ErrorAssertionFunc
+	}{
+		{
+			name:     "first",
+			key:      EncodeKey(PrefixFirst),
+			wantInfo: KeyInfo{Prefix: PrefixFirst},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "last",
+			key:      EncodeKey(PrefixLast),
+			wantInfo: KeyInfo{Prefix: PrefixLast},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "sealed",
+			key:      EncodeKey(PrefixSealed),
+			wantInfo: KeyInfo{Prefix: PrefixSealed},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "height for block",
+			key:      EncodeKey(PrefixHeightForBlock, id),
+			wantInfo: KeyInfo{Prefix: PrefixHeightForBlock, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "height for transaction",
+			key:      EncodeKey(PrefixHeightForTransaction, id),
+			wantInfo: KeyInfo{Prefix: PrefixHeightForTransaction, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "transaction",
+			key:      EncodeKey(PrefixTransaction, id),
+			wantInfo: KeyInfo{Prefix: PrefixTransaction, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "collection",
+			key:      EncodeKey(PrefixCollection, id),
+			wantInfo: KeyInfo{Prefix: PrefixCollection, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "guarantee",
+			key:      EncodeKey(PrefixGuarantee, id),
+			wantInfo: KeyInfo{Prefix: PrefixGuarantee, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "transactions for collection",
+			key:      EncodeKey(PrefixTransactionsForCollection, id),
+			wantInfo: KeyInfo{Prefix: PrefixTransactionsForCollection, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "results",
+			key:      EncodeKey(PrefixResults, id),
+			wantInfo: KeyInfo{Prefix: PrefixResults, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "seal",
+			key:      EncodeKey(PrefixSeal, id),
+			wantInfo: KeyInfo{Prefix: PrefixSeal, ID: id},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "commit",
+			key:      EncodeKey(PrefixCommit, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixCommit, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "header",
+			key:      EncodeKey(PrefixHeader, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixHeader, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "transactions for height",
+			key:      EncodeKey(PrefixTransactionsForHeight, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixTransactionsForHeight, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "collections for height",
+			key:      EncodeKey(PrefixCollectionsForHeight, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixCollectionsForHeight, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "seals for height",
+			key:      EncodeKey(PrefixSealsForHeight, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixSealsForHeight, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "failure",
+			key:      EncodeKey(PrefixFailure, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixFailure, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "events",
+			key:      EncodeKey(PrefixEvents, mocks.GenericHeight, hash),
+			wantInfo: KeyInfo{Prefix: PrefixEvents, Height: mocks.GenericHeight, Hash: hash},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "payload",
+			key:      EncodeKey(PrefixPayload, path, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixPayload, Path: path, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "skipped",
+			key:      EncodeKey(PrefixSkipped, path, mocks.GenericHeight),
+			wantInfo: KeyInfo{Prefix: PrefixSkipped, Path: path, Height: mocks.GenericHeight},
+			checkErr: require.NoError,
+		},
+		{
+			name:     "handles empty key",
+			key:      []byte{},
+			checkErr: require.Error,
+		},
+		{
+			name:     "handles unknown prefix",
+			key:      []byte{0xff},
+			checkErr: require.Error,
+		},
+		{
+			name:     "handles invalid key length",
+			key:      EncodeKey(PrefixPayload, path),
+			checkErr: require.Error,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DecodeKey(test.key)
+
+			test.checkErr(t, err)
+			if err == nil {
+				assert.Equal(t, test.wantInfo, got)
+			}
+		})
+	}
+}
+
+func TestDumpKeys(t *testing.T) {
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	err := db.Update(func(tx *badger.Txn) error {
+		for i := uint64(0); i < 4; i++ {
+			err := tx.Set(EncodeKey(PrefixHeader, mocks.GenericHeight+i), mocks.GenericBytes)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Set(EncodeKey(PrefixCommit, mocks.GenericHeight), mocks.GenericBytes)
+	})
+	require.NoError(t, err)
+
+	// NOTE: The following subtests should NOT be run in parallel, because of the deferral
+	// to close the database above.
+	t.Run("nominal case", func(t *testing.T) {
+		var got []KeyInfo
+		err := db.View(DumpKeys([]byte{PrefixHeader}, 0, &got))
+
+		require.NoError(t, err)
+		require.Len(t, got, 4)
+		for i, info := range got {
+			assert.Equal(t, uint8(PrefixHeader), info.Prefix)
+			assert.Equal(t, mocks.GenericHeight+uint64(i), info.Height)
+			assert.Equal(t, int64(len(mocks.GenericBytes)), info.Size)
+		}
+	})
+
+	t.Run("respects limit", func(t *testing.T) {
+		var got []KeyInfo
+		err := db.View(DumpKeys([]byte{PrefixHeader}, 2, &got))
+
+		require.NoError(t, err)
+		assert.Len(t, got, 2)
+	})
+
+	t.Run("handles undecodable keys", func(t *testing.T) {
+		invalid := helpers.InMemoryDB(t)
+		defer invalid.Close()
+
+		err := invalid.Update(func(tx *badger.Txn) error {
+			return tx.Set([]byte{PrefixHeader, 0x01}, mocks.GenericBytes)
+		})
+		require.NoError(t, err)
+
+		var got []KeyInfo
+		err = invalid.View(DumpKeys([]byte{PrefixHeader}, 0, &got))
+
+		assert.Error(t, err)
+	})
+}
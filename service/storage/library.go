@@ -18,9 +18,21 @@ import (
 	"github.com/optakt/flow-dps/models/dps"
 )
 
+// States of the cached knowledge about skipped register payloads.
+const (
+	skippedUnknown int32 = iota
+	skippedNone
+	skippedSome
+)
+
 // Library is the storage library.
 type Library struct {
 	codec dps.Codec
+
+	// skipped caches whether the payload of any register was skipped in the
+	// index, so that payload reads only look for skipped markers if there are
+	// any. It is accessed atomically.
+	skipped int32
 }
 
 // New returns a new storage library using the given codec.
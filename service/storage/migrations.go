@@ -0,0 +1,129 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
+)
+
+// Migration brings an index from the previous schema version to its version.
+// Migrations can be interrupted before the schema version is updated, so they
+// have to be idempotent, so that they can simply be applied again.
+type Migration struct {
+	Version     uint32
+	Description string
+	Apply       func(db *badger.DB, lib *Library) error
+}
+
+// Migrations is the registry of migrations to bring an index up to the current
+// schema version, ordered by version. The version of its last migration has to
+// match `dps.SchemaVersion`.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "baseline schema",
+		Apply:       func(*badger.DB, *Library) error { return nil },
+	},
+}
+
+// Migrate brings the index up to the current schema version by applying the
+// migrations of the registry that were not applied to it yet. The given
+// dictionary fingerprint is recorded for indexes that have no metadata record
+// yet, which can only be read with the dictionary they were written with. It
+// returns the applied migrations.
+func (l *Library) Migrate(db *badger.DB, dictionary string) ([]Migration, error) {
+	return l.migrate(db, dictionary, Migrations)
+}
+
+// migrate applies the given migrations to the index in order, skipping those
+// that the schema version of the index shows as already applied. Indexes that
+// have no metadata record predate the baseline schema and are considered to be
+// at version zero; their metadata is reconstructed before anything is applied.
+// After each successful migration, the schema version of the index is updated
+// in its own transaction.
+func (l *Library) migrate(db *badger.DB, dictionary string, migrations []Migration) ([]Migration, error) {
+
+	for i, migration := range migrations {
+		if migration.Version != uint32(i+1) {
+			return nil, fmt.Errorf("invalid migration version at position %d (got: %d, want: %d)", i, migration.Version, i+1)
+		}
+	}
+
+	var meta dps.Metadata
+	err := db.View(l.RetrieveMetadata(&meta))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		meta, err = l.legacyMetadata(db, dictionary)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve metadata: %w", err)
+	}
+
+	var applied []Migration
+	for _, migration := range migrations {
+		if migration.Version <= meta.SchemaVersion {
+			continue
+		}
+
+		err = migration.Apply(db, l)
+		if err != nil {
+			return applied, fmt.Errorf("could not apply migration (version: %d, description: %s): %w", migration.Version, migration.Description, err)
+		}
+
+		meta.SchemaVersion = migration.Version
+		err = db.Update(l.SaveMetadata(meta))
+		if err != nil {
+			return applied, fmt.Errorf("could not update schema version (version: %d): %w", migration.Version, err)
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// legacyMetadata reconstructs the metadata of an index that was created before
+// the metadata record was introduced, at schema version zero. The chain ID is
+// taken from the header of the first indexed block. As the creation time of
+// such an index was not recorded, the time of the migration is used instead.
+func (l *Library) legacyMetadata(db *badger.DB, dictionary string) (dps.Metadata, error) {
+
+	var first uint64
+	err := db.View(l.RetrieveFirst(&first))
+	if err != nil {
+		return dps.Metadata{}, fmt.Errorf("could not retrieve first height: %w", err)
+	}
+	var header flow.Header
+	err = db.View(l.RetrieveHeader(first, &header))
+	if err != nil {
+		return dps.Metadata{}, fmt.Errorf("could not retrieve first header (height: %d): %w", first, err)
+	}
+
+	meta := dps.Metadata{
+		SchemaVersion: 0,
+		Dictionary:    dictionary,
+		ChainID:       header.ChainID,
+		Created:       time.Now().UTC(),
+	}
+
+	return meta, nil
+}
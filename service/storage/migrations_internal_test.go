@@ -0,0 +1,189 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestMigrations(t *testing.T) {
+	require.NotEmpty(t, Migrations)
+
+	last := Migrations[len(Migrations)-1]
+	assert.Equal(t, uint32(dps.SchemaVersion), last.Version)
+}
+
+func TestLibrary_Migrate(t *testing.T) {
+	lib := New(zbor.NewCodec())
+
+	// counting returns migrations that count how many times each of them was
+	// applied.
+	counting := func(counts map[uint32]int, versions ...uint32) []Migration {
+		migrations := make([]Migration, 0, len(versions))
+		for _, version := range versions {
+			version := version
+			migrations = append(migrations, Migration{
+				Version:     version,
+				Description: "test migration",
+				Apply: func(*badger.DB, *Library) error {
+					counts[version]++
+					return nil
+				},
+			})
+		}
+		return migrations
+	}
+
+	// legacy returns an index that predates the metadata record.
+	legacy := func(t *testing.T) *badger.DB {
+		t.Helper()
+
+		db := helpers.InMemoryDB(t)
+		require.NoError(t, db.Update(lib.SaveFirst(mocks.GenericHeight)))
+		require.NoError(t, db.Update(lib.SaveHeader(mocks.GenericHeight, mocks.GenericHeader)))
+
+		return db
+	}
+
+	t.Run("applies all migrations to index without metadata", func(t *testing.T) {
+		t.Parallel()
+
+		db := legacy(t)
+		defer db.Close()
+
+		counts := make(map[uint32]int)
+		applied, err := lib.migrate(db, zbor.Fingerprint(), counting(counts, 1, 2, 3))
+
+		require.NoError(t, err)
+		assert.Len(t, applied, 3)
+		assert.Equal(t, map[uint32]int{1: 1, 2: 1, 3: 1}, counts)
+
+		var meta dps.Metadata
+		require.NoError(t, db.View(lib.RetrieveMetadata(&meta)))
+		assert.Equal(t, uint32(3), meta.SchemaVersion)
+		assert.Equal(t, zbor.Fingerprint(), meta.Dictionary)
+		assert.Equal(t, mocks.GenericHeader.ChainID, meta.ChainID)
+		assert.False(t, meta.Created.IsZero())
+	})
+
+	t.Run("handles index without metadata and first height", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		counts := make(map[uint32]int)
+		_, err := lib.migrate(db, zbor.Fingerprint(), counting(counts, 1))
+
+		assert.Error(t, err)
+		assert.Empty(t, counts)
+
+		// No incomplete metadata record should have been written.
+		var meta dps.Metadata
+		err = db.View(lib.RetrieveMetadata(&meta))
+		assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+	})
+
+	t.Run("skips already applied migrations", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		meta := dps.Metadata{
+			SchemaVersion: 2,
+			Dictionary:    zbor.Fingerprint(),
+			ChainID:       dps.FlowTestnet,
+		}
+		require.NoError(t, db.Update(lib.SaveMetadata(meta)))
+
+		counts := make(map[uint32]int)
+		applied, err := lib.migrate(db, zbor.Fingerprint(), counting(counts, 1, 2, 3))
+
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, uint32(3), applied[0].Version)
+		assert.Equal(t, map[uint32]int{3: 1}, counts)
+
+		var got dps.Metadata
+		require.NoError(t, db.View(lib.RetrieveMetadata(&got)))
+		assert.Equal(t, uint32(3), got.SchemaVersion)
+		assert.Equal(t, meta.Dictionary, got.Dictionary)
+		assert.Equal(t, meta.ChainID, got.ChainID)
+
+		// Running the migrations again should not apply anything.
+		applied, err = lib.migrate(db, zbor.Fingerprint(), counting(counts, 1, 2, 3))
+
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+		assert.Equal(t, map[uint32]int{3: 1}, counts)
+	})
+
+	t.Run("keeps schema version of failed migration", func(t *testing.T) {
+		t.Parallel()
+
+		db := legacy(t)
+		defer db.Close()
+
+		counts := make(map[uint32]int)
+		migrations := counting(counts, 1, 2)
+		migrations[1].Apply = func(*badger.DB, *Library) error {
+			return mocks.GenericError
+		}
+
+		applied, err := lib.migrate(db, zbor.Fingerprint(), migrations)
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+		assert.Len(t, applied, 1)
+
+		var meta dps.Metadata
+		require.NoError(t, db.View(lib.RetrieveMetadata(&meta)))
+		assert.Equal(t, uint32(1), meta.SchemaVersion)
+	})
+
+	t.Run("handles invalid migration versions", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		counts := make(map[uint32]int)
+		_, err := lib.migrate(db, zbor.Fingerprint(), counting(counts, 1, 3))
+
+		assert.Error(t, err)
+		assert.Empty(t, counts)
+	})
+
+	t.Run("applies baseline migration", func(t *testing.T) {
+		t.Parallel()
+
+		db := legacy(t)
+		defer db.Close()
+
+		applied, err := lib.Migrate(db, zbor.Fingerprint())
+
+		require.NoError(t, err)
+		assert.Len(t, applied, len(Migrations))
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"sync/atomic"
 
 	"github.com/OneOfOne/xxhash"
 	"github.com/dgraph-io/badger/v2"
@@ -25,8 +26,15 @@ import (
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/ledger/common/pathfinder"
 	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
 )
 
+// SaveMetadata is an operation that writes the metadata of the index.
+func (l *Library) SaveMetadata(meta dps.Metadata) func(*badger.Txn) error {
+	return l.save(EncodeKey(PrefixMetadata), meta)
+}
+
 // SaveFirst is an operation that writes the height of the first indexed block.
 func (l *Library) SaveFirst(height uint64) func(*badger.Txn) error {
 	return l.save(EncodeKey(PrefixFirst), height)
@@ -37,6 +45,12 @@ func (l *Library) SaveLast(height uint64) func(*badger.Txn) error {
 	return l.save(EncodeKey(PrefixLast), height)
 }
 
+// SaveSealed is an operation that writes the height of the last indexed block
+// that was sealed by one of the indexed blocks.
+func (l *Library) SaveSealed(height uint64) func(*badger.Txn) error {
+	return l.save(EncodeKey(PrefixSealed), height)
+}
+
 // IndexHeightForBlock is an operation that indexes the given height for its block identifier.
 func (l *Library) IndexHeightForBlock(blockID flow.Identifier, height uint64) func(*badger.Txn) error {
 	return l.save(EncodeKey(PrefixHeightForBlock, blockID), height)
@@ -109,11 +123,53 @@ func (l *Library) IndexSealsForHeight(height uint64, sealIDs []flow.Identifier)
 	return l.save(EncodeKey(PrefixSealsForHeight, height), sealIDs)
 }
 
+// SaveFailure is an operation that records the reason for which the block at
+// the given height could not be fully indexed.
+func (l *Library) SaveFailure(height uint64, reason string) func(*badger.Txn) error {
+	return l.save(EncodeKey(PrefixFailure, height), reason)
+}
+
+// SaveSkipped is an operation that records that the payload of the register
+// at the given path was not indexed at the given height, along with the
+// reason, so that reads of the register at that height and above fail instead
+// of returning an outdated value.
+func (l *Library) SaveSkipped(height uint64, path ledger.Path, reason string) func(*badger.Txn) error {
+	save := l.save(EncodeKey(PrefixSkipped, path, height), reason)
+	return func(tx *badger.Txn) error {
+		atomic.StoreInt32(&l.skipped, skippedSome)
+		return save(tx)
+	}
+}
+
+// DeleteFailure is an operation that removes the recorded failure for the
+// block at the given height, once it has been fully indexed.
+func (l *Library) DeleteFailure(height uint64) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+		key := EncodeKey(PrefixFailure, height)
+		err := tx.Delete(key)
+		if err != nil {
+			return fmt.Errorf("could not delete value (key: %x): %w", key, err)
+		}
+		return nil
+	}
+}
+
+// SaveClasses is an operation that records which classes of data were indexed
+// for the block at the given height.
+func (l *Library) SaveClasses(height uint64, classes dps.Classes) func(*badger.Txn) error {
+	return l.save(EncodeKey(PrefixClasses, height), classes)
+}
+
 // SaveResult is an operation that writes the given transaction result.
 func (l *Library) SaveResult(result *flow.TransactionResult) func(*badger.Txn) error {
 	return l.save(EncodeKey(PrefixResults, result.TransactionID), result)
 }
 
+// RetrieveMetadata retrieves the metadata of the index.
+func (l *Library) RetrieveMetadata(meta *dps.Metadata) func(*badger.Txn) error {
+	return l.retrieve(EncodeKey(PrefixMetadata), meta)
+}
+
 // RetrieveFirst retrieves the first indexed height.
 func (l *Library) RetrieveFirst(height *uint64) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixFirst), height)
@@ -124,6 +180,11 @@ func (l *Library) RetrieveLast(height *uint64) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixLast), height)
 }
 
+// RetrieveSealed retrieves the last sealed height.
+func (l *Library) RetrieveSealed(height *uint64) func(*badger.Txn) error {
+	return l.retrieve(EncodeKey(PrefixSealed), height)
+}
+
 // LookupHeightForBlock retrieves the height of the given block identifier.
 func (l *Library) LookupHeightForBlock(blockID flow.Identifier, height *uint64) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixHeightForBlock, blockID), height)
@@ -134,6 +195,29 @@ func (l *Library) RetrieveHeader(height uint64, header *flow.Header) func(*badge
 	return l.retrieve(EncodeKey(PrefixHeader, height), header)
 }
 
+// RetrieveLowestHeader retrieves the lowest height for which a header was
+// indexed, regardless of the first indexed height.
+func (l *Library) RetrieveLowestHeader(height *uint64) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+
+		prefix := EncodeKey(PrefixHeader)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		opts.PrefetchValues = false
+
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		it.Seek(prefix)
+		if !it.ValidForPrefix(prefix) {
+			return dps.ErrHeightNotIndexed
+		}
+
+		*height = binary.BigEndian.Uint64(it.Item().Key()[1:])
+		return nil
+	}
+}
+
 // RetrieveCommit retrieves the commit at the given height.
 func (l *Library) RetrieveCommit(height uint64, commit *flow.StateCommitment) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixCommit, height), commit)
@@ -199,8 +283,24 @@ func (l *Library) RetrievePayload(height uint64, path ledger.Path, payload *ledg
 		defer it.Close()
 
 		it.Seek(key)
-		if !it.Valid() {
-			return badger.ErrKeyNotFound
+		found := it.Valid()
+
+		// If the payload of the register was skipped at a height above the
+		// one of its last indexed payload, that payload is outdated. Skipped
+		// payloads are rare, so we only look for them if there are any.
+		var indexed uint64
+		if found {
+			indexed = binary.BigEndian.Uint64(it.Item().Key()[1+pathfinder.PathByteSize:])
+		}
+		if l.anySkipped(tx) {
+			skipped, ok := lastSkipped(tx, height, path)
+			if ok && (!found || skipped > indexed) {
+				return fmt.Errorf("register payload was skipped (path: %x, height: %d): %w", path, skipped, dps.ErrIncomplete)
+			}
+		}
+
+		if !found {
+			return dps.ErrRegisterNotFound
 		}
 
 		err := it.Item().Value(func(val []byte) error {
@@ -211,6 +311,43 @@ func (l *Library) RetrievePayload(height uint64, path ledger.Path, payload *ledg
 	}
 }
 
+// IteratePayloads is an operation that calls the given function with each
+// payload indexed for the given path, starting at the given height, in
+// ascending order of height. As payloads are only indexed when they are
+// updated, this yields the history of changes to the path.
+func (l *Library) IteratePayloads(path ledger.Path, from uint64, process func(height uint64, payload *ledger.Payload) error) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+
+		key := EncodeKey(PrefixPayload, path, from)
+		prefix := key[:1+pathfinder.PathByteSize]
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(key); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			height := binary.BigEndian.Uint64(item.Key()[1+pathfinder.PathByteSize:])
+
+			var payload ledger.Payload
+			err := item.Value(func(val []byte) error {
+				return l.codec.Unmarshal(val, &payload)
+			})
+			if err != nil {
+				return fmt.Errorf("could not decode value (height: %d): %w", height, err)
+			}
+
+			err = process(height, &payload)
+			if err != nil {
+				return fmt.Errorf("could not process payload (height: %d): %w", height, err)
+			}
+		}
+
+		return nil
+	}
+}
+
 // RetrieveCollection retrieves the collection with the given identifier.
 func (l *Library) RetrieveCollection(collectionID flow.Identifier, collection *flow.LightCollection) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixCollection, collectionID), collection)
@@ -257,6 +394,52 @@ func (l *Library) LookupSealsForHeight(height uint64, sealIDs *[]flow.Identifier
 	return l.retrieve(EncodeKey(PrefixSealsForHeight, height), sealIDs)
 }
 
+// RetrieveFailure retrieves the reason for which the block at the given height
+// could not be fully indexed.
+func (l *Library) RetrieveFailure(height uint64, reason *string) func(*badger.Txn) error {
+	return l.retrieve(EncodeKey(PrefixFailure, height), reason)
+}
+
+// IterateFailures is an operation that calls the given function with the
+// height and reason of each recorded failure, in ascending order of height.
+func (l *Library) IterateFailures(process func(height uint64, reason string) error) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+
+		prefix := EncodeKey(PrefixFailure)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			height := binary.BigEndian.Uint64(item.Key()[1:])
+
+			var reason string
+			err := item.Value(func(val []byte) error {
+				return l.codec.Unmarshal(val, &reason)
+			})
+			if err != nil {
+				return fmt.Errorf("could not decode value (height: %d): %w", height, err)
+			}
+
+			err = process(height, reason)
+			if err != nil {
+				return fmt.Errorf("could not process failure (height: %d): %w", height, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// RetrieveClasses retrieves which classes of data were indexed for the block at
+// the given height.
+func (l *Library) RetrieveClasses(height uint64, classes *dps.Classes) func(*badger.Txn) error {
+	return l.retrieve(EncodeKey(PrefixClasses, height), classes)
+}
+
 // RetrieveResult retrieves the result with the given transaction identifier.
 func (l *Library) RetrieveResult(txID flow.Identifier, result *flow.TransactionResult) func(*badger.Txn) error {
 	return l.retrieve(EncodeKey(PrefixResults, txID), result)
@@ -19,6 +19,7 @@ package storage_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/stretchr/testify/assert"
@@ -28,6 +29,7 @@ import (
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/storage"
 	"github.com/optakt/flow-dps/testing/helpers"
 	"github.com/optakt/flow-dps/testing/mocks"
@@ -64,6 +66,21 @@ func TestLibrary(t *testing.T) {
 		assert.Equal(t, mocks.GenericHeight, got)
 	})
 
+	t.Run("sealed", func(t *testing.T) {
+		t.Parallel()
+
+		db, lib := setupLibrary(t)
+
+		err := db.Update(lib.SaveSealed(mocks.GenericHeight))
+		assert.NoError(t, err)
+
+		var got uint64
+		err = db.View(lib.RetrieveSealed(&got))
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got)
+	})
+
 	t.Run("height for block", func(t *testing.T) {
 		t.Parallel()
 
@@ -184,6 +201,123 @@ func TestLibrary(t *testing.T) {
 		assert.Equal(t, *mocks.GenericLedgerPayload(0), got)
 	})
 
+	t.Run("skipped payload", func(t *testing.T) {
+		t.Parallel()
+
+		db, lib := setupLibrary(t)
+
+		path := mocks.GenericLedgerPath(0)
+		err := db.Update(lib.SavePayload(mocks.GenericHeight, path, mocks.GenericLedgerPayload(0)))
+		require.NoError(t, err)
+
+		// Reading before any payload was skipped caches that there are none,
+		// which saving a skipped payload has to invalidate.
+		var got ledger.Payload
+		err = db.View(lib.RetrievePayload(mocks.GenericHeight+5, path, &got))
+		require.NoError(t, err)
+
+		err = db.Update(lib.SaveSkipped(mocks.GenericHeight+5, path, "register value exceeds maximum size"))
+		require.NoError(t, err)
+		err = db.Update(lib.SavePayload(mocks.GenericHeight+10, path, mocks.GenericLedgerPayload(1)))
+		require.NoError(t, err)
+
+		err = db.View(lib.RetrievePayload(mocks.GenericHeight+4, path, &got))
+		require.NoError(t, err)
+		assert.Equal(t, *mocks.GenericLedgerPayload(0), got)
+
+		err = db.View(lib.RetrievePayload(mocks.GenericHeight+5, path, &got))
+		assert.ErrorIs(t, err, dps.ErrIncomplete)
+
+		err = db.View(lib.RetrievePayload(mocks.GenericHeight+9, path, &got))
+		assert.ErrorIs(t, err, dps.ErrIncomplete)
+
+		err = db.View(lib.RetrievePayload(mocks.GenericHeight+10, path, &got))
+		require.NoError(t, err)
+		assert.Equal(t, *mocks.GenericLedgerPayload(1), got)
+
+		// A register whose first payload was skipped must not be reported as
+		// not found.
+		other := mocks.GenericLedgerPath(1)
+		err = db.Update(lib.SaveSkipped(mocks.GenericHeight, other, "register value exceeds maximum size"))
+		require.NoError(t, err)
+
+		err = db.View(lib.RetrievePayload(mocks.GenericHeight, other, &got))
+		assert.ErrorIs(t, err, dps.ErrIncomplete)
+	})
+
+	t.Run("payload history", func(t *testing.T) {
+		t.Parallel()
+
+		db, lib := setupLibrary(t)
+
+		path := mocks.GenericLedgerPath(0)
+		heights := []uint64{mocks.GenericHeight, mocks.GenericHeight + 5, mocks.GenericHeight + 10}
+		for i, height := range heights {
+			err := db.Update(lib.SavePayload(height, path, mocks.GenericLedgerPayload(i)))
+			require.NoError(t, err)
+		}
+		// A payload on another path must not show up in the history.
+		err := db.Update(lib.SavePayload(mocks.GenericHeight+1, mocks.GenericLedgerPath(1), mocks.GenericLedgerPayload(3)))
+		require.NoError(t, err)
+
+		var got []uint64
+		err = db.View(lib.IteratePayloads(path, mocks.GenericHeight+1, func(height uint64, payload *ledger.Payload) error {
+			index := len(got) + 1
+			assert.Equal(t, mocks.GenericLedgerPayload(index), payload)
+			got = append(got, height)
+			return nil
+		}))
+
+		require.NoError(t, err)
+		assert.Equal(t, heights[1:], got)
+
+		err = db.View(lib.IteratePayloads(path, 0, func(uint64, *ledger.Payload) error {
+			return mocks.GenericError
+		}))
+
+		assert.ErrorIs(t, err, mocks.GenericError)
+	})
+
+	t.Run("classes", func(t *testing.T) {
+		t.Parallel()
+
+		db, lib := setupLibrary(t)
+
+		classes := dps.ClassesAll &^ dps.ClassRegisters
+		err := db.Update(lib.SaveClasses(mocks.GenericHeight, classes))
+		assert.NoError(t, err)
+
+		var got dps.Classes
+		err = db.View(lib.RetrieveClasses(mocks.GenericHeight, &got))
+
+		require.NoError(t, err)
+		assert.Equal(t, classes, got)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		t.Parallel()
+
+		db, lib := setupLibrary(t)
+
+		meta := dps.Metadata{
+			SchemaVersion: dps.SchemaVersion,
+			Dictionary:    "0123456789abcdef",
+			ChainID:       dps.FlowTestnet,
+			Created:       time.Date(2021, time.November, 1, 12, 0, 0, 0, time.UTC),
+		}
+		err := db.Update(lib.SaveMetadata(meta))
+		assert.NoError(t, err)
+
+		var got dps.Metadata
+		err = db.View(lib.RetrieveMetadata(&got))
+
+		require.NoError(t, err)
+		assert.Equal(t, meta.SchemaVersion, got.SchemaVersion)
+		assert.Equal(t, meta.Dictionary, got.Dictionary)
+		assert.Equal(t, meta.ChainID, got.ChainID)
+		assert.True(t, meta.Created.Equal(got.Created))
+	})
+
 	t.Run("transaction", func(t *testing.T) {
 		t.Parallel()
 
@@ -337,6 +471,45 @@ func TestLibrary(t *testing.T) {
 		assert.NoError(t, err)
 		assert.ElementsMatch(t, sealIDs, got)
 	})
+
+	t.Run("failure", func(t *testing.T) {
+		t.Parallel()
+
+		db, lib := setupLibrary(t)
+
+		reason := mocks.GenericError.Error()
+
+		var got string
+		err := db.View(lib.RetrieveFailure(mocks.GenericHeight, &got))
+		assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+
+		err = db.Update(lib.SaveFailure(mocks.GenericHeight, reason))
+		assert.NoError(t, err)
+
+		err = db.View(lib.RetrieveFailure(mocks.GenericHeight, &got))
+
+		assert.NoError(t, err)
+		assert.Equal(t, reason, got)
+
+		err = db.Update(lib.SaveFailure(mocks.GenericHeight+1, reason))
+		assert.NoError(t, err)
+
+		var heights []uint64
+		err = db.View(lib.IterateFailures(func(height uint64, got string) error {
+			assert.Equal(t, reason, got)
+			heights = append(heights, height)
+			return nil
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{mocks.GenericHeight, mocks.GenericHeight + 1}, heights)
+
+		err = db.Update(lib.DeleteFailure(mocks.GenericHeight))
+		assert.NoError(t, err)
+
+		err = db.View(lib.RetrieveFailure(mocks.GenericHeight, &got))
+		assert.ErrorIs(t, err, badger.ErrKeyNotFound)
+	})
 }
 
 func setupLibrary(t *testing.T) (*badger.DB, *storage.Library) {
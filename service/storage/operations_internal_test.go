@@ -26,6 +26,7 @@ import (
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/models/dps"
 	"github.com/optakt/flow-dps/service/loader"
 	"github.com/optakt/flow-dps/testing/helpers"
 	"github.com/optakt/flow-dps/testing/mocks"
@@ -131,6 +132,56 @@ func TestLibrary_SaveAndRetrieveLast(t *testing.T) {
 	})
 }
 
+func TestLibrary_SaveAndRetrieveSealed(t *testing.T) {
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	testKey := EncodeKey(PrefixSealed)
+
+	t.Run("save sealed height", func(t *testing.T) {
+
+		codec := mocks.BaselineCodec(t)
+		codec.MarshalFunc = func(v interface{}) ([]byte, error) {
+			assert.IsType(t, uint64(0), v)
+			return mocks.GenericLedgerValue(0), nil
+		}
+
+		l := &Library{
+			codec: codec,
+		}
+
+		err := db.Update(l.SaveSealed(mocks.GenericHeight))
+		assert.NoError(t, err)
+	})
+
+	t.Run("retrieve sealed height", func(t *testing.T) {
+		err := db.Update(func(tx *badger.Txn) error {
+			return tx.Set(testKey, mocks.GenericBytes)
+		})
+		require.NoError(t, err)
+
+		decodeCallCount := 0
+		codec := mocks.BaselineCodec(t)
+		codec.UnmarshalFunc = func(b []byte, v interface{}) error {
+			assert.Equal(t, mocks.GenericBytes, b)
+			assert.IsType(t, &mocks.GenericHeight, v)
+			decodeCallCount++
+
+			return nil
+		}
+
+		l := &Library{
+			codec: codec,
+		}
+
+		var got uint64
+		err = db.View(l.RetrieveSealed(&got))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, decodeCallCount)
+	})
+}
+
 func TestLibrary_SaveAndRetrieveCommit(t *testing.T) {
 	db := helpers.InMemoryDB(t)
 	defer db.Close()
@@ -232,6 +283,43 @@ func TestLibrary_SaveAndRetrieveHeader(t *testing.T) {
 	})
 }
 
+func TestLibrary_RetrieveLowestHeader(t *testing.T) {
+	db := helpers.InMemoryDB(t)
+	defer db.Close()
+
+	l := &Library{
+		codec: mocks.BaselineCodec(t),
+	}
+
+	t.Run("handles missing headers", func(t *testing.T) {
+		var got uint64
+		err := db.View(l.RetrieveLowestHeader(&got))
+
+		assert.ErrorIs(t, err, dps.ErrHeightNotIndexed)
+	})
+
+	t.Run("retrieve lowest header", func(t *testing.T) {
+		err := db.Update(func(tx *badger.Txn) error {
+			// The commit is indexed at a lower height than the headers, so
+			// that we make sure only header keys are considered.
+			err := tx.Set(EncodeKey(PrefixCommit, mocks.GenericHeight-1), mocks.GenericBytes)
+			require.NoError(t, err)
+			err = tx.Set(EncodeKey(PrefixFirst), mocks.GenericBytes)
+			require.NoError(t, err)
+			err = tx.Set(EncodeKey(PrefixHeader, mocks.GenericHeight+1), mocks.GenericBytes)
+			require.NoError(t, err)
+			return tx.Set(EncodeKey(PrefixHeader, mocks.GenericHeight), mocks.GenericBytes)
+		})
+		require.NoError(t, err)
+
+		var got uint64
+		err = db.View(l.RetrieveLowestHeader(&got))
+
+		require.NoError(t, err)
+		assert.Equal(t, mocks.GenericHeight, got)
+	})
+}
+
 func TestLibrary_SaveAndRetrieveEvents(t *testing.T) {
 	testKey1 := EncodeKey(PrefixEvents, mocks.GenericHeight, xxhash.ChecksumString64(string(mocks.GenericEventType(0))))
 	testKey2 := EncodeKey(PrefixEvents, mocks.GenericHeight, xxhash.ChecksumString64(string(mocks.GenericEventType(1))))
@@ -639,7 +727,7 @@ func TestLibrary_SaveAndRetrievePayload(t *testing.T) {
 		var got ledger.Payload
 		err = db.View(l.RetrievePayload(mocks.GenericHeight/2, mocks.GenericLedgerPath(0), &got))
 
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, dps.ErrRegisterNotFound)
 		assert.Equal(t, 0, decodeCallCount) // Should never be called since key does not match anything.
 	})
 
@@ -677,7 +765,7 @@ func TestLibrary_SaveAndRetrievePayload(t *testing.T) {
 		var got ledger.Payload
 		err = db.View(l.RetrievePayload(mocks.GenericHeight, unknownPath, &got))
 
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, dps.ErrRegisterNotFound)
 		assert.Equal(t, 0, decodeCallCount) // Should never be called since key does not match anything.
 	})
 }
@@ -1270,7 +1358,7 @@ func TestLibrary_IterateLedger(t *testing.T) {
 		defer db.Close()
 
 		codec := zbor.NewCodec()
-		l := &Library{codec}
+		l := &Library{codec: codec}
 
 		for i := 0; i < entries; i++ {
 			height := mocks.GenericHeight + uint64(i)
@@ -1300,7 +1388,7 @@ func TestLibrary_IterateLedger(t *testing.T) {
 		defer db.Close()
 
 		codec := zbor.NewCodec()
-		l := &Library{codec}
+		l := &Library{codec: codec}
 
 		// Always use paths[0] for every payload.
 		path := paths[0]
@@ -1334,7 +1422,7 @@ func TestLibrary_IterateLedger(t *testing.T) {
 		codec.UnmarshalFunc = func([]byte, interface{}) error {
 			return mocks.GenericError
 		}
-		l := &Library{codec}
+		l := &Library{codec: codec}
 
 		for i := 0; i < entries; i++ {
 			height := mocks.GenericHeight + uint64(i)
@@ -1361,7 +1449,7 @@ func TestLibrary_IterateLedger(t *testing.T) {
 		defer db.Close()
 
 		codec := zbor.NewCodec()
-		l := &Library{codec}
+		l := &Library{codec: codec}
 
 		for i := 0; i < entries; i++ {
 			height := mocks.GenericHeight + uint64(i)
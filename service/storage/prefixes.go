@@ -37,4 +37,13 @@ const (
 
 	PrefixSeal           = 14
 	PrefixSealsForHeight = 15
+
+	PrefixFailure = 18
+	PrefixClasses = 19
+
+	PrefixMetadata = 20
+
+	PrefixSkipped = 21
+
+	PrefixSealed = 22
 )
@@ -0,0 +1,106 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/onflow/flow-go/ledger/common/pathfinder"
+)
+
+// PrefixStats contains statistics about the keys of an index with a given key
+// prefix. The dictionary is the identifier of the zstandard dictionary that the
+// first value with the prefix was compressed with, or zero if it was compressed
+// without a dictionary or not compressed at all.
+type PrefixStats struct {
+	Keys       uint64
+	Size       int64
+	Dictionary uint32
+}
+
+// Stats contains statistics about the contents of an index. Registers is the
+// number of distinct ledger registers that have at least one payload.
+type Stats struct {
+	Prefixes  map[uint8]*PrefixStats
+	Registers uint64
+}
+
+// CollectStats is an operation that goes through all the keys of an index to
+// count them and sum up the size of their values for each key prefix. Along
+// the way, it counts the distinct ledger registers and determines which
+// compression dictionary the values of each prefix were encoded with, which
+// serves as a fingerprint of the codec version that created the index.
+func CollectStats(stats *Stats) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+
+		stats.Prefixes = make(map[uint8]*PrefixStats)
+		stats.Registers = 0
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		var path []byte
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := item.Key()
+			if len(key) == 0 {
+				continue
+			}
+
+			// On the first key of each prefix, we look at the frame header of
+			// its value to find out which dictionary it was compressed with.
+			prefix := key[0]
+			prefixStats, ok := stats.Prefixes[prefix]
+			if !ok {
+				prefixStats = &PrefixStats{}
+				err := item.Value(func(val []byte) error {
+					var header zstd.Header
+					err := header.Decode(val)
+					if err == nil {
+						prefixStats.Dictionary = header.DictionaryID
+					}
+					return nil
+				})
+				if err != nil {
+					return fmt.Errorf("could not read value (key: %x): %w", key, err)
+				}
+				stats.Prefixes[prefix] = prefixStats
+			}
+
+			prefixStats.Keys++
+			prefixStats.Size += item.ValueSize()
+
+			// Payload keys are sorted by path first, so all payloads of the
+			// same register are next to each other.
+			if prefix != PrefixPayload || len(key) < 1+pathfinder.PathByteSize {
+				continue
+			}
+			current := key[1 : 1+pathfinder.PathByteSize]
+			if !bytes.Equal(current, path) {
+				stats.Registers++
+				path = append(path[:0], current...)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestCollectStats(t *testing.T) {
+	lib := New(zbor.NewCodec())
+
+	t.Run("nominal case", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *badger.Txn) error {
+			for i := uint64(0); i < 3; i++ {
+				err := lib.SaveHeader(mocks.GenericHeight+i, mocks.GenericHeader)(tx)
+				if err != nil {
+					return err
+				}
+			}
+			// Two registers, the first of which is updated at two heights.
+			paths := mocks.GenericLedgerPaths(2)
+			payloads := mocks.GenericLedgerPayloads(3)
+			err := lib.SavePayload(mocks.GenericHeight, paths[0], payloads[0])(tx)
+			if err != nil {
+				return err
+			}
+			err = lib.SavePayload(mocks.GenericHeight+1, paths[0], payloads[1])(tx)
+			if err != nil {
+				return err
+			}
+			return lib.SavePayload(mocks.GenericHeight, paths[1], payloads[2])(tx)
+		})
+		require.NoError(t, err)
+
+		var stats Stats
+		err = db.View(CollectStats(&stats))
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), stats.Registers)
+		require.Len(t, stats.Prefixes, 2)
+
+		headers := stats.Prefixes[PrefixHeader]
+		require.NotNil(t, headers)
+		assert.Equal(t, uint64(3), headers.Keys)
+		assert.Positive(t, headers.Size)
+		assert.NotZero(t, headers.Dictionary)
+
+		payloads := stats.Prefixes[PrefixPayload]
+		require.NotNil(t, payloads)
+		assert.Equal(t, uint64(3), payloads.Keys)
+		assert.Positive(t, payloads.Size)
+		assert.NotZero(t, payloads.Dictionary)
+
+		// Headers and payloads are compressed with different dictionaries.
+		assert.NotEqual(t, headers.Dictionary, payloads.Dictionary)
+	})
+
+	t.Run("handles uncompressed values", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *badger.Txn) error {
+			return tx.Set(EncodeKey(PrefixCommit, mocks.GenericHeight), mocks.GenericBytes)
+		})
+		require.NoError(t, err)
+
+		var stats Stats
+		err = db.View(CollectStats(&stats))
+
+		require.NoError(t, err)
+		require.Contains(t, stats.Prefixes, uint8(PrefixCommit))
+		assert.Equal(t, uint64(1), stats.Prefixes[PrefixCommit].Keys)
+		assert.Equal(t, int64(len(mocks.GenericBytes)), stats.Prefixes[PrefixCommit].Size)
+		assert.Zero(t, stats.Prefixes[PrefixCommit].Dictionary)
+		assert.Zero(t, stats.Registers)
+	})
+
+	t.Run("handles empty index", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		var stats Stats
+		err := db.View(CollectStats(&stats))
+
+		require.NoError(t, err)
+		assert.Empty(t, stats.Prefixes)
+		assert.Zero(t, stats.Registers)
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/onflow/flow-go/ledger"
+)
+
+// VerifyPayloads is an operation that decodes one out of every given number of
+// ledger register payloads in the index, across all of its heights, to detect
+// a damaged index before serving data from it. A sample of zero or one means
+// that every payload is decoded. It stops at the first payload that can not be
+// decoded, and stores the number of decoded payloads in the given counter.
+func (l *Library) VerifyPayloads(sample uint, verified *uint64) func(*badger.Txn) error {
+	return func(tx *badger.Txn) error {
+
+		if sample == 0 {
+			sample = 1
+		}
+
+		prefix := EncodeKey(PrefixPayload)
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := tx.NewIterator(opts)
+		defer it.Close()
+
+		*verified = 0
+		var seen uint64
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			seen++
+			if (seen-1)%uint64(sample) != 0 {
+				continue
+			}
+
+			item := it.Item()
+			_, err := DecodeKey(item.Key())
+			if err != nil {
+				return fmt.Errorf("could not decode payload key (key: %x): %w", item.Key(), err)
+			}
+			var payload ledger.Payload
+			err = item.Value(func(val []byte) error {
+				return l.codec.Unmarshal(val, &payload)
+			})
+			if err != nil {
+				return fmt.Errorf("could not decode payload (key: %x): %w", item.Key(), err)
+			}
+
+			*verified++
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 Optakt Labs OÜ
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy of
+// the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations under
+// the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optakt/flow-dps/codec/zbor"
+	"github.com/optakt/flow-dps/testing/helpers"
+	"github.com/optakt/flow-dps/testing/mocks"
+)
+
+func TestLibrary_VerifyPayloads(t *testing.T) {
+	lib := New(zbor.NewCodec())
+	paths := mocks.GenericLedgerPaths(4)
+	payloads := mocks.GenericLedgerPayloads(4)
+
+	setup := func(t *testing.T) *badger.DB {
+		t.Helper()
+
+		db := helpers.InMemoryDB(t)
+		err := db.Update(func(tx *badger.Txn) error {
+			for i, path := range paths {
+				err := lib.SavePayload(mocks.GenericHeight+uint64(i), path, payloads[i])(tx)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		return db
+	}
+
+	t.Run("healthy store", func(t *testing.T) {
+		t.Parallel()
+
+		db := setup(t)
+		defer db.Close()
+
+		var verified uint64
+		err := db.View(lib.VerifyPayloads(1, &verified))
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(len(payloads)), verified)
+	})
+
+	t.Run("samples payloads", func(t *testing.T) {
+		t.Parallel()
+
+		db := setup(t)
+		defer db.Close()
+
+		var verified uint64
+		err := db.View(lib.VerifyPayloads(3, &verified))
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), verified)
+	})
+
+	t.Run("tampered store", func(t *testing.T) {
+		t.Parallel()
+
+		db := setup(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *badger.Txn) error {
+			return tx.Set(EncodeKey(PrefixPayload, paths[2], mocks.GenericHeight+2), []byte(`corrupted`))
+		})
+		require.NoError(t, err)
+
+		var verified uint64
+		err = db.View(lib.VerifyPayloads(1, &verified))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("handles empty store", func(t *testing.T) {
+		t.Parallel()
+
+		db := helpers.InMemoryDB(t)
+		defer db.Close()
+
+		var verified uint64
+		err := db.View(lib.VerifyPayloads(1, &verified))
+
+		require.NoError(t, err)
+		assert.Zero(t, verified)
+	})
+}
@@ -31,6 +31,7 @@ type Mock struct {
 	PathsFunc  func(commit flow.StateCommitment) ([]ledger.Path, bool)
 	ParentFunc func(commit flow.StateCommitment) (flow.StateCommitment, bool)
 	ResetFunc  func(finalized flow.StateCommitment)
+	PruneFunc  func(finalized flow.StateCommitment)
 	SizeFunc   func() uint
 }
 
@@ -52,6 +53,7 @@ func BaselineMock(t *testing.T, hasCommit bool) *Mock {
 			return mocks.GenericCommit(1), true
 		},
 		ResetFunc: func(finalized flow.StateCommitment) {},
+		PruneFunc: func(finalized flow.StateCommitment) {},
 		SizeFunc: func() uint {
 			return 42
 		},
@@ -84,6 +86,10 @@ func (f *Mock) Reset(finalized flow.StateCommitment) {
 	f.ResetFunc(finalized)
 }
 
+func (f *Mock) Prune(finalized flow.StateCommitment) {
+	f.PruneFunc(finalized)
+}
+
 func (f *Mock) Size() uint {
 	return f.SizeFunc()
 }
@@ -24,6 +24,7 @@ import (
 type Reader struct {
 	FirstFunc                func() (uint64, error)
 	LastFunc                 func() (uint64, error)
+	SealedFunc               func() (uint64, error)
 	HeightForBlockFunc       func(blockID flow.Identifier) (uint64, error)
 	CommitFunc               func(height uint64) (flow.StateCommitment, error)
 	HeaderFunc               func(height uint64) (*flow.Header, error)
@@ -50,6 +51,9 @@ func BaselineReader(t *testing.T) *Reader {
 		LastFunc: func() (uint64, error) {
 			return GenericHeight, nil
 		},
+		SealedFunc: func() (uint64, error) {
+			return GenericHeight, nil
+		},
 		HeightForBlockFunc: func(blockID flow.Identifier) (uint64, error) {
 			return GenericHeight, nil
 		},
@@ -105,6 +109,10 @@ func (r *Reader) Last() (uint64, error) {
 	return r.LastFunc()
 }
 
+func (r *Reader) Sealed() (uint64, error) {
+	return r.SealedFunc()
+}
+
 func (r *Reader) HeightForBlock(blockID flow.Identifier) (uint64, error) {
 	return r.HeightForBlockFunc(blockID)
 }
@@ -19,9 +19,11 @@ import (
 )
 
 type WALReader struct {
-	NextFunc   func() bool
-	ErrFunc    func() error
-	RecordFunc func() []byte
+	NextFunc    func() bool
+	ErrFunc     func() error
+	RecordFunc  func() []byte
+	SegmentFunc func() int
+	OffsetFunc  func() int64
 }
 
 func BaselineWALReader(t *testing.T) *WALReader {
@@ -37,6 +39,12 @@ func BaselineWALReader(t *testing.T) *WALReader {
 		RecordFunc: func() []byte {
 			return GenericBytes
 		},
+		SegmentFunc: func() int {
+			return 0
+		},
+		OffsetFunc: func() int64 {
+			return 0
+		},
 	}
 }
 
@@ -51,3 +59,11 @@ func (w *WALReader) Err() error {
 func (w *WALReader) Record() []byte {
 	return w.RecordFunc()
 }
+
+func (w *WALReader) Segment() int {
+	return w.SegmentFunc()
+}
+
+func (w *WALReader) Offset() int64 {
+	return w.OffsetFunc()
+}
@@ -19,11 +19,14 @@ import (
 
 	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/optakt/flow-dps/models/dps"
 )
 
 type Writer struct {
 	FirstFunc        func(height uint64) error
 	LastFunc         func(height uint64) error
+	SealedFunc       func(height uint64) error
 	HeaderFunc       func(height uint64, header *flow.Header) error
 	CommitFunc       func(height uint64, commit flow.StateCommitment) error
 	PayloadsFunc     func(height uint64, paths []ledger.Path, value []*ledger.Payload) error
@@ -34,6 +37,9 @@ type Writer struct {
 	ResultsFunc      func(results []*flow.TransactionResult) error
 	EventsFunc       func(height uint64, events []flow.Event) error
 	SealsFunc        func(height uint64, seals []*flow.Seal) error
+	FailureFunc      func(height uint64, reason string) error
+	SkippedFunc      func(height uint64, path ledger.Path, reason string) error
+	ClassesFunc      func(height uint64, classes dps.Classes) error
 	CloseFunc        func() error
 }
 
@@ -47,6 +53,9 @@ func BaselineWriter(t *testing.T) *Writer {
 		LastFunc: func(height uint64) error {
 			return nil
 		},
+		SealedFunc: func(height uint64) error {
+			return nil
+		},
 		HeaderFunc: func(height uint64, header *flow.Header) error {
 			return nil
 		},
@@ -77,6 +86,15 @@ func BaselineWriter(t *testing.T) *Writer {
 		SealsFunc: func(height uint64, seals []*flow.Seal) error {
 			return nil
 		},
+		FailureFunc: func(height uint64, reason string) error {
+			return nil
+		},
+		SkippedFunc: func(height uint64, path ledger.Path, reason string) error {
+			return nil
+		},
+		ClassesFunc: func(height uint64, classes dps.Classes) error {
+			return nil
+		},
 		CloseFunc: func() error {
 			return nil
 		},
@@ -93,6 +111,10 @@ func (w *Writer) Last(height uint64) error {
 	return w.LastFunc(height)
 }
 
+func (w *Writer) Sealed(height uint64) error {
+	return w.SealedFunc(height)
+}
+
 func (w *Writer) Header(height uint64, header *flow.Header) error {
 	return w.HeaderFunc(height, header)
 }
@@ -133,6 +155,18 @@ func (w *Writer) Seals(height uint64, seals []*flow.Seal) error {
 	return w.SealsFunc(height, seals)
 }
 
+func (w *Writer) Failure(height uint64, reason string) error {
+	return w.FailureFunc(height, reason)
+}
+
+func (w *Writer) Skipped(height uint64, path ledger.Path, reason string) error {
+	return w.SkippedFunc(height, path, reason)
+}
+
+func (w *Writer) Classes(height uint64, classes dps.Classes) error {
+	return w.ClassesFunc(height, classes)
+}
+
 func (w *Writer) Close() error {
-	return w.Close()
+	return w.CloseFunc()
 }